@@ -1,8 +1,23 @@
 package app
 
 import (
+	"net"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/blobs"
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/idempotency"
 	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	"github.com/sauravgsh16/bookstore_users-api/publishers"
+	"github.com/sauravgsh16/bookstore_users-api/ratelimit"
+	"github.com/sauravgsh16/bookstore_users-api/rpc"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/cache"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/resp"
 )
 
 var (
@@ -11,11 +26,164 @@ var (
 
 // StartApp starts the user service application
 func StartApp() {
+	if err := usersdb.Open(); err != nil {
+		logger.Error("failed to connect to the users database, error: ", err)
+		panic(err)
+	}
+
+	keys, err := idempotency.New()
+	if err != nil {
+		logger.Error("failed to configure idempotency store, error: ", err)
+		panic(err)
+	}
+	idempotency.StartPurgeJob(keys, config.GetDuration("IDEMPOTENCY_PURGE_INTERVAL", time.Hour))
+
+	limits, err := ratelimit.New()
+	if err != nil {
+		logger.Error("failed to configure rate limit store, error: ", err)
+		panic(err)
+	}
+
+	router.Use(
+		middlewares.Metrics(),
+		middlewares.RequestID(),
+		middlewares.Authenticate(),
+		middlewares.RateLimit(limits, rateLimitRules()...),
+		middlewares.DBSession(),
+		middlewares.View(),
+	)
+	if config.GetBool("OPENAPI_VALIDATE_REQUESTS", true) {
+		router.Use(middlewares.ValidateRequest())
+	}
+	router.Use(middlewares.Idempotency(keys))
 	mapUrls()
 
+	if config.GetBool("USERS_CACHE_ENABLED", true) {
+		services.UserServ = services.NewCachedUserService(
+			services.UserServ,
+			newUserCache(),
+			config.GetDuration("USERS_CACHE_TTL", time.Minute),
+			config.GetDuration("USERS_CACHE_NEGATIVE_TTL", 10*time.Second),
+		)
+	}
+
+	store, err := blobs.New()
+	if err != nil {
+		logger.Error("failed to configure blob store, error: ", err)
+		panic(err)
+	}
+	services.ProfileServ = services.NewProfileService(store)
+
+	services.StartPurgeJob(
+		config.GetDuration("USERS_RETENTION_PERIOD", 30*24*time.Hour),
+		config.GetDuration("USERS_PURGE_INTERVAL", time.Hour),
+	)
+
+	publisher, err := publishers.New()
+	if err != nil {
+		logger.Error("failed to configure event publisher, error: ", err)
+		panic(err)
+	}
+	services.StartOutboxRelay(
+		publishers.NewMultiPublisher(publisher, services.WebhookFanout{}),
+		config.GetDuration("OUTBOX_RELAY_INTERVAL", time.Second),
+		config.GetInt("OUTBOX_BATCH_SIZE", 100),
+	)
+	services.StartWebhookDispatcher(
+		config.GetDuration("WEBHOOK_DISPATCH_INTERVAL", time.Second),
+		config.GetInt("WEBHOOK_BATCH_SIZE", 50),
+	)
+
+	retained := config.GetInt("USERS_EVENTS_RETAINED", 10000)
+	stream := publishers.NewStream(retained, config.GetInt("USERS_EVENTS_BUFFER", 256))
+	if err := services.StartEventStream(stream, retained); err != nil {
+		logger.Error("failed to start event stream, error: ", err)
+		panic(err)
+	}
+	services.EventsServ = services.NewEventsService(stream)
+
+	startGRPCServer(config.GetString("USERS_GRPC_ADDR", ":9090"))
+
 	logger.Info("about to start application....")
 	if err := router.Run(":8080"); err != nil {
 		logger.Error("failed to run gin gonic server, error: ", err)
 		panic(err)
 	}
 }
+
+// startGRPCServer serves the gRPC API on its own port, next to gin
+func startGRPCServer(addr string) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("failed to listen for grpc, error: ", err)
+		panic(err)
+	}
+	go func() {
+		if err := rpc.NewServer().Serve(lis); err != nil {
+			logger.Error("failed to run grpc server, error: ", err)
+			panic(err)
+		}
+	}()
+}
+
+// rateLimitRules are the limits applied to the routes, the first matching one winning. Each is
+// tuned by RATELIMIT_<NAME>_LIMIT and RATELIMIT_<NAME>_WINDOW, a limit of 0 disabling it. The
+// event stream is exempt.
+func rateLimitRules() []middlewares.RateLimitRule {
+	rule := func(name, algorithm, identity string, limit int, window time.Duration, routes ...string) middlewares.RateLimitRule {
+		prefix := "RATELIMIT_" + strings.ToUpper(name)
+		return middlewares.RateLimitRule{
+			Policy: ratelimit.Policy{
+				Name:      name,
+				Algorithm: algorithm,
+				Limit:     config.GetInt(prefix+"_LIMIT", limit),
+				Window:    config.GetDuration(prefix+"_WINDOW", window),
+			},
+			Routes:   routes,
+			Identity: identity,
+		}
+	}
+	return []middlewares.RateLimitRule{
+		// event streams are long lived, a reconnect resumes where the stream left off
+		{Routes: everyVersion("GET /internal/users/events"), Exempt: true},
+		rule("login", ratelimit.SlidingWindow, middlewares.IdentityIP, 10, time.Minute, everyVersion("POST /users/login")...),
+		rule("signup", ratelimit.SlidingWindow, middlewares.IdentityIP, 20, time.Hour, everyVersion("POST /users")...),
+		rule("internal", ratelimit.TokenBucket, middlewares.IdentityAPIKey, 300, time.Minute, everyVersion("* /internal/*")...),
+		rule("default", ratelimit.TokenBucket, middlewares.IdentityUser, 600, time.Minute, "* /*"),
+	}
+}
+
+// everyVersion expands "METHOD /path" routes to their unversioned and versioned paths
+func everyVersion(routes ...string) []string {
+	var result []string
+	for _, route := range routes {
+		parts := strings.SplitN(route, " ", 2)
+		result = append(result, route)
+		for _, prefix := range []string{"/v1", "/v2"} {
+			result = append(result, parts[0]+" "+prefix+parts[1])
+		}
+	}
+	return result
+}
+
+// newUserCache returns an in process LRU, fronting a shared Redis protocol cache when
+// CACHE_REDIS_ADDR is set
+func newUserCache() cache.Cache {
+	local := cache.NewLRU(config.GetInt("USERS_CACHE_SIZE", 10000))
+	addr := config.GetString("CACHE_REDIS_ADDR", "")
+	if addr == "" {
+		return local
+	}
+
+	client := resp.NewClient(resp.Options{
+		Addr:     addr,
+		Password: config.GetString("CACHE_REDIS_PASSWORD", ""),
+		DB:       config.GetInt("CACHE_REDIS_DB", 0),
+		Timeout:  config.GetDuration("CACHE_REDIS_TIMEOUT", 100*time.Millisecond),
+	})
+	return &cache.Tiered{
+		Local:    local,
+		Remote:   cache.NewRedis(client, "users-api:"),
+		LocalTTL: config.GetDuration("USERS_CACHE_LOCAL_TTL", 5*time.Second),
+	}
+}
@@ -0,0 +1,43 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	"github.com/sauravgsh16/bookstore_users-api/ratelimit"
+)
+
+func TestRateLimitRules(t *testing.T) {
+	engine := gin.New()
+	engine.Use(middlewares.RateLimit(ratelimit.NewMemoryStore(), rateLimitRules()...))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	for _, path := range []string{"/internal/users/events", "/v2/internal/users/events", "/internal/users/search", "/users/login", "/users/:user_id"} {
+		engine.GET(path, ok)
+		engine.POST(path, ok)
+	}
+
+	// rules are told apart by their default limits
+	tests := []struct {
+		method, path string
+		limit        string
+	}{
+		{method: http.MethodGet, path: "/internal/users/events"},
+		{method: http.MethodGet, path: "/v2/internal/users/events"},
+		{method: http.MethodGet, path: "/internal/users/search", limit: "300"},
+		{method: http.MethodPost, path: "/users/login", limit: "10"},
+		{method: http.MethodGet, path: "/users/1", limit: "600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if limit := rec.Header().Get("RateLimit-Limit"); limit != tt.limit {
+				t.Errorf("limited to %q requests, want %q", limit, tt.limit)
+			}
+		})
+	}
+}
@@ -1,23 +1,94 @@
 package app
 
 import (
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/controllers/docs"
 	"github.com/sauravgsh16/bookstore_users-api/controllers/graphql"
+	"github.com/sauravgsh16/bookstore_users-api/controllers/metrics"
 	"github.com/sauravgsh16/bookstore_users-api/controllers/ping"
 	"github.com/sauravgsh16/bookstore_users-api/controllers/users"
+	"github.com/sauravgsh16/bookstore_users-api/controllers/webhooks"
+	domain "github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
 )
 
 func mapUrls() {
 	router.GET("/ping", ping.Ping)
 
-	router.GET("/users/:user_id", users.Get)
-	router.POST("/users", users.Create)
-	router.PUT("/users/:user_id", users.Update)
-	router.PATCH("/users/:user_id", users.Update)
-	router.DELETE("/users/:user_id", users.Delete)
-	router.GET("/internal/users/search", users.Search)
-	router.POST("/users/login", users.LoginUser)
+	// The API is served under /v1 and /v2, unversioned paths picking the version from the
+	// version parameter of Accept
+	mapAPI(router.Group("", middlewares.Version(0)))
+	mapAPI(router.Group("/v1", middlewares.Version(domain.Version1)))
+	mapAPI(router.Group("/v2", middlewares.Version(domain.Version2)))
 
 	// GraphQL
 	router.GET("/graphql", graphql.Handler())
 	router.POST("/graphql", graphql.Handler())
+
+	// Prometheus
+	router.GET("/metrics", metrics.Metrics)
+
+	if config.GetBool("OPENAPI_SWAGGER_UI", false) {
+		router.GET("/docs", docs.SwaggerUI)
+	}
+}
+
+// mapAPI registers the REST API on a version group
+func mapAPI(api *gin.RouterGroup) {
+	api.GET("/openapi.json", docs.OpenAPI)
+
+	api.GET("/users/:user_id", users.Get)
+	api.POST("/users", users.Create)
+	api.PUT("/users/:user_id", users.Update)
+	api.PATCH("/users/:user_id", users.Patch)
+	api.DELETE("/users/:user_id", users.Delete)
+	api.GET("/internal/users/search", users.Search)
+	api.POST("/internal/users/bulk", users.BulkImport)
+	api.GET("/internal/users/export", users.Export)
+	api.GET("/internal/users/events", users.Events)
+	api.POST("/users/login", users.LoginUser)
+
+	// Account lifecycle
+	api.POST("/users/:user_id/activate", users.ChangeStatus(domain.ActionActivate))
+	api.POST("/users/:user_id/suspend", users.ChangeStatus(domain.ActionSuspend))
+	api.POST("/users/:user_id/reactivate", users.ChangeStatus(domain.ActionReactivate))
+	api.POST("/users/:user_id/deactivate", users.ChangeStatus(domain.ActionDeactivate))
+	api.POST("/users/:user_id/restore", users.ChangeStatus(domain.ActionRestore))
+	api.GET("/users/:user_id/status-history", users.GetStatusHistory)
+
+	// Profile, addresses and avatar
+	api.GET("/users/:user_id/profile", users.GetProfile)
+	api.PUT("/users/:user_id/profile", users.UpdateProfile)
+	api.PATCH("/users/:user_id/profile", users.UpdateProfile)
+	api.GET("/users/:user_id/addresses", users.GetAddresses)
+	api.POST("/users/:user_id/addresses", users.CreateAddress)
+	api.GET("/users/:user_id/addresses/:address_id", users.GetAddress)
+	api.PUT("/users/:user_id/addresses/:address_id", users.UpdateAddress)
+	api.DELETE("/users/:user_id/addresses/:address_id", users.DeleteAddress)
+	api.GET("/users/:user_id/avatar", users.GetAvatar)
+	api.PUT("/users/:user_id/avatar", users.UploadAvatar)
+	api.DELETE("/users/:user_id/avatar", users.DeleteAvatar)
+
+	// Data subject requests
+	api.GET("/users/:user_id/data-export", users.DataExport)
+	api.POST("/users/:user_id/erase", users.Erase)
+
+	// Audit trail
+	api.GET("/users/:user_id/audit", users.GetAuditLog)
+
+	// External identity providers
+	api.GET("/oidc/:provider/login", users.ProviderLogin)
+	api.GET("/oidc/:provider/callback", users.ProviderCallback)
+	api.GET("/users/:user_id/identities", users.GetIdentities)
+	api.POST("/users/:user_id/identities/:provider", users.LinkIdentity)
+	api.DELETE("/users/:user_id/identities/:provider", users.UnlinkIdentity)
+
+	// Webhooks
+	api.POST("/webhooks", webhooks.Create)
+	api.GET("/webhooks", webhooks.List)
+	api.GET("/webhooks/:webhook_id", webhooks.Get)
+	api.DELETE("/webhooks/:webhook_id", webhooks.Delete)
+	api.GET("/webhooks/:webhook_id/deliveries", webhooks.Deliveries)
+	api.POST("/webhooks/:webhook_id/deliveries/:delivery_id/redeliver", webhooks.Redeliver)
 }
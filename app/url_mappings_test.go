@@ -0,0 +1,15 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/sauravgsh16/bookstore_users-api/openapi"
+)
+
+// the document is written by hand next to the routes, so the two must not drift
+func TestRoutesAreDocumented(t *testing.T) {
+	mapUrls()
+	if err := openapi.CheckRoutes(router.Routes(), "/docs", "/metrics"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,33 @@
+// Package blobs stores binary objects, such as avatars, outside of the database
+package blobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+)
+
+// ErrNotFound is returned when no blob is stored under a key
+var ErrNotFound = errors.New("blobs: not found")
+
+// Store keeps blobs under slash separated keys. Putting a key again replaces its blob.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get returns ErrNotFound for a missing key, the caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete is a no-op for a missing key
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the store selected by BLOB_STORE, only filesystem for now
+func New() (Store, error) {
+	switch kind := config.GetString("BLOB_STORE", "filesystem"); kind {
+	case "filesystem":
+		return NewFileStore(config.GetString("BLOB_FS_ROOT", "data/blobs"))
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", kind)
+	}
+}
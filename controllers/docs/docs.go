@@ -0,0 +1,55 @@
+package docs
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	"github.com/sauravgsh16/bookstore_users-api/openapi"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+)
+
+// swaggerUIAssets is where the Swagger UI scripts and styles are loaded from
+var swaggerUIAssets = config.GetString("SWAGGER_UI_ASSETS", "https://unpkg.com/swagger-ui-dist@5")
+
+var swaggerUI = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Bookstore users API</title>
+<link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+<script>
+SwaggerUIBundle({
+	dom_id: "#swagger-ui",
+	urls: [{url: "/v2/openapi.json", name: "v2"}, {url: "/v1/openapi.json", name: "v1 (deprecated)"}]
+});
+</script>
+</body>
+</html>
+`))
+
+// OpenAPI serves the OpenAPI document of the request version
+func OpenAPI(c *gin.Context) {
+	doc := *openapi.Spec(c.GetInt(middlewares.VersionKey))
+	if strings.TrimSuffix(c.FullPath(), "/openapi.json") == "" {
+		// unversioned paths serve the version negotiated with Accept
+		doc.Servers = []openapi.Server{{URL: "/"}}
+	}
+	c.JSON(http.StatusOK, &doc)
+}
+
+// SwaggerUI serves a Swagger UI browsing the documents of every version
+func SwaggerUI(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := swaggerUI.Execute(c.Writer, struct{ Assets string }{swaggerUIAssets}); err != nil {
+		logger.Error("failed to render swagger ui", err)
+	}
+}
@@ -1,23 +1,43 @@
 package graphql
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/graphql-go/handler"
 	schema "github.com/sauravgsh16/bookstore_users-api/domain/graphql-schema"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
 	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
 )
 
-// Handler graphql handler
+// Handler graphql handler, serving the schema of the request view and recording its operations
 func Handler() gin.HandlerFunc {
 	schema.InitQL(&services.Resolver{})
 
-	h := handler.New(&handler.Config{
-		Schema:   &schema.Schema,
-		Pretty:   true,
-		GraphiQL: true,
-	})
+	handlers := make(map[string]*handler.Handler, len(schema.Schemas))
+	for view, s := range schema.Schemas {
+		handlers[view] = handler.New(&handler.Config{
+			Schema:           s,
+			Pretty:           true,
+			GraphiQL:         true,
+			ResultCallbackFn: recordOperation,
+		})
+	}
 
 	return func(c *gin.Context) {
-		h.ServeHTTP(c.Writer, c.Request)
+		view := c.GetString(middlewares.ViewKey)
+		h, ok := handlers[view]
+		if !ok {
+			err := errors.NewBadRequestError(fmt.Sprintf("unknown view %q", view))
+			c.JSON(err.Status, err)
+			return
+		}
+		op := &operation{}
+		start := time.Now()
+		h.ServeHTTP(c.Writer, c.Request.WithContext(context.WithValue(c.Request.Context(), operationKey{}, op)))
+		op.observe(time.Since(start).Seconds())
 	}
 }
@@ -0,0 +1,91 @@
+package graphql
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/sauravgsh16/bookstore_users-api/utils/metrics"
+)
+
+const (
+	// anonymousOperation labels operations without a name
+	anonymousOperation = "anonymous"
+	// invalidOperation labels documents that do not parse or lack the requested operation
+	invalidOperation = "invalid"
+	// maxOperationName is the longest operation name used as a label value
+	maxOperationName = 64
+)
+
+var (
+	operations = metrics.NewCounter("graphql_operations_total",
+		"GraphQL operations executed, by operation name and type", "operation", "type")
+	operationDuration = metrics.NewHistogram("graphql_operation_duration_seconds",
+		"Time taken by GraphQL operations, by operation name and type", metrics.DefBuckets, "operation", "type")
+	operationErrors = metrics.NewCounter("graphql_operation_errors_total",
+		"Errors returned by GraphQL operations, by operation name and type", "operation", "type")
+)
+
+type operationKey struct{}
+
+// operation is filled in by recordOperation once the handler executed a request
+type operation struct {
+	executed bool
+	name     string
+	typ      string
+	errors   int
+}
+
+// recordOperation is the result callback of the handlers, describing the operation executed
+func recordOperation(ctx context.Context, params *graphql.Params, result *graphql.Result, _ []byte) {
+	op, ok := ctx.Value(operationKey{}).(*operation)
+	if !ok {
+		return
+	}
+	op.executed = true
+	op.name, op.typ = describeOperation(params.RequestString, params.OperationName)
+	op.errors = len(result.Errors)
+}
+
+// observe records op, which took seconds, if the handler executed it
+func (op *operation) observe(seconds float64) {
+	if !op.executed {
+		return
+	}
+	operations.Inc(op.name, op.typ)
+	operationDuration.Observe(seconds, op.name, op.typ)
+	if op.errors > 0 {
+		operationErrors.Add(float64(op.errors), op.name, op.typ)
+	}
+}
+
+// describeOperation returns the name and type of the operation of query selected by name. Names
+// come from clients, so beyond the series cap of the metrics they are bounded in length.
+func describeOperation(query, name string) (string, string) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return invalidOperation, invalidOperation
+	}
+	for _, def := range doc.Definitions {
+		od, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		var opName string
+		if od.Name != nil {
+			opName = od.Name.Value
+		}
+		if name != "" && opName != name {
+			continue
+		}
+		switch {
+		case opName == "":
+			opName = anonymousOperation
+		case len(opName) > maxOperationName:
+			opName = invalidOperation
+		}
+		return opName, od.Operation
+	}
+	return invalidOperation, invalidOperation
+}
@@ -0,0 +1,32 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribeOperation(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		opName   string
+		wantName string
+		wantType string
+	}{
+		{name: "named query", query: `query GetUser { user(id: 1) { id } }`, wantName: "GetUser", wantType: "query"},
+		{name: "anonymous query", query: `{ user(id: 1) { id } }`, wantName: anonymousOperation, wantType: "query"},
+		{name: "selected operation", query: `query A { user(id: 1) { id } } mutation B { deleteUser(id: 1) }`, opName: "B", wantName: "B", wantType: "mutation"},
+		{name: "missing operation", query: `query A { user(id: 1) { id } }`, opName: "B", wantName: invalidOperation, wantType: invalidOperation},
+		{name: "syntax error", query: `query {`, wantName: invalidOperation, wantType: invalidOperation},
+		{name: "long name", query: "query " + strings.Repeat("a", maxOperationName+1) + " { user(id: 1) { id } }", wantName: invalidOperation, wantType: "query"},
+		{name: "fragments only", query: `fragment F on User { id }`, wantName: invalidOperation, wantType: invalidOperation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, typ := describeOperation(tt.query, tt.opName)
+			if name != tt.wantName || typ != tt.wantType {
+				t.Errorf("describeOperation = %q, %q, want %q, %q", name, typ, tt.wantName, tt.wantType)
+			}
+		})
+	}
+}
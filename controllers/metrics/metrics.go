@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/metrics"
+)
+
+// Metrics exposes the metrics of the service to Prometheus
+func Metrics(c *gin.Context) {
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	if _, err := metrics.DefaultRegistry.WriteTo(c.Writer); err != nil {
+		logger.Error("failed to write metrics: ", err)
+	}
+}
@@ -0,0 +1,305 @@
+package users
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+const (
+	mimeJSON   = "application/json"
+	mimeNDJSON = "application/x-ndjson"
+	mimeCSV    = "text/csv"
+
+	// exportFlushEvery rows the export is flushed to the client
+	exportFlushEvery = 500
+)
+
+var maxBulkRows = config.GetInt("USERS_BULK_MAX_ROWS", 10000)
+
+// BulkImport creates users from a JSON array, NDJSON or CSV body, reporting the outcome of
+// every row. dry_run=true only validates the rows, mode=rows creates them one at a time.
+func BulkImport(c *gin.Context) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+
+	var decode func(io.Reader) ([]users.BulkRow, *errors.RestErr)
+	switch mediaType {
+	case mimeJSON, "":
+		decode = decodeJSONRows
+	case mimeNDJSON:
+		decode = decodeNDJSONRows
+	case mimeCSV:
+		decode = decodeCSVRows
+	default:
+		err := errors.NewUnsupportedMediaTypeError(fmt.Sprintf("unsupported content type %q", mediaType))
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	rows, err := decode(c.Request.Body)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	mode := c.DefaultQuery("mode", users.BulkModeCopy)
+	dryRun := c.Query("dry_run") == "true"
+	report, err := services.UserServ.ImportUsers(c.Request.Context(), rows, mode, dryRun, getCaller(c))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	render.Respond(c, http.StatusOK, report)
+}
+
+func tooManyRows() *errors.RestErr {
+	return errors.NewBadRequestError(fmt.Sprintf("imports are limited to %d rows", maxBulkRows))
+}
+
+// decodeJSONRows reads a JSON array of users one element at a time
+func decodeJSONRows(r io.Reader) ([]users.BulkRow, *errors.RestErr) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.NewBadRequestError("invalid json body: expected an array of users")
+	}
+
+	var rows []users.BulkRow
+	for dec.More() {
+		if len(rows) == maxBulkRows {
+			return nil, tooManyRows()
+		}
+		row := users.BulkRow{Row: len(rows) + 1}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("invalid json body at row %d: %s", row.Row, err.Error()))
+		}
+		if err := json.Unmarshal(raw, &row.User); err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("invalid json body: %s", err.Error()))
+	}
+	return rows, nil
+}
+
+// decodeNDJSONRows reads one user per line, blank lines are skipped
+func decodeNDJSONRows(r io.Reader) ([]users.BulkRow, *errors.RestErr) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []users.BulkRow
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows) == maxBulkRows {
+			return nil, tooManyRows()
+		}
+		row := users.BulkRow{Row: line}
+		if err := json.Unmarshal([]byte(text), &row.User); err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("invalid ndjson body: %s", err.Error()))
+	}
+	return rows, nil
+}
+
+// decodeCSVRows reads users from CSV whose header names the columns, in any order
+func decodeCSVRows(r io.Reader) ([]users.BulkRow, *errors.RestErr) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.NewBadRequestError("invalid csv body: missing header")
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, col := range []string{"email", "password"} {
+		if _, ok := index[col]; !ok {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("invalid csv body: missing %s column", col))
+		}
+	}
+
+	var rows []users.BulkRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if len(rows) == maxBulkRows {
+			return nil, tooManyRows()
+		}
+		row := users.BulkRow{Row: len(rows) + 1}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, errors.NewBadRequestError(fmt.Sprintf("invalid csv body: %s", err.Error()))
+			}
+			row.Error = err.Error()
+			rows = append(rows, row)
+			continue
+		}
+		if len(record) != len(header) {
+			row.Error = fmt.Sprintf("expected %d fields, got %d", len(header), len(record))
+			rows = append(rows, row)
+			continue
+		}
+
+		field := func(name string) string {
+			if i, ok := index[name]; ok {
+				return record[i]
+			}
+			return ""
+		}
+		row.User = users.User{
+			FirstName: field("first_name"),
+			LastName:  field("last_name"),
+			Email:     field("email"),
+			Password:  field("password"),
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// Export streams every user as NDJSON, or CSV when format=csv or text/csv is accepted, holding
+// the fields of the caller's view. Soft deleted users are included with include_deleted=true.
+func Export(c *gin.Context) {
+	projection, projErr := getProjection(c)
+	if projErr != nil {
+		render.Respond(c, projErr.Status, projErr)
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), mimeCSV) {
+		format = "csv"
+	}
+
+	var (
+		contentType string
+		write       func(*users.User) error
+		flush       func() error
+	)
+	switch format {
+	case "", "ndjson":
+		format, contentType = "ndjson", mimeNDJSON
+		enc := json.NewEncoder(c.Writer)
+		write = func(u *users.User) error {
+			return enc.Encode(u.Marshall(projection))
+		}
+		flush = func() error { return nil }
+	case "csv":
+		contentType = mimeCSV
+		w := csv.NewWriter(c.Writer)
+		fields := csvFields(projection)
+		header := make([]string, len(fields))
+		for i, f := range fields {
+			header[i] = f.Name
+		}
+		// buffered until the first flush, so errors before any row can still be reported
+		w.Write(header)
+		record := make([]string, len(fields))
+		write = func(u *users.User) error {
+			for i, f := range fields {
+				record[i] = csvValue(f, u)
+			}
+			return w.Write(record)
+		}
+		flush = func() error {
+			w.Flush()
+			return w.Error()
+		}
+	default:
+		err := errors.NewBadRequestError(fmt.Sprintf("unsupported export format %q", format))
+		render.Respond(c, err.Status, err)
+		return
+	}
+	n := 0
+	started := false
+	start := func() {
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=users.%s", format))
+		c.Status(http.StatusOK)
+		started = true
+	}
+	err := services.UserServ.ExportUsers(c.Request.Context(), c.Query("include_deleted") == "true", func(u *users.User) *errors.RestErr {
+		if !started {
+			start()
+		}
+		if err := write(u); err != nil {
+			return errors.NewInternalServerError(fmt.Sprintf("failed to write export: %s", err.Error()))
+		}
+		if n++; n%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return errors.NewInternalServerError(fmt.Sprintf("failed to write export: %s", err.Error()))
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	if err != nil {
+		// the status line is gone, the client sees a truncated body
+		logger.Error(fmt.Sprintf("user export aborted after %d rows: ", n), goerrors.New(err.Message))
+		return
+	}
+	if !started {
+		start()
+	}
+	flush()
+	c.Writer.Flush()
+}
+
+// csvFields returns the fields of p a CSV column can hold, the nested profile is left out
+func csvFields(p users.Projection) []users.Field {
+	var fields []users.Field
+	for _, f := range p.Fields() {
+		if f.Name != "profile" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// csvValue formats the field of u for a CSV cell, dates as ISO 8601 and missing values empty
+func csvValue(f users.Field, u *users.User) string {
+	switch v := f.Value(u).(type) {
+	case nil:
+		return ""
+	case string:
+		if f.Date {
+			return dates.ToISO8601(v)
+		}
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,96 @@
+package users
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+var (
+	// requireIfMatch rejects PUT, PATCH and DELETE requests without an If-Match header. It is
+	// off by default as v1 clients were never required to send one.
+	requireIfMatch = config.GetBool("USERS_REQUIRE_IF_MATCH", false)
+)
+
+// setETag sets and returns the entity tag of the user represented on p, in the format the
+// request accepts. The tag is the user version followed by a digest of the representation, so
+// a cache never serves one representation of a version for another.
+func setETag(c *gin.Context, u *users.User, p users.Projection) string {
+	tag := fmt.Sprintf(`"%d-%s"`, u.Version, representation(c, p))
+	c.Header("ETag", tag)
+	c.Header("Vary", "X-View, X-Public")
+	return tag
+}
+
+// representation digests the media type, API version, view and fields of the response
+func representation(c *gin.Context, p users.Projection) string {
+	f := render.Negotiate(c.GetHeader("Accept"), users.Document{})
+	if f == nil {
+		f = render.JSON
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s;%d;%s", f.MediaType, p.Version, p.View)
+	for _, field := range p.Fields() {
+		fmt.Fprintf(h, ";%s", field.Name)
+	}
+	return strconv.FormatUint(uint64(h.Sum32()), 36)
+}
+
+// getIfMatchVersion returns the user version the request is conditional on, 0 for any version
+func getIfMatchVersion(c *gin.Context) (int, *errors.RestErr) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		if requireIfMatch {
+			return 0, errors.NewPreconditionRequiredError("If-Match header is required")
+		}
+		return 0, nil
+	}
+	if ifMatch == "*" {
+		return 0, nil
+	}
+
+	// If-Match uses the strong comparison so weak or malformed tags never match. Any
+	// representation of the current version matches.
+	version, err := parseETag(ifMatch)
+	if err != nil || version <= 0 {
+		return 0, errors.NewPreconditionFailedError("If-Match does not match the current user version")
+	}
+	return version, nil
+}
+
+// matchesIfNoneMatch returns true if the request If-None-Match header matches etag
+func matchesIfNoneMatch(c *gin.Context, etag string) bool {
+	ifNoneMatch := strings.TrimSpace(c.GetHeader("If-None-Match"))
+	if ifNoneMatch == "" {
+		return false
+	}
+	if ifNoneMatch == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		// If-None-Match uses the weak comparison
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseETag returns the user version of tag, as set by setETag or holding the version only
+func parseETag(tag string) (int, error) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, fmt.Errorf("malformed entity tag %s", tag)
+	}
+	opaque := tag[1 : len(tag)-1]
+	if i := strings.IndexByte(opaque, '-'); i >= 0 {
+		opaque = opaque[:i]
+	}
+	return strconv.Atoi(opaque)
+}
@@ -0,0 +1,181 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+func testContext(headers map[string]string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	for name, value := range headers {
+		c.Request.Header.Set(name, value)
+	}
+	return c
+}
+
+func testProjection(t *testing.T, view string, version int, names ...string) users.Projection {
+	t.Helper()
+	p, err := users.NewVersionedProjection(view, version, names)
+	if err != nil {
+		t.Fatalf("projection: %s", err.Message)
+	}
+	return p
+}
+
+func TestETagPerRepresentation(t *testing.T) {
+	u := &users.User{ID: 1, Version: 4}
+	base := setETag(testContext(nil), u, testProjection(t, users.ViewSelf, users.Version1))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		p       users.Projection
+		same    bool
+	}{
+		{name: "same representation", p: testProjection(t, users.ViewSelf, users.Version1), same: true},
+		{name: "json accepted explicitly", headers: map[string]string{"Accept": "application/json"}, p: testProjection(t, users.ViewSelf, users.Version1), same: true},
+		{name: "media type", headers: map[string]string{"Accept": "application/xml"}, p: testProjection(t, users.ViewSelf, users.Version1)},
+		{name: "api version", p: testProjection(t, users.ViewSelf, users.Version2)},
+		{name: "view", p: testProjection(t, users.ViewAdmin, users.Version1)},
+		{name: "sparse fields", p: testProjection(t, users.ViewSelf, users.Version1, "id", "email")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testContext(tt.headers)
+			tag := setETag(c, u, tt.p)
+			if (tag == base) != tt.same {
+				t.Errorf("tag %s, base %s, want same %v", tag, base, tt.same)
+			}
+			if version, err := parseETag(tag); err != nil || version != u.Version {
+				t.Errorf("parseETag(%s) = %d, %v, want %d", tag, version, err, u.Version)
+			}
+			ifNoneMatch := testContext(map[string]string{"If-None-Match": `W/` + base})
+			if matchesIfNoneMatch(ifNoneMatch, tag) != tt.same {
+				t.Errorf("If-None-Match %s matching %s, want %v", base, tag, tt.same)
+			}
+		})
+	}
+}
+
+func TestGetIfMatchVersion(t *testing.T) {
+	defer func(required bool) { requireIfMatch = required }(requireIfMatch)
+
+	tests := []struct {
+		ifMatch  string
+		required bool
+		want     int
+		status   int
+	}{
+		{ifMatch: `"4-1x2y3z"`, want: 4},
+		{ifMatch: `"4"`, want: 4},
+		{ifMatch: `*`, want: 0},
+		{ifMatch: `W/"4-1x2y3z"`, status: http.StatusPreconditionFailed},
+		{ifMatch: `4`, status: http.StatusPreconditionFailed},
+		{ifMatch: "", want: 0},
+		{ifMatch: `"4"`, required: true, want: 4},
+		{ifMatch: `*`, required: true, want: 0},
+		{ifMatch: "", required: true, status: http.StatusPreconditionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s required %v", tt.ifMatch, tt.required), func(t *testing.T) {
+			requireIfMatch = tt.required
+			version, err := getIfMatchVersion(testContext(map[string]string{"If-Match": tt.ifMatch}))
+			if err != nil {
+				if err.Status != tt.status {
+					t.Fatalf("got %d %s, want status %d", err.Status, err.Message, tt.status)
+				}
+				return
+			}
+			if tt.status != 0 || version != tt.want {
+				t.Errorf("got version %d, want %d or status %d", version, tt.want, tt.status)
+			}
+		})
+	}
+}
+
+// versionedUsers records the versions deletions are conditional on
+type versionedUsers struct {
+	services.UserInterface
+	versions []int
+}
+
+func (s *versionedUsers) DeleteUser(ctx context.Context, userID int, version int, caller users.Caller) *errors.RestErr {
+	s.versions = append(s.versions, version)
+	return nil
+}
+
+func TestDeleteIfMatch(t *testing.T) {
+	defer func(serv services.UserInterface, required bool) {
+		services.UserServ, requireIfMatch = serv, required
+	}(services.UserServ, requireIfMatch)
+
+	tests := []struct {
+		name       string
+		required   bool
+		ifMatch    string
+		wantStatus int
+		// wantVersions are the versions the service was called with
+		wantVersions []int
+	}{
+		{name: "optional and missing", wantStatus: http.StatusOK, wantVersions: []int{0}},
+		{name: "optional and given", ifMatch: `"3-1x2y3z"`, wantStatus: http.StatusOK, wantVersions: []int{3}},
+		{name: "required and missing", required: true, wantStatus: http.StatusPreconditionRequired},
+		{name: "required and given", required: true, ifMatch: `"3"`, wantStatus: http.StatusOK, wantVersions: []int{3}},
+		{name: "weak tag", ifMatch: `W/"3"`, wantStatus: http.StatusPreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := &versionedUsers{}
+			services.UserServ, requireIfMatch = stored, tt.required
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+			c.Params = gin.Params{{Key: "user_id", Value: "1"}}
+			if tt.ifMatch != "" {
+				c.Request.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			Delete(c)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if fmt.Sprint(stored.versions) != fmt.Sprint(tt.wantVersions) {
+				t.Errorf("service called with versions %v, want %v", stored.versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestCSVValue(t *testing.T) {
+	u := &users.User{ID: 7, Email: "a@example.com", DateCreated: "2026-01-02 03:04:05"}
+	p := testProjection(t, users.ViewAdmin, users.Version2)
+
+	got := make(map[string]string)
+	for _, f := range csvFields(p) {
+		got[f.Name] = csvValue(f, u)
+	}
+	want := map[string]string{
+		"id": "7", "first_name": "", "last_name": "", "email": "a@example.com", "date_created": "2026-01-02T03:04:05Z",
+		"status": "", "deleted_at": "", "erased_at": "", "version": "0",
+	}
+	if len(got) != len(want) {
+		t.Errorf("columns %v, want %v", got, want)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/publishers"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+const (
+	mimeEventStream = "text/event-stream"
+
+	// lastEventIDHeader is sent by reconnecting clients with the id of the last event they got
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// eventsHeartbeat is how often an idle stream sends a comment, so proxies keep it open
+var eventsHeartbeat = config.GetDuration("USERS_EVENTS_HEARTBEAT", 15*time.Second)
+
+// Events streams the domain events of users as Server-Sent Events, the id of each being that of
+// the event. type and status take comma separated event types and statuses to filter on, and
+// Last-Event-ID resumes after an event still retained.
+func Events(c *gin.Context) {
+	filter, filterErr := getEventFilter(c)
+	if filterErr != nil {
+		render.Respond(c, filterErr.Status, filterErr)
+		return
+	}
+
+	var lastID int64
+	if header := c.GetHeader(lastEventIDHeader); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || id < 1 {
+			restErr := errors.NewBadRequestError("Last-Event-ID should be an event id")
+			render.Respond(c, restErr.Status, restErr)
+			return
+		}
+		lastID = id
+	}
+
+	backlog, sub, err := services.EventsServ.Subscribe(lastID, filter)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	defer sub.Close()
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", mimeEventStream)
+	header.Set("Cache-Control", "no-cache")
+	// keeps nginx from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+
+	for _, msg := range backlog {
+		if err := writeEvent(c, msg); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				// fell too far behind, the client resumes from the last event it got
+				return
+			}
+			if err := writeEvent(c, msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-c.Request.Context().Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// getEventFilter reads the type and status query parameters
+func getEventFilter(c *gin.Context) (services.EventFilter, *errors.RestErr) {
+	types, err := listParam(c.Query("type"), users.EventTypes, "event type")
+	if err != nil {
+		return services.EventFilter{}, err
+	}
+	statuses, err := listParam(c.Query("status"), users.Statuses, "status")
+	if err != nil {
+		return services.EventFilter{}, err
+	}
+	return services.EventFilter{Types: types, Statuses: statuses}, nil
+}
+
+// listParam returns the set of comma separated values of a parameter, each one of known
+func listParam(value string, known []string, name string) (map[string]bool, *errors.RestErr) {
+	if value == "" {
+		return nil, nil
+	}
+	valid := make(map[string]bool, len(known))
+	for _, k := range known {
+		valid[k] = true
+	}
+	set := make(map[string]bool)
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if !valid[v] {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("unknown %s %q, expected one of %s", name, v, strings.Join(known, ", ")))
+		}
+		set[v] = true
+	}
+	return set, nil
+}
+
+// writeEvent writes msg as an event named after its type, its data being the envelope sent to
+// every HTTP consumer. The envelope is compact JSON, so it fits a single data line.
+func writeEvent(c *gin.Context, msg publishers.Message) error {
+	data, err := msg.Envelope()
+	if err != nil {
+		logger.Error("failed to encode event: ", err)
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Type, data)
+	return err
+}
@@ -0,0 +1,146 @@
+package users
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+const mimeZIP = "application/zip"
+
+var avatarExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// DataExport returns everything held on a user as a document in the accepted format, or as a
+// ZIP archive of one JSON file per section when format=zip or application/zip is accepted
+func DataExport(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), mimeZIP) {
+		format = "zip"
+	}
+	if format != "" && format != "json" && format != "zip" {
+		err := errors.NewBadRequestError(fmt.Sprintf("unsupported export format %q", format))
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	export, err := services.UserServ.ExportUserData(c.Request.Context(), userID)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	name := fmt.Sprintf("user-%d-data", userID)
+	if format != "zip" {
+		if f := render.Negotiate(c.GetHeader("Accept"), export); f != nil {
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", name, f.Name))
+		}
+		render.Respond(c, http.StatusOK, export)
+		return
+	}
+
+	archive, zipErr := zipDataExport(export)
+	if zipErr != nil {
+		logger.Error("failed to build data export archive: ", zipErr)
+		err := errors.NewInternalServerError("failed to build data export")
+		render.Respond(c, err.Status, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", name))
+	c.Data(http.StatusOK, mimeZIP, archive)
+}
+
+// zipDataExport writes every section of the export to its own file, next to a manifest
+func zipDataExport(export *users.DataExport) ([]byte, error) {
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"manifest.json", map[string]interface{}{"user_id": export.UserID, "generated_at": export.GeneratedAt}},
+		{"account.json", export.Account},
+		{"profile.json", export.Profile},
+		{"addresses.json", export.Addresses},
+		{"identities.json", export.Identities},
+		{"status_history.json", export.StatusHistory},
+		{"audit_log.json", export.AuditLog},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.content); err != nil {
+			return nil, err
+		}
+	}
+	if export.Avatar != nil {
+		w, err := zw.Create("avatar" + avatarExtensions[export.Profile.AvatarType])
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(export.Avatar); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Erase irreversibly anonymizes a user, keeping only its ID and history
+func Erase(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	version, err := getIfMatchVersion(c)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	projection, err := getProjection(c)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	// the reason is optional, so an empty body is accepted
+	var req statusRequest
+	_ = render.Bind(c, &req)
+
+	user, eraseErr := services.UserServ.EraseUser(c.Request.Context(), userID, version, req.Reason, getCaller(c))
+	if eraseErr != nil {
+		render.Respond(c, eraseErr.Status, eraseErr)
+		return
+	}
+
+	setETag(c, user, projection)
+	respondUser(c, http.StatusOK, user, projection)
+}
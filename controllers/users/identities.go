@@ -0,0 +1,97 @@
+package users
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+// ProviderLogin redirects to the identity provider to sign in
+func ProviderLogin(c *gin.Context) {
+	authURL, err := services.IdentityServ.BeginLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// ProviderCallback completes a sign in or link started at the identity provider
+func ProviderCallback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		rstErr := errors.NewUnauthorizedError(providerErr)
+		render.Respond(c, rstErr.Status, rstErr)
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		rstErr := errors.NewBadRequestError("missing authorization code")
+		render.Respond(c, rstErr.Status, rstErr)
+		return
+	}
+	projection, projErr := getProjection(c)
+	if projErr != nil {
+		render.Respond(c, projErr.Status, projErr)
+		return
+	}
+
+	user, err := services.IdentityServ.CompleteLogin(c.Request.Context(), c.Param("provider"), c.Query("state"), code, getCaller(c))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	signIn(c, user)
+	respondUser(c, http.StatusOK, user, projection)
+}
+
+// GetIdentities lists the identities linked to a user
+func GetIdentities(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	identities, getErr := services.IdentityServ.GetIdentities(c.Request.Context(), userID, getCaller(c))
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, identities)
+}
+
+// LinkIdentity returns the identity provider url to link a new identity to the signed in user
+func LinkIdentity(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	authURL, linkErr := services.IdentityServ.BeginLink(c.Request.Context(), userID, c.Param("provider"), getCaller(c))
+	if linkErr != nil {
+		render.Respond(c, linkErr.Status, linkErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, map[string]string{"authorization_url": authURL})
+}
+
+// UnlinkIdentity removes an identity provider from a user
+func UnlinkIdentity(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	if err := services.IdentityServ.UnlinkIdentity(c.Request.Context(), userID, c.Param("provider"), getCaller(c)); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	render.Respond(c, http.StatusOK, map[string]string{"status": "unlinked"})
+}
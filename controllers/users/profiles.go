@@ -0,0 +1,251 @@
+package users
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+func getAddressID(idStr string) (int, *errors.RestErr) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, errors.NewBadRequestError("address id should be a number")
+	}
+	return int(id), nil
+}
+
+// respondProfile renders the profile, its avatar urls pointing at the API version of the request
+func respondProfile(c *gin.Context, p *users.Profile) {
+	p.SetAvatarURLs(c.GetInt(middlewares.VersionKey))
+	render.Respond(c, http.StatusOK, p)
+}
+
+// GetProfile returns the profile of a user
+func GetProfile(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	profile, getErr := services.ProfileServ.GetProfile(c.Request.Context(), userID)
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	respondProfile(c, profile)
+}
+
+// UpdateProfile replaces the profile of a user, or only the fields sent on PATCH
+func UpdateProfile(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	var profile users.Profile
+	if err := render.Bind(c, &profile); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	profile.UserID = userID
+
+	result, updateErr := services.ProfileServ.UpdateProfile(c.Request.Context(), profile, c.Request.Method == http.MethodPatch)
+	if updateErr != nil {
+		render.Respond(c, updateErr.Status, updateErr)
+		return
+	}
+	respondProfile(c, result)
+}
+
+// GetAddresses returns the addresses of a user
+func GetAddresses(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	addresses, getErr := services.ProfileServ.GetAddresses(c.Request.Context(), userID)
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, addresses)
+}
+
+// GetAddress returns an address of a user
+func GetAddress(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	addressID, err := getAddressID(c.Param("address_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	address, getErr := services.ProfileServ.GetAddress(c.Request.Context(), userID, addressID)
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, address)
+}
+
+// CreateAddress adds an address to a user
+func CreateAddress(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	var address users.Address
+	if err := render.Bind(c, &address); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	address.UserID = userID
+
+	result, createErr := services.ProfileServ.CreateAddress(c.Request.Context(), address)
+	if createErr != nil {
+		render.Respond(c, createErr.Status, createErr)
+		return
+	}
+	render.Respond(c, http.StatusCreated, result)
+}
+
+// UpdateAddress replaces an address of a user
+func UpdateAddress(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	addressID, err := getAddressID(c.Param("address_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	var address users.Address
+	if err := render.Bind(c, &address); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	address.ID = addressID
+	address.UserID = userID
+
+	result, updateErr := services.ProfileServ.UpdateAddress(c.Request.Context(), address)
+	if updateErr != nil {
+		render.Respond(c, updateErr.Status, updateErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, result)
+}
+
+// DeleteAddress removes an address from a user
+func DeleteAddress(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	addressID, err := getAddressID(c.Param("address_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	if err := services.ProfileServ.DeleteAddress(c.Request.Context(), userID, addressID); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	render.Respond(c, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// UploadAvatar sets the avatar of a user from the avatar field of a multipart form, or from
+// the request body when it is sent as is
+func UploadAvatar(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	// multipart framing needs some room on top of the image itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxAvatarBytes+4096)
+
+	var body io.Reader = c.Request.Body
+	if strings.HasPrefix(c.GetHeader("Content-Type"), "multipart/form-data") {
+		file, _, formErr := c.Request.FormFile("avatar")
+		if formErr != nil {
+			bdErr := errors.NewBadRequestError(fmt.Sprintf("invalid avatar upload: %s", formErr.Error()))
+			render.Respond(c, bdErr.Status, bdErr)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	// one more byte than accepted lets the service tell the upload is too large
+	data, readErr := ioutil.ReadAll(io.LimitReader(body, services.MaxAvatarBytes+1))
+	if readErr != nil {
+		bdErr := errors.NewBadRequestError(fmt.Sprintf("invalid avatar upload: %s", readErr.Error()))
+		render.Respond(c, bdErr.Status, bdErr)
+		return
+	}
+
+	profile, setErr := services.ProfileServ.SetAvatar(c.Request.Context(), userID, data)
+	if setErr != nil {
+		render.Respond(c, setErr.Status, setErr)
+		return
+	}
+	respondProfile(c, profile)
+}
+
+// GetAvatar returns the avatar image of a user, its thumbnail with size=thumbnail
+func GetAvatar(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	r, contentType, getErr := services.ProfileServ.GetAvatar(c.Request.Context(), userID, c.Query("size") == "thumbnail")
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	defer r.Close()
+	c.DataFromReader(http.StatusOK, -1, contentType, r, nil)
+}
+
+// DeleteAvatar removes the avatar of a user
+func DeleteAvatar(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	if err := services.ProfileServ.DeleteAvatar(c.Request.Context(), userID); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	render.Respond(c, http.StatusOK, map[string]string{"status": "deleted"})
+}
@@ -0,0 +1,39 @@
+package users
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+// getProjection returns the fields of the request view and API version, narrowed down to the
+// comma separated fields query parameter
+func getProjection(c *gin.Context) (users.Projection, *errors.RestErr) {
+	var names []string
+	if fields := c.Query("fields"); fields != "" {
+		names = strings.Split(fields, ",")
+	}
+	return users.NewVersionedProjection(c.GetString(middlewares.ViewKey), c.GetInt(middlewares.VersionKey), names)
+}
+
+// respondUser renders the user projected on p, loading the profile when p nests it. The
+// profile of a deleted user is left out.
+func respondUser(c *gin.Context, status int, user *users.User, p users.Projection) {
+	if p.Has("profile") && user.DeletedAt == "" {
+		profile, err := services.ProfileServ.GetProfile(c.Request.Context(), user.ID)
+		if err != nil {
+			render.Respond(c, err.Status, err)
+			return
+		}
+		profile.SetAvatarURLs(c.GetInt(middlewares.VersionKey))
+		withProfile := *user
+		withProfile.Profile = profile
+		user = &withProfile
+	}
+	render.Respond(c, status, user.Marshall(p))
+}
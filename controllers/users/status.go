@@ -0,0 +1,79 @@
+package users
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/auth"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+type statusRequest struct {
+	Reason string `json:"reason"`
+}
+
+// getCaller returns who is responsible for a change and the request it was made in
+func getCaller(c *gin.Context) users.Caller {
+	actor := c.GetHeader("X-Actor")
+	if actor == "" {
+		actor = "anonymous"
+	}
+	return users.Caller{
+		Actor:     actor,
+		RequestID: c.GetString(middlewares.RequestIDKey),
+		UserID:    c.GetInt(middlewares.CallerIDKey),
+	}
+}
+
+// signIn hands the user an access token, to send back as a bearer token in later requests
+func signIn(c *gin.Context, user *users.User) {
+	c.Header(middlewares.AccessTokenHeader, auth.NewToken(user.ID))
+}
+
+// ChangeStatus returns a handler applying the lifecycle action to a user
+func ChangeStatus(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := getUserID(c.Param("user_id"))
+		if err != nil {
+			render.Respond(c, err.Status, err)
+			return
+		}
+		projection, err := getProjection(c)
+		if err != nil {
+			render.Respond(c, err.Status, err)
+			return
+		}
+
+		// the reason is optional, so an empty body is accepted
+		var req statusRequest
+		_ = render.Bind(c, &req)
+
+		user, changeErr := services.UserServ.ChangeStatus(c.Request.Context(), userID, action, req.Reason, getCaller(c))
+		if changeErr != nil {
+			render.Respond(c, changeErr.Status, changeErr)
+			return
+		}
+
+		setETag(c, user, projection)
+		respondUser(c, http.StatusOK, user, projection)
+	}
+}
+
+// GetStatusHistory returns the status changes of a user
+func GetStatusHistory(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	history, getErr := services.UserServ.GetStatusHistory(c.Request.Context(), userID)
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, history)
+}
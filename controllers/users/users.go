@@ -2,6 +2,7 @@ package users
 
 import (
 	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/sauravgsh16/bookstore_users-api/domain/users"
 	"github.com/sauravgsh16/bookstore_users-api/services"
 	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/pagination"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
 )
 
 func getUserID(idStr string) (int, *errors.RestErr) {
@@ -25,115 +28,231 @@ func getUserID(idStr string) (int, *errors.RestErr) {
 func Get(c *gin.Context) {
 	userID, err := getUserID(c.Param("user_id"))
 	if err != nil {
-		c.JSON(err.Status, err)
+		render.Respond(c, err.Status, err)
 		return
 	}
 
-	user, getErr := services.UserServ.GetUser(userID)
+	projection, err := getProjection(c)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	includeDeleted := c.Query("include_deleted") == "true"
+	user, getErr := services.UserServ.GetUser(c.Request.Context(), userID, includeDeleted)
 	if getErr != nil {
-		c.JSON(getErr.Status, getErr)
+		render.Respond(c, getErr.Status, getErr)
 		return
 	}
 
-	isPublic := c.GetHeader("X-Public") == "true"
-	c.JSON(http.StatusOK, user.Marshall(isPublic))
+	etag := setETag(c, user, projection)
+	// the entity tag follows the user version, which profile changes do not bump
+	if !projection.Has("profile") && matchesIfNoneMatch(c, etag) {
+		// the representation, and so the tag, depends on the negotiated format as well
+		c.Writer.Header().Add("Vary", "Accept")
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	respondUser(c, http.StatusOK, user, projection)
 }
 
 // Create creates a new user
 func Create(c *gin.Context) {
+	projection, projErr := getProjection(c)
+	if projErr != nil {
+		render.Respond(c, projErr.Status, projErr)
+		return
+	}
+
 	var user users.User
 
-	// ShouldBindJSON - read request body and unmarshals the []bytes to user
-	if err := c.ShouldBindJSON(&user); err != nil {
-		bdErr := errors.NewBadRequestError(fmt.Sprintf("invalid json body: %s", err.Error()))
-		c.JSON(bdErr.Status, bdErr)
+	// Bind - read request body and unmarshals it to user according to its content type
+	if err := render.Bind(c, &user); err != nil {
+		render.Respond(c, err.Status, err)
 		return
 	}
 
-	result, err := services.UserServ.CreateUser(user)
+	result, err := services.UserServ.CreateUser(c.Request.Context(), user, getCaller(c))
 	if err != nil {
-		c.JSON(err.Status, err)
+		render.Respond(c, err.Status, err)
 		return
 	}
 
-	isPublic := c.GetHeader("X-Public") == "true"
-	c.JSON(http.StatusCreated, result.Marshall(isPublic))
+	setETag(c, result, projection)
+	respondUser(c, http.StatusCreated, result, projection)
 }
 
 // Update updates a user
 func Update(c *gin.Context) {
 	userID, err := getUserID(c.Param("user_id"))
 	if err != nil {
-		c.JSON(err.Status, err)
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	projection, err := getProjection(c)
+	if err != nil {
+		render.Respond(c, err.Status, err)
 		return
 	}
 
 	var newUser users.User
-	if err := c.ShouldBindJSON(&newUser); err != nil {
-		bdErr := errors.NewBadRequestError(fmt.Sprintf("invalid json body %s", err.Error()))
-		c.JSON(bdErr.Status, bdErr)
+	if err := render.Bind(c, &newUser); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	version, err := getIfMatchVersion(c)
+	if err != nil {
+		render.Respond(c, err.Status, err)
 		return
 	}
 
 	newUser.ID = userID
+	newUser.Version = version
 	isPartial := c.Request.Method == http.MethodPatch
 
-	result, updateErr := services.UserServ.UpdateUser(newUser, isPartial)
+	result, updateErr := services.UserServ.UpdateUser(c.Request.Context(), newUser, isPartial, getCaller(c))
+	if updateErr != nil {
+		render.Respond(c, updateErr.Status, updateErr)
+		return
+	}
+
+	setETag(c, result, projection)
+	respondUser(c, http.StatusOK, result, projection)
+}
+
+// Patch applies a merge patch or JSON Patch to a user, according to the Content-Type. Other
+// bodies update the fields they set, as Update does.
+func Patch(c *gin.Context) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != users.PatchMerge && mediaType != users.PatchJSON {
+		Update(c)
+		return
+	}
+
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	projection, err := getProjection(c)
 	if err != nil {
-		c.JSON(updateErr.Status, updateErr)
+		render.Respond(c, err.Status, err)
 		return
 	}
 
-	isPublic := c.GetHeader("X-Public") == "true"
-	c.JSON(http.StatusOK, result.Marshall(isPublic))
+	version, err := getIfMatchVersion(c)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	document, readErr := c.GetRawData()
+	if readErr != nil {
+		bdErr := errors.NewBadRequestError(fmt.Sprintf("failed to read request body: %s", readErr.Error()))
+		render.Respond(c, bdErr.Status, bdErr)
+		return
+	}
+
+	patch := users.Patch{MediaType: mediaType, Document: document}
+	result, patchErr := services.UserServ.PatchUser(c.Request.Context(), userID, version, patch, getCaller(c))
+	if patchErr != nil {
+		render.Respond(c, patchErr.Status, patchErr)
+		return
+	}
+
+	setETag(c, result, projection)
+	respondUser(c, http.StatusOK, result, projection)
 }
 
 // Delete a user from db
 func Delete(c *gin.Context) {
 	userID, err := getUserID(c.Param("user_id"))
 	if err != nil {
-		c.JSON(err.Status, err)
+		render.Respond(c, err.Status, err)
 		return
 	}
 
-	if err := services.UserServ.DeleteUser(userID); err != nil {
-		c.JSON(err.Status, err)
+	version, err := getIfMatchVersion(c)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	if err := services.UserServ.DeleteUser(c.Request.Context(), userID, version, getCaller(c)); err != nil {
+		render.Respond(c, err.Status, err)
 		return
 	}
-	c.JSON(http.StatusOK, map[string]string{"status": "deleted"})
+	render.Respond(c, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
 // Search searches all users
 func Search(c *gin.Context) {
+	projection, projErr := getProjection(c)
+	if projErr != nil {
+		render.Respond(c, projErr.Status, projErr)
+		return
+	}
+
 	status := c.Query("status")
+	includeDeleted := c.Query("include_deleted") == "true"
 
-	users, err := services.UserServ.SearchUser(status)
+	users, err := services.UserServ.SearchUser(c.Request.Context(), status, includeDeleted)
 	if err != nil {
-		c.JSON(err.Status, err)
+		render.Respond(c, err.Status, err)
 		return
 	}
 
-	isPublic := c.GetHeader("X-Public") == "true"
-	c.JSON(http.StatusOK, users.Marshall(isPublic))
+	render.Respond(c, http.StatusOK, users.Marshall(projection))
 }
 
 // LoginUser logs in a user
 func LoginUser(c *gin.Context) {
+	projection, projErr := getProjection(c)
+	if projErr != nil {
+		render.Respond(c, projErr.Status, projErr)
+		return
+	}
+
 	var req users.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		rstErr := errors.NewBadRequestError("invalid request body")
-		c.JSON(rstErr.Status, rstErr)
+	if err := render.Bind(c, &req); err != nil {
+		render.Respond(c, err.Status, err)
 		return
 	}
 
 	req.Password = crypto.GetMd5(req.Password)
 
-	user, err := services.UserServ.LoginUser(req)
+	user, err := services.UserServ.LoginUser(c.Request.Context(), req)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	signIn(c, user)
+	respondUser(c, http.StatusOK, user, projection)
+}
+
+// GetAuditLog returns a page of the audit log of a user
+func GetAuditLog(c *gin.Context) {
+	userID, err := getUserID(c.Param("user_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	page, perPage, err := pagination.Parse(c.Query("page"), c.Query("per_page"))
 	if err != nil {
-		c.JSON(err.Status, err)
+		render.Respond(c, err.Status, err)
 		return
 	}
 
-	isPublic := c.GetHeader("X-Public") == "true"
-	c.JSON(http.StatusOK, user.Marshall(isPublic))
+	result, getErr := services.UserServ.GetAuditLog(c.Request.Context(), userID, page, perPage)
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, result)
 }
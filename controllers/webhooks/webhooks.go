@@ -0,0 +1,122 @@
+package webhooks
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/webhooks"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/pagination"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+func getSubscriptionID(idStr string) (int, *errors.RestErr) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return 0, errors.NewBadRequestError("webhook id should be a number")
+	}
+	return int(id), nil
+}
+
+// Create registers a webhook subscription
+func Create(c *gin.Context) {
+	var sub webhooks.Subscription
+	if err := render.Bind(c, &sub); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	result, err := services.WebhookServ.CreateSubscription(c.Request.Context(), sub)
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	render.Respond(c, http.StatusCreated, result)
+}
+
+// List returns all webhook subscriptions
+func List(c *gin.Context) {
+	result, err := services.WebhookServ.GetSubscriptions(c.Request.Context())
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	render.Respond(c, http.StatusOK, result)
+}
+
+// Get returns a webhook subscription
+func Get(c *gin.Context) {
+	id, err := getSubscriptionID(c.Param("webhook_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	result, getErr := services.WebhookServ.GetSubscription(c.Request.Context(), id)
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, result)
+}
+
+// Delete removes a webhook subscription
+func Delete(c *gin.Context) {
+	id, err := getSubscriptionID(c.Param("webhook_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	if err := services.WebhookServ.DeleteSubscription(c.Request.Context(), id); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	render.Respond(c, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// Deliveries returns a page of the delivery log of a subscription
+func Deliveries(c *gin.Context) {
+	id, err := getSubscriptionID(c.Param("webhook_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	page, perPage, err := pagination.Parse(c.Query("page"), c.Query("per_page"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	result, getErr := services.WebhookServ.GetDeliveries(c.Request.Context(), id, page, perPage)
+	if getErr != nil {
+		render.Respond(c, getErr.Status, getErr)
+		return
+	}
+	render.Respond(c, http.StatusOK, result)
+}
+
+// Redeliver schedules a delivery to be sent again
+func Redeliver(c *gin.Context) {
+	id, err := getSubscriptionID(c.Param("webhook_id"))
+	if err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+
+	deliveryID, parseErr := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if parseErr != nil {
+		bdErr := errors.NewBadRequestError("delivery id should be a number")
+		render.Respond(c, bdErr.Status, bdErr)
+		return
+	}
+
+	if err := services.WebhookServ.Redeliver(c.Request.Context(), id, deliveryID); err != nil {
+		render.Respond(c, err.Status, err)
+		return
+	}
+	render.Respond(c, http.StatusAccepted, map[string]string{"status": "scheduled"})
+}
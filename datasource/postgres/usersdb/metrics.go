@@ -0,0 +1,196 @@
+package usersdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/metrics"
+)
+
+// driverName is the pq driver timing every statement it runs
+const driverName = "postgres-instrumented"
+
+var (
+	queryDuration = metrics.NewHistogram("db_query_duration_seconds",
+		"Time taken by the statements run on the users database, by statement kind and table",
+		metrics.DefBuckets, "statement", "table")
+	queryErrors = metrics.NewCounter("db_query_errors_total",
+		"Statements run on the users database that failed, by statement kind and table", "statement", "table")
+
+	// queryLabels caches the labels of each query, queries being constants of the DAOs
+	queryLabels sync.Map
+
+	tableName = regexp.MustCompile(`^[a-z_][a-z0-9_.]*`)
+)
+
+// knownStatements are the statement kinds given their own label value
+var knownStatements = map[string]bool{
+	"select": true, "insert": true, "update": true, "delete": true, "with": true, "copy": true,
+	"savepoint": true, "release": true, "rollback": true,
+}
+
+func init() {
+	poolGauge := func(name, help string, value func(sql.DBStats) float64) {
+		metrics.NewGaugeFunc(name, help, []string{"pool"}, func() []metrics.Sample { return poolSamples(value) })
+	}
+	poolCounter := func(name, help string, value func(sql.DBStats) float64) {
+		metrics.NewCounterFunc(name, help, []string{"pool"}, func() []metrics.Sample { return poolSamples(value) })
+	}
+	poolGauge("db_pool_max_open_connections", "Maximum number of open connections of the pool",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	poolGauge("db_pool_open_connections", "Connections of the pool, in use or idle",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	poolGauge("db_pool_in_use_connections", "Connections of the pool in use",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	poolGauge("db_pool_idle_connections", "Idle connections of the pool",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	poolCounter("db_pool_wait_count_total", "Times a connection had to be waited for",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	poolCounter("db_pool_wait_duration_seconds_total", "Time spent waiting for a connection",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	poolCounter("db_pool_max_idle_closed_total", "Connections closed as the pool had too many idle ones",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	poolCounter("db_pool_max_lifetime_closed_total", "Connections closed as they reached their maximum lifetime",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
+
+// poolSamples reads a statistic of the primary, labelled primary, and of each replica, labelled
+// with its address
+func poolSamples(value func(sql.DBStats) float64) []metrics.Sample {
+	if DB.primary == nil {
+		return nil
+	}
+	samples := []metrics.Sample{{Values: []string{"primary"}, Value: value(DB.primary.conn.Stats())}}
+	for _, r := range DB.replicas {
+		samples = append(samples, metrics.Sample{Values: []string{r.addr}, Value: value(r.conn.Stats())})
+	}
+	return samples
+}
+
+// labelsOf returns the statement kind and table of query, such as select and users
+func labelsOf(query string) [2]string {
+	if labels, ok := queryLabels.Load(query); ok {
+		return labels.([2]string)
+	}
+
+	labels := [2]string{"other", "none"}
+	fields := strings.Fields(strings.ToLower(query))
+	if len(fields) > 0 && knownStatements[fields[0]] {
+		labels[0] = fields[0]
+		var keyword string
+		switch fields[0] {
+		case "select", "delete", "with":
+			keyword = "from"
+		case "insert":
+			keyword = "into"
+		case "update", "copy":
+			keyword = fields[0]
+		}
+		for i := 0; keyword != "" && i < len(fields)-1; i++ {
+			if fields[i] == keyword {
+				if table := tableName.FindString(fields[i+1]); table != "" {
+					labels[1] = table
+				}
+				break
+			}
+		}
+	}
+	queryLabels.Store(query, labels)
+	return labels
+}
+
+func observe(query string, start time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	labels := labelsOf(query)
+	queryDuration.Observe(time.Since(start).Seconds(), labels[0], labels[1])
+	if err != nil {
+		queryErrors.Inc(labels[0], labels[1])
+	}
+}
+
+// instrumentedDriver wraps the connections of a driver to time their statements
+type instrumentedDriver struct {
+	driver.Driver
+}
+
+func (d instrumentedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn}, nil
+}
+
+type instrumentedConn struct {
+	driver.Conn
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	observe(query, start, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	observe(query, start, err)
+	return res, err
+}
+
+type instrumentedStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s *instrumentedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.Stmt.Exec(args)
+	observe(s.query, start, err)
+	return res, err
+}
+
+func (s *instrumentedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.Stmt.Query(args)
+	observe(s.query, start, err)
+	return rows, err
+}
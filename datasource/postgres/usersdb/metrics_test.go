@@ -0,0 +1,26 @@
+package usersdb
+
+import "testing"
+
+func TestLabelsOf(t *testing.T) {
+	tests := []struct {
+		query string
+		want  [2]string
+	}{
+		{`SELECT id, email FROM users WHERE id=$1;`, [2]string{"select", "users"}},
+		{`INSERT INTO user_outbox(aggregate_id) VALUES ($1);`, [2]string{"insert", "user_outbox"}},
+		{`UPDATE users SET status=$1 WHERE id=$2;`, [2]string{"update", "users"}},
+		{`DELETE FROM idempotency_keys WHERE expires_at <= ($1);`, [2]string{"delete", "idempotency_keys"}},
+		{"WITH due AS (SELECT id FROM user_outbox)\n\tUPDATE user_outbox SET attempts=attempts+1;", [2]string{"with", "user_outbox"}},
+		{`COPY users (email) FROM STDIN`, [2]string{"copy", "users"}},
+		{`SAVEPOINT sp_1`, [2]string{"savepoint", "none"}},
+		{`SELECT pg_try_advisory_xact_lock($1);`, [2]string{"select", "none"}},
+		{`VACUUM users`, [2]string{"other", "none"}},
+		{``, [2]string{"other", "none"}},
+	}
+	for _, tt := range tests {
+		if got := labelsOf(tt.query); got != tt.want {
+			t.Errorf("labelsOf(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS users (
+    id           SERIAL PRIMARY KEY,
+    first_name   VARCHAR(255),
+    last_name    VARCHAR(255),
+    email        VARCHAR(255) NOT NULL UNIQUE,
+    date_created TIMESTAMP NOT NULL,
+    status       VARCHAR(45) NOT NULL,
+    password     VARCHAR(32) NOT NULL
+);
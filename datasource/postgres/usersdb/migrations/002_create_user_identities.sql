@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider     VARCHAR(64) NOT NULL,
+    subject      VARCHAR(255) NOT NULL,
+    email        VARCHAR(255),
+    date_created TIMESTAMP NOT NULL,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
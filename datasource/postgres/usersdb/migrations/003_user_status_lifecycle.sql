@@ -0,0 +1,17 @@
+UPDATE users SET status = 'deactivated' WHERE status = 'inactive';
+
+ALTER TABLE users ADD CONSTRAINT users_status_check
+    CHECK (status IN ('pending', 'active', 'suspended', 'deactivated', 'deleted'));
+
+CREATE TABLE IF NOT EXISTS user_status_changes (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action       VARCHAR(45) NOT NULL,
+    from_status  VARCHAR(45) NOT NULL,
+    to_status    VARCHAR(45) NOT NULL,
+    reason       TEXT NOT NULL DEFAULT '',
+    actor        VARCHAR(255) NOT NULL,
+    date_created TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_status_changes_user_id_idx ON user_status_changes(user_id);
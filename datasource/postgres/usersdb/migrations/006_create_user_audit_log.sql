@@ -0,0 +1,28 @@
+-- user_id deliberately has no foreign key so entries outlive purged users
+CREATE TABLE IF NOT EXISTS user_audit_log (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL,
+    actor        VARCHAR(255) NOT NULL,
+    action       VARCHAR(45) NOT NULL,
+    changes      JSONB NOT NULL,
+    request_id   VARCHAR(128) NOT NULL DEFAULT '',
+    date_created TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_audit_log_user_id_idx ON user_audit_log(user_id, id DESC);
+
+CREATE OR REPLACE FUNCTION user_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'user_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_audit_log_no_update ON user_audit_log;
+CREATE TRIGGER user_audit_log_no_update
+    BEFORE UPDATE OR DELETE ON user_audit_log
+    FOR EACH ROW EXECUTE PROCEDURE user_audit_log_append_only();
+
+DROP TRIGGER IF EXISTS user_audit_log_no_truncate ON user_audit_log;
+CREATE TRIGGER user_audit_log_no_truncate
+    BEFORE TRUNCATE ON user_audit_log
+    FOR EACH STATEMENT EXECUTE PROCEDURE user_audit_log_append_only();
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_outbox (
+    id           BIGSERIAL PRIMARY KEY,
+    aggregate_id INTEGER NOT NULL,
+    event_type   VARCHAR(64) NOT NULL,
+    payload      JSONB NOT NULL,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    date_created TIMESTAMP NOT NULL,
+    published_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_outbox_pending_idx ON user_outbox(id) WHERE published_at IS NULL;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id           SERIAL PRIMARY KEY,
+    url          TEXT NOT NULL,
+    event_types  TEXT[] NOT NULL,
+    secret       VARCHAR(255) NOT NULL,
+    date_created TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    subscription_id  INTEGER NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id         BIGINT NOT NULL,
+    event_type       VARCHAR(64) NOT NULL,
+    payload          JSONB NOT NULL,
+    status           VARCHAR(16) NOT NULL,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP NULL,
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error       TEXT NOT NULL DEFAULT '',
+    date_created     TIMESTAMP NOT NULL,
+    date_delivered   TIMESTAMP NULL,
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries(subscription_id, id DESC);
@@ -0,0 +1,35 @@
+-- erased users keep their row, with tombstones in place of personal data, and are never purged
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP NULL;
+
+-- user_redact_pii replaces the personal data held in an audit change set or event payload
+CREATE OR REPLACE FUNCTION user_redact_pii(doc JSONB) RETURNS JSONB AS $$
+DECLARE
+    k TEXT;
+BEGIN
+    FOREACH k IN ARRAY ARRAY['first_name', 'last_name', 'email', 'old_email', 'new_email'] LOOP
+        IF doc ? k THEN
+            IF jsonb_typeof(doc -> k) = 'object' THEN
+                doc := jsonb_set(doc, ARRAY[k], '{"from": "[REDACTED]", "to": "[REDACTED]"}');
+            ELSE
+                doc := jsonb_set(doc, ARRAY[k], '"[REDACTED]"');
+            END IF;
+        END IF;
+    END LOOP;
+    IF jsonb_typeof(doc -> 'changes') = 'object' THEN
+        doc := jsonb_set(doc, '{changes}', user_redact_pii(doc -> 'changes'));
+    END IF;
+    RETURN doc;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- an erasure, and nothing else, may redact the changes of existing entries
+CREATE OR REPLACE FUNCTION user_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND current_setting('users.erasing', true) = 'on'
+        AND (NEW.id, NEW.user_id, NEW.actor, NEW.action, NEW.request_id, NEW.date_created)
+            IS NOT DISTINCT FROM (OLD.id, OLD.user_id, OLD.actor, OLD.action, OLD.request_id, OLD.date_created) THEN
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'user_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id               INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    phone                 VARCHAR(16) NOT NULL DEFAULT '',
+    date_of_birth         DATE NULL,
+    locale                VARCHAR(35) NOT NULL DEFAULT '',
+    timezone              VARCHAR(64) NOT NULL DEFAULT '',
+    avatar_content_type   VARCHAR(32) NOT NULL DEFAULT '',
+    date_updated          TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_addresses (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type         VARCHAR(16) NOT NULL CHECK (type IN ('shipping', 'billing')),
+    is_default   BOOLEAN NOT NULL DEFAULT FALSE,
+    recipient    VARCHAR(255) NOT NULL DEFAULT '',
+    line1        VARCHAR(255) NOT NULL,
+    line2        VARCHAR(255) NOT NULL DEFAULT '',
+    city         VARCHAR(255) NOT NULL,
+    region       VARCHAR(255) NOT NULL DEFAULT '',
+    postal_code  VARCHAR(32) NOT NULL DEFAULT '',
+    country      CHAR(2) NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    date_updated TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_addresses_user_id_idx ON user_addresses(user_id, id);
+-- a user has at most one default address of each type
+CREATE UNIQUE INDEX IF NOT EXISTS user_addresses_default_idx ON user_addresses(user_id, type) WHERE is_default;
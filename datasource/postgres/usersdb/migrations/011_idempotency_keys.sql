@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key          VARCHAR(255) PRIMARY KEY,
+    fingerprint  CHAR(64) NOT NULL,
+    status       INTEGER NULL,
+    header       JSONB NULL,
+    body         BYTEA NULL,
+    locked_until TIMESTAMP NOT NULL,
+    expires_at   TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys(expires_at);
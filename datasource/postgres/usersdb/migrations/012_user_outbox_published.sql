@@ -0,0 +1,2 @@
+-- lets the event stream load the messages published last
+CREATE INDEX IF NOT EXISTS user_outbox_published_idx ON user_outbox(published_at, id) WHERE published_at IS NOT NULL;
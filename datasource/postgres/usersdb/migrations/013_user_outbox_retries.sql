@@ -0,0 +1,9 @@
+-- failed messages are retried with backoff and set aside once they run out of attempts
+ALTER TABLE user_outbox ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP NULL;
+ALTER TABLE user_outbox ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE user_outbox ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP NULL;
+
+DROP INDEX IF EXISTS user_outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS user_outbox_pending_idx ON user_outbox(id) WHERE published_at IS NULL AND dead_at IS NULL;
+-- lets the relay hold back the messages of a user while an earlier one waits for a retry
+CREATE INDEX IF NOT EXISTS user_outbox_pending_aggregate_idx ON user_outbox(aggregate_id, id) WHERE published_at IS NULL AND dead_at IS NULL;
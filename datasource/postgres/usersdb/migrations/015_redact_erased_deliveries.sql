@@ -0,0 +1,4 @@
+-- deliveries hold the event envelope, whose payload erasures used to leave in clear
+UPDATE webhook_deliveries d SET payload=jsonb_set(d.payload, '{payload}', user_redact_pii(d.payload->'payload'))
+    FROM user_outbox o JOIN users u ON u.id = o.aggregate_id
+    WHERE d.event_id = o.id AND u.erased_at IS NOT NULL AND jsonb_typeof(d.payload->'payload') = 'object';
@@ -0,0 +1,71 @@
+package usersdb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// postgres query_canceled, raised when statement_timeout or a cancel request fires
+const codeQueryCanceled = "57014"
+
+// Prepare returns the cached statement for query and ctx bounded by the timeout configured
+// for op. Reads and searches may be served by a replica. The statement joins the ambient
+// transaction of ctx, if any. cancel must be called once the statement results are consumed.
+func Prepare(ctx context.Context, op, query string) (*sql.Stmt, context.Context, context.CancelFunc, *errors.RestErr) {
+	ctx, cancel := WithTimeout(ctx, op)
+	var (
+		stmt *sql.Stmt
+		err  error
+	)
+	if ReadOnly(op) {
+		stmt, err = DB.PrepareRead(ctx, query)
+	} else {
+		stmt, err = DB.Prepare(ctx, query)
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, nil, PrepareError(err)
+	}
+	if tx, ok := FromContext(ctx); ok {
+		stmt = tx.StmtContext(ctx, stmt)
+	}
+	return stmt, ctx, cancel, nil
+}
+
+// PrepareIn returns the cached statement for query bound to tx
+func PrepareIn(ctx context.Context, tx *sql.Tx, query string) (*sql.Stmt, *errors.RestErr) {
+	stmt, err := DB.Prepare(ctx, query)
+	if err != nil {
+		return nil, PrepareError(err)
+	}
+	return tx.StmtContext(ctx, stmt), nil
+}
+
+// PrepareError turns an error getting a connection or preparing a statement into a RestErr
+func PrepareError(err error) *errors.RestErr {
+	if IsTimeout(err) {
+		logger.Error("timed out waiting for a database connection: ", err)
+		return errors.NewServiceUnavailableError("database unavailable, try again later")
+	}
+	if _, ok := err.(*pq.Error); ok {
+		logger.Error("failed to prepare statement: ", err)
+		return errors.NewInternalServerError("database error")
+	}
+	logger.Error("failed to get database connection: ", err)
+	return errors.NewServiceUnavailableError("database unavailable, try again later")
+}
+
+// IsTimeout reports whether err comes from a deadline or cancellation, either
+// on the client side or as postgres' query_canceled
+func IsTimeout(err error) bool {
+	if err == context.DeadlineExceeded || err == context.Canceled {
+		return true
+	}
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == codeQueryCanceled
+}
@@ -0,0 +1,191 @@
+package usersdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+)
+
+const (
+	// queryPrimaryLSN returns the current write position of the primary
+	queryPrimaryLSN = `SELECT pg_current_wal_lsn()::text;`
+	// queryReplicaStatus reports whether the server is a standby and how far behind the primary
+	// it is, given the position the primary had written up to. A standby that replayed up to it
+	// is not lagging, even if the primary has been idle since the last replayed transaction.
+	// Otherwise, and when the primary position is unknown, the lag is the time since the last
+	// replayed transaction, which keeps growing on a standby disconnected from the primary.
+	queryReplicaStatus = `SELECT pg_is_in_recovery(),
+	CASE WHEN ($1)::pg_lsn IS NOT NULL AND pg_last_wal_replay_lsn() >= ($1)::pg_lsn THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())::float8, 'Infinity'::float8) END;`
+)
+
+var (
+	maxReplicaLag        = config.GetDuration("DB_REPLICA_MAX_LAG", 10*time.Second)
+	replicaCheckInterval = config.GetDuration("DB_REPLICA_CHECK_INTERVAL", 5*time.Second)
+	readYourWrites       = config.GetBool("DB_READ_YOUR_WRITES", true)
+)
+
+// replica is a read only standby, only used while healthy and within the lag limit
+type replica struct {
+	*pool
+	// primary is the pool the replica lag is measured against
+	primary *pool
+	addr    string
+	healthy int32
+	lag     int64
+}
+
+// openReplicas connects to the standbys listed as host:port and starts checking their health
+func (db *dbConn) openReplicas(addrs []string) error {
+	for _, addr := range addrs {
+		host, portStr, err := net.SplitHostPort(addr)
+		if err != nil {
+			return fmt.Errorf("invalid replica address %q: %v", addr, err)
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return fmt.Errorf("invalid replica port %q: %v", addr, err)
+		}
+		p, err := openPool(host, port)
+		if err != nil {
+			return err
+		}
+		r := &replica{pool: p, primary: db.primary, addr: addr}
+		r.check()
+		db.replicas = append(db.replicas, r)
+		go r.monitor()
+	}
+	if len(db.replicas) > 0 {
+		log.Printf("Configured %d read replicas", len(db.replicas))
+	}
+	return nil
+}
+
+func (r *replica) monitor() {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.check()
+	}
+}
+
+// check marks the replica healthy if it answers as a standby within the lag limit
+func (r *replica) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), replicaCheckInterval)
+	defer cancel()
+
+	// the primary position stays unknown if the primary cannot be reached
+	var primaryLSN sql.NullString
+	r.primary.conn.QueryRowContext(ctx, queryPrimaryLSN).Scan(&primaryLSN)
+
+	var (
+		standby bool
+		lagSecs float64
+	)
+	err := r.conn.QueryRowContext(ctx, queryReplicaStatus, primaryLSN).Scan(&standby, &lagSecs)
+	lag := maxLag
+	if lagSecs < maxLag.Seconds() {
+		lag = time.Duration(lagSecs * float64(time.Second))
+	}
+	healthy := err == nil && standby && lag <= maxReplicaLag
+
+	atomic.StoreInt64(&r.lag, int64(lag))
+	if was := atomic.SwapInt32(&r.healthy, boolToInt32(healthy)) == 1; was != healthy {
+		switch {
+		case err != nil:
+			log.Printf("Replica %s unavailable: %v", r.addr, err)
+		case !standby:
+			log.Printf("Replica %s is not in recovery, ignoring it", r.addr)
+		case lag == maxLag:
+			log.Printf("Replica %s is behind the primary and never replayed a transaction", r.addr)
+		case !healthy:
+			log.Printf("Replica %s lagging by %s, over the %s limit", r.addr, lag, maxReplicaLag)
+		default:
+			log.Printf("Replica %s available", r.addr)
+		}
+	}
+}
+
+// maxLag is reported for standbys that never replayed a transaction
+const maxLag = time.Duration(math.MaxInt64)
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// PrepareRead returns the statement for a read only query on a healthy replica, picked round
+// robin. It falls back to the primary when no replica can serve it, when ctx carries a
+// transaction or is pinned to the primary, or when the session of ctx has written and read
+// your writes is enabled.
+func (db *dbConn) PrepareRead(ctx context.Context, query string) (*sql.Stmt, error) {
+	if _, inTx := FromContext(ctx); inTx || onPrimary(ctx) || (readYourWrites && hasWritten(ctx)) {
+		return db.primary.prepare(ctx, query)
+	}
+
+	n := len(db.replicas)
+	start := int(atomic.AddUint32(&db.next, 1))
+	for i := 0; i < n; i++ {
+		r := db.replicas[(start+i)%n]
+		if !r.isHealthy() {
+			continue
+		}
+		stmt, err := r.prepare(ctx, query)
+		if err == nil {
+			return stmt, nil
+		}
+		log.Printf("Replica %s failed to prepare statement, trying next: %v", r.addr, err)
+	}
+	return db.primary.prepare(ctx, query)
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a copy of ctx whose reads are served by the primary, for reads that must
+// not see a lagging replica
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+func onPrimary(ctx context.Context) bool {
+	pinned, _ := ctx.Value(primaryKey{}).(bool)
+	return pinned
+}
+
+type sessionKey struct{}
+
+// session tracks whether a request wrote to the primary
+type session struct {
+	written int32
+}
+
+// WithSession returns a copy of ctx whose reads are pinned to the primary once it has written,
+// so a request reads its own writes whatever the replica lag
+func WithSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+func markWrite(ctx context.Context) {
+	if s, ok := ctx.Value(sessionKey{}).(*session); ok {
+		atomic.StoreInt32(&s.written, 1)
+	}
+}
+
+func hasWritten(ctx context.Context) bool {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && atomic.LoadInt32(&s.written) == 1
+}
@@ -0,0 +1,257 @@
+package usersdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fakeDriverName = "usersdb_fake"
+
+// fakeServer stands for a database server, answering queries with its respond function
+type fakeServer struct {
+	mu   sync.Mutex
+	down bool
+	// respond returns the single row answering query
+	respond func(query string, args []driver.Value) ([]driver.Value, error)
+	// args are those of the last query
+	args []driver.Value
+}
+
+var (
+	fakeServersMu sync.Mutex
+	fakeServers   = make(map[string]*fakeServer)
+)
+
+func init() {
+	sql.Register(fakeDriverName, fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	fakeServersMu.Lock()
+	defer fakeServersMu.Unlock()
+	s, ok := fakeServers[name]
+	if !ok {
+		return nil, errors.New("unknown fake server " + name)
+	}
+	return &fakeConn{server: s}, nil
+}
+
+type fakeConn struct {
+	server *fakeServer
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.server.down {
+		return nil, errors.New("connection refused")
+	}
+	return &fakeStmt{server: c.server, query: query}, nil
+}
+
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+type fakeStmt struct {
+	server *fakeServer
+	query  string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("exec not supported")
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.server.mu.Lock()
+	defer s.server.mu.Unlock()
+	if s.server.down {
+		return nil, errors.New("connection refused")
+	}
+	s.server.args = args
+	if s.server.respond == nil {
+		return nil, errors.New("no response for " + s.query)
+	}
+	row, err := s.server.respond(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{row: row}, nil
+}
+
+type fakeRows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *fakeRows) Columns() []string { return make([]string, len(r.row)) }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+// newFakePool returns a pool connected to a new fake server named name
+func newFakePool(t *testing.T, name string) (*pool, *fakeServer) {
+	t.Helper()
+	s := &fakeServer{}
+	fakeServersMu.Lock()
+	fakeServers[name] = s
+	fakeServersMu.Unlock()
+
+	conn, err := sql.Open(fakeDriverName, name)
+	if err != nil {
+		t.Fatalf("opening %s: %v", name, err)
+	}
+	return &pool{conn: conn, stmts: make(map[string]*sql.Stmt)}, s
+}
+
+// servedBy returns the address of the replica that prepared stmt, primary for the primary
+func servedBy(db *dbConn, query string, stmt *sql.Stmt) string {
+	if db.primary.stmts[query] == stmt {
+		return "primary"
+	}
+	for _, r := range db.replicas {
+		if r.stmts[query] == stmt {
+			return r.addr
+		}
+	}
+	return "unknown"
+}
+
+func TestPrepareRead(t *testing.T) {
+	defer func(enabled bool) { readYourWrites = enabled }(readYourWrites)
+	const query = `SELECT id FROM users WHERE id=$1;`
+
+	written := WithSession(context.Background())
+	markWrite(written)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		// healthy and down tell the state of each replica
+		healthy        []bool
+		down           []bool
+		readYourWrites bool
+		want           []string
+	}{
+		{name: "no replicas", ctx: context.Background(), want: []string{"primary", "primary"}},
+		{name: "round robin", ctx: context.Background(), healthy: []bool{true, true}, down: []bool{false, false}, want: []string{"r1", "r0", "r1", "r0"}},
+		{name: "unhealthy replica skipped", ctx: context.Background(), healthy: []bool{false, true}, down: []bool{false, false}, want: []string{"r1", "r1"}},
+		{name: "no healthy replica", ctx: context.Background(), healthy: []bool{false, false}, down: []bool{false, false}, want: []string{"primary", "primary"}},
+		{name: "replica failing to prepare", ctx: context.Background(), healthy: []bool{true, true}, down: []bool{true, false}, want: []string{"r1", "r1"}},
+		{name: "every replica failing to prepare", ctx: context.Background(), healthy: []bool{true}, down: []bool{true}, want: []string{"primary"}},
+		{name: "in a transaction", ctx: NewContext(context.Background(), nil), healthy: []bool{true}, down: []bool{false}, want: []string{"primary"}},
+		{name: "pinned to the primary", ctx: WithPrimary(context.Background()), healthy: []bool{true}, down: []bool{false}, want: []string{"primary"}},
+		{name: "session that wrote", ctx: written, healthy: []bool{true}, down: []bool{false}, readYourWrites: true, want: []string{"primary"}},
+		{name: "session that wrote without read your writes", ctx: written, healthy: []bool{true}, down: []bool{false}, want: []string{"r0"}},
+		{name: "session that did not write", ctx: WithSession(context.Background()), healthy: []bool{true}, down: []bool{false}, readYourWrites: true, want: []string{"r0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readYourWrites = tt.readYourWrites
+			primary, _ := newFakePool(t, t.Name()+"/primary")
+			db := &dbConn{primary: primary}
+			for i, healthy := range tt.healthy {
+				addr := fmt.Sprintf("r%d", i)
+				p, s := newFakePool(t, t.Name()+"/"+addr)
+				s.down = tt.down[i]
+				db.replicas = append(db.replicas, &replica{pool: p, primary: primary, addr: addr, healthy: boolToInt32(healthy)})
+			}
+
+			var got []string
+			for range tt.want {
+				stmt, err := db.PrepareRead(tt.ctx, query)
+				if err != nil {
+					t.Fatalf("PrepareRead: %v", err)
+				}
+				got = append(got, servedBy(db, query, stmt))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("served by %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("served by %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplicaCheck(t *testing.T) {
+	defer func(max time.Duration) { maxReplicaLag = max }(maxReplicaLag)
+	maxReplicaLag = 10 * time.Second
+	const primaryLSN = "0/3000060"
+
+	tests := []struct {
+		name        string
+		primaryDown bool
+		replicaDown bool
+		standby     bool
+		// lagSecs is what the standby reports
+		lagSecs     float64
+		wantLSN     interface{}
+		wantHealthy bool
+		wantLag     time.Duration
+	}{
+		{name: "caught up", standby: true, lagSecs: 0, wantLSN: primaryLSN, wantHealthy: true},
+		{name: "within the limit", standby: true, lagSecs: 3, wantLSN: primaryLSN, wantHealthy: true, wantLag: 3 * time.Second},
+		{name: "disconnected", standby: true, lagSecs: 30, wantLSN: primaryLSN, wantHealthy: false, wantLag: 30 * time.Second},
+		{name: "never replayed", standby: true, lagSecs: math.Inf(1), wantLSN: primaryLSN, wantHealthy: false, wantLag: maxLag},
+		{name: "not a standby", standby: false, lagSecs: 0, wantLSN: primaryLSN, wantHealthy: false},
+		{name: "primary unreachable", primaryDown: true, standby: true, lagSecs: 1, wantLSN: nil, wantHealthy: true, wantLag: time.Second},
+		{name: "replica unreachable", replicaDown: true, wantHealthy: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, ps := newFakePool(t, t.Name()+"/primary")
+			ps.down = tt.primaryDown
+			ps.respond = func(query string, args []driver.Value) ([]driver.Value, error) {
+				if query != queryPrimaryLSN {
+					return nil, errors.New("unexpected query on the primary: " + query)
+				}
+				return []driver.Value{primaryLSN}, nil
+			}
+			p, rs := newFakePool(t, t.Name()+"/replica")
+			rs.down = tt.replicaDown
+			rs.respond = func(query string, args []driver.Value) ([]driver.Value, error) {
+				if query != queryReplicaStatus {
+					return nil, errors.New("unexpected query on the replica: " + query)
+				}
+				return []driver.Value{tt.standby, tt.lagSecs}, nil
+			}
+			// the replica starts healthy so a failed check has to mark it otherwise
+			r := &replica{pool: p, primary: primary, addr: "replica", healthy: 1}
+
+			r.check()
+			if got := r.isHealthy(); got != tt.wantHealthy {
+				t.Errorf("healthy = %v, want %v", got, tt.wantHealthy)
+			}
+			if tt.replicaDown {
+				return
+			}
+			if len(rs.args) != 1 || rs.args[0] != tt.wantLSN {
+				t.Errorf("replica status queried with %v, want the primary position %v", rs.args, tt.wantLSN)
+			}
+			if got := time.Duration(r.lag); got != tt.wantLag {
+				t.Errorf("lag = %v, want %v", got, tt.wantLag)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package usersdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+)
+
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+)
+
+var isolationLevels = map[string]sql.IsolationLevel{
+	"read_committed":  sql.LevelReadCommitted,
+	"repeatable_read": sql.LevelRepeatableRead,
+	"serializable":    sql.LevelSerializable,
+}
+
+var (
+	// TxOptions are used for transactions started by the DAO, the isolation is set with DB_TX_ISOLATION
+	TxOptions = &sql.TxOptions{Isolation: sql.LevelReadCommitted}
+	// MaxTxAttempts bounds how many times a transaction aborted by a concurrent one is run
+	MaxTxAttempts = config.GetInt("DB_TX_MAX_ATTEMPTS", 3)
+)
+
+func init() {
+	name := strings.ToLower(config.GetString("DB_TX_ISOLATION", "read_committed"))
+	level, ok := isolationLevels[name]
+	if !ok {
+		panic(fmt.Sprintf("unknown DB_TX_ISOLATION %q", name))
+	}
+	TxOptions.Isolation = level
+}
+
+type txKey struct{}
+
+// Tx is the ambient transaction carried by a context, with the depth of savepoints opened in it
+type Tx struct {
+	*sql.Tx
+	depth int
+	// onCommit is shared by the savepoints of the transaction
+	onCommit *[]func()
+	// mark is the length of onCommit when the savepoint opened in t was
+	mark int
+}
+
+// NewContext returns a copy of ctx carrying tx as the ambient transaction
+func NewContext(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, &Tx{Tx: tx, onCommit: new([]func())})
+}
+
+// OnCommit runs fn once the ambient transaction of ctx commits, right away without one. fn is
+// dropped if a savepoint it was registered in is rolled back.
+func OnCommit(ctx context.Context, fn func()) {
+	if tx, ok := FromContext(ctx); ok {
+		*tx.onCommit = append(*tx.onCommit, fn)
+		return
+	}
+	fn()
+}
+
+// Committed runs the functions registered with OnCommit, once the transaction committed
+func (t *Tx) Committed() {
+	for _, fn := range *t.onCommit {
+		fn()
+	}
+}
+
+// FromContext returns the ambient transaction of ctx, if any
+func FromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	return tx, ok
+}
+
+// Savepoint opens a savepoint in the transaction, returning its name and a copy of ctx in
+// which further nested work opens deeper savepoints
+func (t *Tx) Savepoint(ctx context.Context) (string, context.Context, error) {
+	nested := &Tx{Tx: t.Tx, depth: t.depth + 1, onCommit: t.onCommit}
+	name := fmt.Sprintf("sp_%d", nested.depth)
+	if _, err := t.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return "", nil, err
+	}
+	t.mark = len(*t.onCommit)
+	return name, context.WithValue(ctx, txKey{}, nested), nil
+}
+
+// RollbackTo undoes the work done since the named savepoint, leaving the transaction usable
+func (t *Tx) RollbackTo(ctx context.Context, name string) error {
+	if _, err := t.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); err != nil {
+		return err
+	}
+	*t.onCommit = (*t.onCommit)[:t.mark]
+	return nil
+}
+
+// Release keeps the work done since the named savepoint
+func (t *Tx) Release(ctx context.Context, name string) error {
+	_, err := t.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+// IsRetryable reports whether err aborted the transaction because of a concurrent one,
+// in which case running it again may succeed
+func IsRetryable(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && (pqErr.Code == codeSerializationFailure || pqErr.Code == codeDeadlockDetected)
+}
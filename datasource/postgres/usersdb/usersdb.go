@@ -5,9 +5,13 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
-	// postgres driver
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
 )
 
 const (
@@ -18,38 +22,180 @@ const (
 	dBName = "bookstore"
 )
 
+const (
+	// OpRead covers single row lookups
+	OpRead = "read"
+	// OpWrite covers inserts, updates and their transactions
+	OpWrite = "write"
+	// OpSearch covers multi row queries
+	OpSearch = "search"
+	// OpMaintenance covers background jobs such as purges and relays
+	OpMaintenance = "maintenance"
+	// OpExport covers streaming every user out
+	OpExport = "export"
+)
+
+// default per operation timeouts, each overridable with DB_TIMEOUT_<OP>
+var timeouts = map[string]time.Duration{
+	OpRead:        2 * time.Second,
+	OpWrite:       5 * time.Second,
+	OpSearch:      10 * time.Second,
+	OpMaintenance: time.Minute,
+	OpExport:      10 * time.Minute,
+}
+
 // DB connection
 var DB dbConn
 
 type dbConn struct {
+	primary  *pool
+	replicas []*replica
+	next     uint32
+}
+
+// pool is a connection pool with the statements prepared on it
+type pool struct {
 	conn *sql.DB
+
+	mu    sync.RWMutex
+	stmts map[string]*sql.Stmt
 }
 
 func init() {
-	connInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		dBHost,
-		dBPort,
+	sql.Register(driverName, instrumentedDriver{&pq.Driver{}})
+	for op, d := range timeouts {
+		timeouts[op] = config.GetDuration("DB_TIMEOUT_"+strings.ToUpper(op), d)
+	}
+}
+
+// Open connects DB to the primary, and to the replicas listed in DB_REPLICAS
+func Open() error {
+	primary, err := openPool(dBHost, dBPort)
+	if err != nil {
+		return err
+	}
+	if err = primary.conn.Ping(); err != nil {
+		primary.close()
+		return err
+	}
+	DB = dbConn{primary: primary}
+	log.Println("Successfully configured database")
+
+	return DB.openReplicas(config.GetList("DB_REPLICAS"))
+}
+
+func connInfo(host string, port int) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		host,
+		port,
 		dBUser,
 		dBPwd,
 		dBName,
 	)
-	var err error
-	DB = dbConn{}
-	DB.conn, err = sql.Open("postgres", connInfo)
+}
+
+func openPool(host string, port int) (*pool, error) {
+	conn, err := sql.Open(driverName, connInfo(host, port))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	if err = DB.conn.Ping(); err != nil {
-		panic(err)
-	}
-	log.Println("Successfully configured database")
+	conn.SetMaxOpenConns(config.GetInt("DB_MAX_OPEN_CONNS", 20))
+	conn.SetMaxIdleConns(config.GetInt("DB_MAX_IDLE_CONNS", 5))
+	conn.SetConnMaxLifetime(config.GetDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute))
+	return &pool{conn: conn, stmts: make(map[string]*sql.Stmt)}, nil
 }
 
-func (db *dbConn) GetConn(ctx context.Context) *sql.Conn {
-	conn, err := db.conn.Conn(ctx)
+// prepare returns the statement for query, preparing it the first time it is used
+func (p *pool) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	p.mu.RLock()
+	stmt, ok := p.stmts[query]
+	p.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := p.conn.PrepareContext(ctx, query)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
+	}
+	p.stmts[query] = stmt
+	return stmt, nil
+}
+
+func (p *pool) close() error {
+	p.mu.Lock()
+	for query, stmt := range p.stmts {
+		stmt.Close()
+		delete(p.stmts, query)
+	}
+	p.mu.Unlock()
+	return p.conn.Close()
+}
+
+// GetConn checks out a dedicated connection to the primary, for work needing a single session
+func (db *dbConn) GetConn(ctx context.Context) (*sql.Conn, error) {
+	return db.primary.conn.Conn(ctx)
+}
+
+// Listen returns a listener for the notifications sent on channel through the primary. It holds
+// its own connection and reconnects by itself, sending a nil notification once it has, as
+// notifications sent meanwhile are lost.
+func (db *dbConn) Listen(channel string) (*pq.Listener, error) {
+	l := pq.NewListener(connInfo(dBHost, dBPort), time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Listener on %s: %v", channel, err)
+		}
+	})
+	if err := l.Listen(channel); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Prepare returns the statement for query on the primary, preparing it the first time it is
+// used. Statements are shared and must not be closed by callers. Using it marks the session
+// of ctx as having written, see WithSession.
+func (db *dbConn) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	markWrite(ctx)
+	return db.primary.prepare(ctx, query)
+}
+
+// BeginTx starts a transaction on a connection checked out from the primary pool
+func (db *dbConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	markWrite(ctx)
+	return db.primary.conn.BeginTx(ctx, opts)
+}
+
+// Stats returns the primary pool statistics
+func (db *dbConn) Stats() sql.DBStats {
+	return db.primary.conn.Stats()
+}
+
+// Close closes the cached statements and the pools
+func (db *dbConn) Close() error {
+	for _, r := range db.replicas {
+		r.close()
+	}
+	return db.primary.close()
+}
+
+// ReadOnly reports whether op only reads, so it may be served by a replica
+func ReadOnly(op string) bool {
+	return op == OpRead || op == OpSearch || op == OpExport
+}
+
+// WithTimeout returns ctx bounded by the timeout configured for op
+func WithTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	d, ok := timeouts[op]
+	if !ok {
+		d = timeouts[OpRead]
 	}
-	return conn
+	return context.WithTimeout(ctx, d)
 }
@@ -4,37 +4,37 @@ import (
 	"fmt"
 
 	"github.com/graphql-go/graphql"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
 	"github.com/sauravgsh16/bookstore_users-api/logger"
 	"github.com/sauravgsh16/bookstore_users-api/services"
 )
 
-// Schema graphql schema
-var Schema graphql.Schema
+// Schemas holds a schema per view, exposing only the user fields the view does
+var Schemas = make(map[string]*graphql.Schema)
 
-// InitQL schema
+// InitQL schemas
 func InitQL(r services.GraphQLResolvers) {
+	for _, view := range users.Views {
+		schema, err := newSchema(r, users.ViewProjection(view))
+		if err != nil {
+			panic(fmt.Errorf("failed to create new schema: %s", err.Error()))
+		}
+		Schemas[view] = &schema
+	}
+	logger.Info("Successfully initialized GraphQL")
+}
+
+func newSchema(r services.GraphQLResolvers, projection users.Projection) (graphql.Schema, error) {
+	userFields := graphql.Fields{}
+	for _, f := range projection.Fields() {
+		userFields[f.Name] = &graphql.Field{
+			Type:    fieldType(f),
+			Resolve: resolveField(f),
+		}
+	}
 	var userType = graphql.NewObject(graphql.ObjectConfig{
-		Name: "User",
-		Fields: graphql.Fields{
-			"id": &graphql.Field{
-				Type: graphql.Int,
-			},
-			"first_name": &graphql.Field{
-				Type: graphql.String,
-			},
-			"last_name": &graphql.Field{
-				Type: graphql.String,
-			},
-			"email": &graphql.Field{
-				Type: graphql.String,
-			},
-			"date_created": &graphql.Field{
-				Type: graphql.String,
-			},
-			"status": &graphql.Field{
-				Type: graphql.String,
-			},
-		},
+		Name:   "User",
+		Fields: userFields,
 	})
 
 	fields := graphql.Fields{
@@ -65,13 +65,29 @@ func InitQL(r services.GraphQLResolvers) {
 	schemaConfig := graphql.SchemaConfig{
 		Query: graphql.NewObject(rootQuery),
 	}
+	return graphql.NewSchema(schemaConfig)
+}
+
+func fieldType(f users.Field) graphql.Output {
+	if _, ok := f.Value(&users.User{}).(int); ok {
+		return graphql.Int
+	}
+	return graphql.String
+}
 
-	var err error
-	Schema, err = graphql.NewSchema(schemaConfig)
-	if err != nil {
-		panic(fmt.Errorf("failed to create new schema: %s", err.Error()))
+// resolveField reads the field as documents do, empty optional fields resolving to null
+func resolveField(f users.Field) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		u, ok := p.Source.(*users.User)
+		if !ok {
+			return nil, nil
+		}
+		v := f.Value(u)
+		if f.OmitEmpty && (v == "" || v == 0) {
+			return nil, nil
+		}
+		return v, nil
 	}
-	logger.Info("Successfully initialized GraphQL")
 }
 
 /*
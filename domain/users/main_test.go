@@ -0,0 +1,69 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+)
+
+// testUsers numbers the users made by tests, keeping their emails apart within a run
+var testUsers int64
+
+// testCaller is the actor of the changes made by tests
+var testCaller = Caller{Actor: "test", RequestID: "test"}
+
+// dbErr is why the users database could not be opened, tests needing it being skipped then
+var dbErr error
+
+func TestMain(m *testing.M) {
+	dbErr = usersdb.Open()
+	os.Exit(m.Run())
+}
+
+// requireDB skips tb unless the users database is reachable. It must hold the latest schema,
+// the migrations being applied beforehand.
+func requireDB(tb testing.TB) {
+	tb.Helper()
+	if dbErr != nil {
+		tb.Skipf("users database unavailable: %v", dbErr)
+	}
+}
+
+// newTestUser returns an active user with an email no other run uses
+func newTestUser(name string) *User {
+	return &User{
+		FirstName:   name,
+		LastName:    "test",
+		Email:       fmt.Sprintf("%s.%d.%d@test.invalid", name, time.Now().UnixNano(), atomic.AddInt64(&testUsers, 1)),
+		DateCreated: dates.GetNowDBString(),
+		Status:      StatusActive,
+		Password:    "5f4dcc3b5aa765d61d8327deb882cf99",
+	}
+}
+
+// saveTestUser saves a new active user, as signed up by the test actor
+func saveTestUser(tb testing.TB, name string) *User {
+	tb.Helper()
+	u := newTestUser(name)
+	if err := u.Save(context.Background(), NewAuditEntry(AuditActionCreate, testCaller, nil, u)); err != nil {
+		tb.Fatalf("saving user %s: %s", name, err.Message)
+	}
+	return u
+}
+
+// deleteTestUser soft deletes u, as the delete status action does
+func deleteTestUser(tb testing.TB, u *User) {
+	tb.Helper()
+	sc := &StatusChange{Action: ActionDelete, From: u.Status, To: StatusDeleted, Actor: testCaller.Actor, DateCreated: dates.GetNowDBString()}
+	after := *u
+	after.Status, after.DeletedAt = sc.To, sc.DateCreated
+	if err := u.ChangeStatus(context.Background(), sc, NewAuditEntry(ActionDelete, testCaller, u, &after)); err != nil {
+		tb.Fatalf("deleting user %d: %s", u.ID, err.Message)
+	}
+}
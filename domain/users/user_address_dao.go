@@ -0,0 +1,170 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	queryInsertAddress = `INSERT INTO user_addresses(user_id, type, is_default, recipient, line1, line2, city, region, postal_code, country, date_created, date_updated)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $11) RETURNING ID;`
+	querySelectAddress = `SELECT ID, USER_ID, TYPE, IS_DEFAULT, RECIPIENT, LINE1, LINE2, CITY, REGION, POSTAL_CODE, COUNTRY, DATE_CREATED, DATE_UPDATED
+		FROM user_addresses WHERE ID=($1) AND USER_ID=($2);`
+	queryFindAddresses = `SELECT ID, USER_ID, TYPE, IS_DEFAULT, RECIPIENT, LINE1, LINE2, CITY, REGION, POSTAL_CODE, COUNTRY, DATE_CREATED, DATE_UPDATED
+		FROM user_addresses WHERE USER_ID=($1) ORDER BY ID;`
+	queryUpdateAddress = `UPDATE user_addresses SET type=($1), is_default=($2), recipient=($3), line1=($4), line2=($5), city=($6), region=($7), postal_code=($8), country=($9), date_updated=($10)
+		WHERE ID=($11) AND USER_ID=($12) RETURNING DATE_CREATED;`
+	queryClearDefaultAddress = `UPDATE user_addresses SET is_default=FALSE WHERE USER_ID=($1) AND TYPE=($2) AND IS_DEFAULT AND ID<>($3);`
+	queryDeleteAddress       = `DELETE FROM user_addresses WHERE ID=($1) AND USER_ID=($2);`
+)
+
+// Save the address of the user. A default address replaces the user's previous default of its type.
+func (a *Address) Save(ctx context.Context) *errors.RestErr {
+	return runInTx(ctx, usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		if err := a.clearOtherDefault(ctx, tx); err != nil {
+			return err
+		}
+
+		stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryInsertAddress)
+		if stmtErr != nil {
+			return stmtErr
+		}
+		row := stmt.QueryRowContext(ctx, a.UserID, a.Type, a.IsDefault, a.Recipient, a.Line1, a.Line2, a.City, a.Region, a.PostalCode, a.Country, a.DateCreated)
+		if err := row.Scan(&a.ID); err != nil {
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			logger.Error("failed to save address, error: ", err)
+			return errors.NewInternalServerError("database error when trying to save address")
+		}
+		a.DateUpdated = a.DateCreated
+		return nil
+	})
+}
+
+// Update the address of the user. A default address replaces the user's previous default of its type.
+func (a *Address) Update(ctx context.Context) *errors.RestErr {
+	return runInTx(ctx, usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		if err := a.clearOtherDefault(ctx, tx); err != nil {
+			return err
+		}
+
+		stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryUpdateAddress)
+		if stmtErr != nil {
+			return stmtErr
+		}
+		row := stmt.QueryRowContext(ctx, a.Type, a.IsDefault, a.Recipient, a.Line1, a.Line2, a.City, a.Region, a.PostalCode, a.Country, a.DateUpdated, a.ID, a.UserID)
+		if err := row.Scan(&a.DateCreated); err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewNotFoundError(fmt.Sprintf("address %d not found", a.ID))
+			}
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			logger.Error("failed to update address, error: ", err)
+			return errors.NewInternalServerError("database error when trying to update address")
+		}
+		return nil
+	})
+}
+
+func (a *Address) clearOtherDefault(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+	if !a.IsDefault {
+		return nil
+	}
+	stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryClearDefaultAddress)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	if _, err := stmt.ExecContext(ctx, a.UserID, a.Type, a.ID); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to clear default address, error: ", err)
+		return errors.NewInternalServerError("database error when trying to save address")
+	}
+	return nil
+}
+
+// Get populates the address a.ID of the user a.UserID
+func (a *Address) Get(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, querySelectAddress)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	row := stmt.QueryRowContext(ctx, a.ID, a.UserID)
+	if err := a.scan(row); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		return errors.NewNotFoundError(fmt.Sprintf("address %d not found", a.ID))
+	}
+	return nil
+}
+
+// FindByUserID returns the addresses of the user, oldest first
+func (a *Address) FindByUserID(ctx context.Context, userID int) (Addresses, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpSearch, queryFindAddresses)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to execute address query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	addresses := make(Addresses, 0)
+	for rows.Next() {
+		addr := new(Address)
+		if err := addr.scan(rows); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		addresses = append(addresses, addr)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return addresses, nil
+}
+
+func (a *Address) scan(row interface{ Scan(...interface{}) error }) error {
+	return row.Scan(&a.ID, &a.UserID, &a.Type, &a.IsDefault, &a.Recipient, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode, &a.Country, &a.DateCreated, &a.DateUpdated)
+}
+
+// Delete the address a.ID of the user a.UserID
+func (a *Address) Delete(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryDeleteAddress)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	res, err := stmt.ExecContext(ctx, a.ID, a.UserID)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to execute delete query, error: ", err)
+		return errors.NewInternalServerError("database error when trying to delete address")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.NewNotFoundError(fmt.Sprintf("address %d not found", a.ID))
+	}
+	return nil
+}
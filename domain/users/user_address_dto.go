@@ -0,0 +1,57 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	// AddressShipping is an address orders are delivered to
+	AddressShipping = "shipping"
+	// AddressBilling is an address invoices are made out to
+	AddressBilling = "billing"
+)
+
+// Address is a postal address of a user. A user has at most one default address of each type.
+type Address struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"user_id"`
+	Type        string `json:"type"`
+	IsDefault   bool   `json:"is_default"`
+	Recipient   string `json:"recipient"`
+	Line1       string `json:"line1"`
+	Line2       string `json:"line2"`
+	City        string `json:"city"`
+	Region      string `json:"region"`
+	PostalCode  string `json:"postal_code"`
+	Country     string `json:"country"`
+	DateCreated string `json:"date_created"`
+	DateUpdated string `json:"date_updated"`
+}
+
+// Addresses is a slice of addresses
+type Addresses []*Address
+
+// Validate normalizes the address fields and checks they are accepted
+func (a *Address) Validate() *errors.RestErr {
+	a.Type = strings.TrimSpace(strings.ToLower(a.Type))
+	if a.Type != AddressShipping && a.Type != AddressBilling {
+		return errors.NewBadRequestError(fmt.Sprintf("address type must be %s or %s", AddressShipping, AddressBilling))
+	}
+
+	for _, f := range []*string{&a.Recipient, &a.Line1, &a.Line2, &a.City, &a.Region, &a.PostalCode} {
+		*f = strings.TrimSpace(*f)
+	}
+	if a.Line1 == "" || a.City == "" {
+		return errors.NewBadRequestError("address line1 and city are required")
+	}
+
+	// ISO 3166-1 alpha-2
+	a.Country = strings.TrimSpace(strings.ToUpper(a.Country))
+	if len(a.Country) != 2 || a.Country[0] < 'A' || a.Country[0] > 'Z' || a.Country[1] < 'A' || a.Country[1] > 'Z' {
+		return errors.NewBadRequestError("country must be a two letter ISO 3166 code")
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package users
+
+import "testing"
+
+func TestAddressValidate(t *testing.T) {
+	valid := Address{Type: AddressShipping, Line1: "1 Infinite Loop", City: "Cupertino", Country: "US"}
+
+	tests := []struct {
+		name    string
+		address Address
+		want    Address
+		wantErr bool
+	}{
+		{name: "valid", address: valid, want: valid},
+		{
+			name: "normalized",
+			address: Address{Type: " Billing ", Recipient: " Alice ", Line1: " 1 Infinite Loop ", Line2: " Suite 2 ",
+				City: " Cupertino ", Region: " CA ", PostalCode: " 95014 ", Country: " us "},
+			want: Address{Type: AddressBilling, Recipient: "Alice", Line1: "1 Infinite Loop", Line2: "Suite 2",
+				City: "Cupertino", Region: "CA", PostalCode: "95014", Country: "US"},
+		},
+		{name: "unknown type", address: Address{Type: "home", Line1: "1 Infinite Loop", City: "Cupertino", Country: "US"}, wantErr: true},
+		{name: "missing type", address: Address{Line1: "1 Infinite Loop", City: "Cupertino", Country: "US"}, wantErr: true},
+		{name: "blank line1", address: Address{Type: AddressShipping, Line1: "  ", City: "Cupertino", Country: "US"}, wantErr: true},
+		{name: "missing city", address: Address{Type: AddressShipping, Line1: "1 Infinite Loop", Country: "US"}, wantErr: true},
+		{name: "three letter country", address: Address{Type: AddressShipping, Line1: "1 Infinite Loop", City: "Cupertino", Country: "USA"}, wantErr: true},
+		{name: "numeric country", address: Address{Type: AddressShipping, Line1: "1 Infinite Loop", City: "Cupertino", Country: "84"}, wantErr: true},
+		{name: "missing country", address: Address{Type: AddressShipping, Line1: "1 Infinite Loop", City: "Cupertino"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.address
+			err := a.Validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Validate(%+v) = nil, want an error", tt.address)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate(%+v): %s", tt.address, err.Message)
+			}
+			if a != tt.want {
+				t.Errorf("Validate(%+v) = %+v, want %+v", tt.address, a, tt.want)
+			}
+		})
+	}
+}
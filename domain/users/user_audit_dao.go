@@ -0,0 +1,106 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	queryInsertAuditEntry = `INSERT INTO user_audit_log(user_id, actor, action, changes, request_id, date_created) VALUES($1, $2, $3, $4, $5, $6) RETURNING ID;`
+	queryFindSignupActor  = `SELECT ACTOR FROM user_audit_log WHERE USER_ID=($1) AND ACTION=($2) ORDER BY ID LIMIT 1;`
+	queryFindAuditEntries = `SELECT ID, USER_ID, ACTOR, ACTION, CHANGES, REQUEST_ID, DATE_CREATED, COUNT(*) OVER() FROM user_audit_log WHERE USER_ID=($1) ORDER BY ID DESC LIMIT ($2) OFFSET ($3);`
+)
+
+// save appends the entry to the audit log within tx
+func (e *AuditEntry) save(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		logger.Error("failed to encode audit changes: ", err)
+		return errors.NewInternalServerError("failed to record audit entry")
+	}
+	if e.DateCreated == "" {
+		e.DateCreated = dates.GetNowDBString()
+	}
+
+	stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryInsertAuditEntry)
+	if stmtErr != nil {
+		return stmtErr
+	}
+
+	row := stmt.QueryRowContext(ctx, e.UserID, e.Actor, e.Action, string(changes), e.RequestID, e.DateCreated)
+	if err := row.Scan(&e.ID); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to record audit entry, error: ", err)
+		return errors.NewInternalServerError("database error when trying to record audit entry")
+	}
+	return nil
+}
+
+// FindAuditEntries returns a page of the user's audit log, newest first
+func (u *User) FindAuditEntries(ctx context.Context, page, perPage int) (*AuditPage, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpSearch, queryFindAuditEntries)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, u.ID, perPage, (page-1)*perPage)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to execute audit query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	result := &AuditPage{
+		Entries: make(AuditEntries, 0),
+		Page:    page,
+		PerPage: perPage,
+	}
+	for rows.Next() {
+		var changes []byte
+		e := new(AuditEntry)
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Actor, &e.Action, &changes, &e.RequestID, &e.DateCreated, &result.Total); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		if err := json.Unmarshal(changes, &e.Changes); err != nil {
+			logger.Error("failed to decode audit changes, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		result.Entries = append(result.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return result, nil
+}
+
+// FindSignupActor returns who created the user, as recorded in the audit log
+func (u *User) FindSignupActor(ctx context.Context) (string, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, queryFindSignupActor)
+	if stmtErr != nil {
+		return "", stmtErr
+	}
+	defer cancel()
+
+	var actor string
+	if err := stmt.QueryRowContext(ctx, u.ID, AuditActionCreate).Scan(&actor); err != nil {
+		if err := handleDBError(err); err != nil {
+			return "", err
+		}
+		return "", errors.NewNotFoundError("no sign up recorded for user")
+	}
+	return actor, nil
+}
@@ -0,0 +1,59 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestFindAuditEntries(t *testing.T) {
+	requireDB(t)
+	ctx := ReadFromPrimary(context.Background())
+	u := saveTestUser(t, "audited")
+	for i := 1; i <= 4; i++ {
+		before := *u
+		u.FirstName = fmt.Sprintf("audited%d", i)
+		if err := u.Update(context.Background(), NewAuditEntry(AuditActionUpdate, testCaller, &before, u)); err != nil {
+			t.Fatalf("update %d: %s", i, err.Message)
+		}
+	}
+
+	tests := []struct {
+		page, perPage int
+		// wantNames are the first names set by the entries, newest first
+		wantNames []string
+	}{
+		{page: 1, perPage: 2, wantNames: []string{"audited4", "audited3"}},
+		{page: 2, perPage: 2, wantNames: []string{"audited2", "audited1"}},
+		{page: 3, perPage: 2, wantNames: []string{"audited"}},
+		{page: 1, perPage: 10, wantNames: []string{"audited4", "audited3", "audited2", "audited1", "audited"}},
+		{page: 4, perPage: 2, wantNames: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("page %d of %d", tt.page, tt.perPage), func(t *testing.T) {
+			result, err := u.FindAuditEntries(ctx, tt.page, tt.perPage)
+			if err != nil {
+				t.Fatalf("FindAuditEntries: %s", err.Message)
+			}
+			if result.Page != tt.page || result.PerPage != tt.perPage {
+				t.Errorf("page = %d of %d, want %d of %d", result.Page, result.PerPage, tt.page, tt.perPage)
+			}
+			if len(tt.wantNames) > 0 && result.Total != 5 {
+				t.Errorf("total = %d, want 5", result.Total)
+			}
+			names := make([]string, 0, len(result.Entries))
+			for _, e := range result.Entries {
+				if e.UserID != u.ID || e.Actor != testCaller.Actor || e.RequestID != testCaller.RequestID {
+					t.Errorf("entry %+v, want one of user %d by %+v", e, u.ID, testCaller)
+				}
+				if _, ok := e.Changes["password"]; ok && e.Changes["password"].To != redacted {
+					t.Errorf("entry %d holds the password in clear", e.ID)
+				}
+				names = append(names, fmt.Sprint(e.Changes["first_name"].To))
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) {
+				t.Errorf("entries set first names %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
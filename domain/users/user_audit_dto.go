@@ -0,0 +1,109 @@
+package users
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	// AuditActionCreate records a user sign up
+	AuditActionCreate = "create"
+	// AuditActionUpdate records a change of user fields
+	AuditActionUpdate = "update"
+
+	redacted = "[REDACTED]"
+)
+
+var (
+	// sensitiveFields are never written to the audit log in clear
+	sensitiveFields = map[string]bool{
+		"password": true,
+	}
+
+	// personalFields are written in clear until the user is erased
+	personalFields = map[string]bool{
+		"first_name": true,
+		"last_name":  true,
+		"email":      true,
+	}
+)
+
+// Caller identifies who made a change and in which request
+type Caller struct {
+	Actor     string
+	RequestID string
+	// UserID is the user signed in with the access token of the request, 0 if anonymous
+	UserID int
+}
+
+// FieldChange holds the previous and new value of a field
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditEntry records a single mutation of a user
+type AuditEntry struct {
+	ID          int                    `json:"id"`
+	UserID      int                    `json:"user_id"`
+	Actor       string                 `json:"actor"`
+	Action      string                 `json:"action"`
+	Changes     map[string]FieldChange `json:"changes"`
+	RequestID   string                 `json:"request_id"`
+	DateCreated string                 `json:"date_created"`
+}
+
+// AuditEntries is a slice of audit entries
+type AuditEntries []*AuditEntry
+
+// AuditPage is a page of a user's audit log, newest first
+type AuditPage struct {
+	Entries AuditEntries `json:"entries"`
+	Page    int          `json:"page"`
+	PerPage int          `json:"per_page"`
+	Total   int          `json:"total"`
+}
+
+// NewAuditEntry returns an entry for action holding the fields that differ between before
+// and after. A nil before records a creation.
+func NewAuditEntry(action string, caller Caller, before, after *User) *AuditEntry {
+	if before == nil {
+		before = &User{}
+	}
+
+	changes := make(map[string]FieldChange)
+	b := reflect.ValueOf(*before)
+	a := reflect.ValueOf(*after)
+	for i := 0; i < b.NumField(); i++ {
+		name := strings.Split(b.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || name == "id" {
+			continue
+		}
+
+		from, to := b.Field(i).Interface(), a.Field(i).Interface()
+		if from == to {
+			continue
+		}
+		if sensitiveFields[name] {
+			from, to = redacted, redacted
+		}
+		changes[name] = FieldChange{From: from, To: to}
+	}
+
+	return &AuditEntry{
+		UserID:    after.ID,
+		Actor:     caller.Actor,
+		Action:    action,
+		Changes:   changes,
+		RequestID: caller.RequestID,
+	}
+}
+
+// RedactPersonalData hides the personal data held by the entry's changes
+func (e *AuditEntry) RedactPersonalData() {
+	for name := range e.Changes {
+		if personalFields[name] {
+			e.Changes[name] = FieldChange{From: redacted, To: redacted}
+		}
+	}
+}
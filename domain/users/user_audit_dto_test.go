@@ -0,0 +1,102 @@
+package users
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewAuditEntry(t *testing.T) {
+	alice := User{ID: 7, FirstName: "Alice", LastName: "Liddell", Email: "alice@test.invalid", DateCreated: "2026-01-02 03:04:05",
+		Status: StatusPending, Password: "hash", Version: 1}
+	renamed := alice
+	renamed.FirstName, renamed.Email, renamed.Version = "Alicia", "alicia@test.invalid", 2
+	newPassword := alice
+	newPassword.Password = "other hash"
+	withProfile := alice
+	withProfile.Profile = &Profile{UserID: 7, Phone: "+14155552671"}
+	deleted := alice
+	deleted.Status, deleted.DeletedAt = StatusDeleted, "2026-02-03 04:05:06"
+
+	tests := []struct {
+		name   string
+		action string
+		before *User
+		after  *User
+		want   map[string]FieldChange
+	}{
+		{
+			name:   "creation records the set fields",
+			action: AuditActionCreate,
+			after:  &alice,
+			want: map[string]FieldChange{
+				"first_name":   {From: "", To: "Alice"},
+				"last_name":    {From: "", To: "Liddell"},
+				"email":        {From: "", To: "alice@test.invalid"},
+				"date_created": {From: "", To: "2026-01-02 03:04:05"},
+				"status":       {From: "", To: StatusPending},
+				"password":     {From: redacted, To: redacted},
+			},
+		},
+		{
+			name:   "update records the changed fields only",
+			action: AuditActionUpdate,
+			before: &alice,
+			after:  &renamed,
+			want: map[string]FieldChange{
+				"first_name": {From: "Alice", To: "Alicia"},
+				"email":      {From: "alice@test.invalid", To: "alicia@test.invalid"},
+			},
+		},
+		{
+			name:   "passwords are redacted",
+			action: AuditActionUpdate,
+			before: &alice,
+			after:  &newPassword,
+			want:   map[string]FieldChange{"password": {From: redacted, To: redacted}},
+		},
+		{
+			name:   "status change",
+			action: ActionDelete,
+			before: &alice,
+			after:  &deleted,
+			want: map[string]FieldChange{
+				"status":     {From: StatusPending, To: StatusDeleted},
+				"deleted_at": {From: "", To: "2026-02-03 04:05:06"},
+			},
+		},
+		{name: "no change", action: AuditActionUpdate, before: &alice, after: &alice, want: map[string]FieldChange{}},
+		{name: "fields left out of representations", action: AuditActionUpdate, before: &alice, after: &withProfile, want: map[string]FieldChange{}},
+	}
+	caller := Caller{Actor: "admin", RequestID: "req-1", UserID: 3}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := NewAuditEntry(tt.action, caller, tt.before, tt.after)
+			if !reflect.DeepEqual(entry.Changes, tt.want) {
+				t.Errorf("changes = %+v, want %+v", entry.Changes, tt.want)
+			}
+			if entry.UserID != tt.after.ID || entry.Action != tt.action || entry.Actor != caller.Actor || entry.RequestID != caller.RequestID {
+				t.Errorf("entry = %+v, want user %d, action %s, actor and request of %+v", entry, tt.after.ID, tt.action, caller)
+			}
+		})
+	}
+}
+
+func TestRedactPersonalData(t *testing.T) {
+	entry := &AuditEntry{Changes: map[string]FieldChange{
+		"first_name": {From: "Alice", To: "Alicia"},
+		"email":      {From: "alice@test.invalid", To: "alicia@test.invalid"},
+		"status":     {From: StatusActive, To: StatusSuspended},
+		"password":   {From: redacted, To: redacted},
+	}}
+	entry.RedactPersonalData()
+
+	want := map[string]FieldChange{
+		"first_name": {From: redacted, To: redacted},
+		"email":      {From: redacted, To: redacted},
+		"status":     {From: StatusActive, To: StatusSuspended},
+		"password":   {From: redacted, To: redacted},
+	}
+	if !reflect.DeepEqual(entry.Changes, want) {
+		t.Errorf("changes = %+v, want %+v", entry.Changes, want)
+	}
+}
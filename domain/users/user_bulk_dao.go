@@ -0,0 +1,225 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/lib/pq"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	queryFindExistingEmails = `SELECT EMAIL FROM users WHERE EMAIL = ANY($1) AND DELETED_AT IS NULL;`
+	queryCreateImportTable  = `CREATE TEMP TABLE users_import (
+		line         INTEGER,
+		first_name   VARCHAR(255),
+		last_name    VARCHAR(255),
+		email        VARCHAR(255),
+		date_created TIMESTAMP,
+		status       VARCHAR(45),
+		password     VARCHAR(32)
+	) ON COMMIT DROP;`
+	queryInsertImported = `INSERT INTO users(first_name, last_name, email, date_created, status, password)
+		SELECT first_name, last_name, email, date_created, status, password FROM users_import ORDER BY line
+		ON CONFLICT (email) WHERE deleted_at IS NULL DO NOTHING RETURNING ID, EMAIL, VERSION;`
+	queryExportUsers = `SELECT ID, FIRST_NAME, LAST_NAME, EMAIL, DATE_CREATED, STATUS, DELETED_AT, VERSION FROM users WHERE (($1) OR DELETED_AT IS NULL) ORDER BY ID;`
+)
+
+// FindExistingEmails returns which of emails are taken by users that are not soft deleted
+func FindExistingEmails(ctx context.Context, emails []string) (map[string]bool, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpSearch, queryFindExistingEmails)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, pq.Array(emails))
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to execute email query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		existing[email] = true
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return existing, nil
+}
+
+// CopyUsers inserts us in a single transaction using COPY, along with their audit entries and
+// creation events. Users whose email is already taken are skipped and keep a zero ID.
+func CopyUsers(ctx context.Context, us []*User, caller Caller) *errors.RestErr {
+	return runInTx(ctx, usersdb.OpMaintenance, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		if _, err := tx.ExecContext(ctx, queryCreateImportTable); err != nil {
+			logger.Error("failed to create import table, error: ", err)
+			return errors.NewInternalServerError("database error when trying to import users")
+		}
+
+		byEmail := make(map[string]*User, len(us))
+		if err := copyRows(ctx, tx, "users_import", []string{"line", "first_name", "last_name", "email", "date_created", "status", "password"}, len(us), func(i int) []interface{} {
+			u := us[i]
+			u.ID = 0
+			byEmail[u.Email] = u
+			return []interface{}{i, u.FirstName, u.LastName, u.Email, u.DateCreated, u.Status, u.Password}
+		}); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, queryInsertImported)
+		if err != nil {
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			logger.Error("failed to insert imported users, error: ", err)
+			return errors.NewInternalServerError("database error when trying to import users")
+		}
+		var created []*User
+		for rows.Next() {
+			var (
+				id, version int
+				email       string
+			)
+			if err := rows.Scan(&id, &email, &version); err != nil {
+				rows.Close()
+				logger.Error("failed to scan rows, error: ", err)
+				return errors.NewInternalServerError("database error")
+			}
+			u := byEmail[email]
+			u.ID, u.Version = id, version
+			created = append(created, u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			logger.Error("Row error: ", err)
+			return errors.NewInternalServerError("database error")
+		}
+		return copyChanges(ctx, tx, created, caller)
+	})
+}
+
+// copyChanges records the creation of us in the audit log and the outbox, as recordChange
+// does for a single user
+func copyChanges(ctx context.Context, tx *sql.Tx, us []*User, caller Caller) *errors.RestErr {
+	entries := make([]*AuditEntry, len(us))
+	type outboxRow struct {
+		event   DomainEvent
+		payload string
+		date    string
+	}
+	var events []outboxRow
+
+	for i, u := range us {
+		entry := NewAuditEntry(AuditActionCreate, caller, nil, u)
+		entry.DateCreated = u.DateCreated
+		entries[i] = entry
+		for _, event := range eventsFor(u, entry) {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				logger.Error("failed to encode domain event: ", err)
+				return errors.NewInternalServerError("failed to record domain event")
+			}
+			events = append(events, outboxRow{event: event, payload: string(payload), date: entry.DateCreated})
+		}
+	}
+
+	if err := copyRows(ctx, tx, "user_audit_log", []string{"user_id", "actor", "action", "changes", "request_id", "date_created"}, len(entries), func(i int) []interface{} {
+		e := entries[i]
+		changes, _ := json.Marshal(e.Changes)
+		return []interface{}{e.UserID, e.Actor, e.Action, string(changes), e.RequestID, e.DateCreated}
+	}); err != nil {
+		return err
+	}
+	return copyRows(ctx, tx, "user_outbox", []string{"aggregate_id", "event_type", "payload", "date_created"}, len(events), func(i int) []interface{} {
+		e := events[i]
+		return []interface{}{e.event.AggregateID(), e.event.EventType(), e.payload, e.date}
+	})
+}
+
+// copyRows streams n rows built by row into table with COPY
+func copyRows(ctx context.Context, tx *sql.Tx, table string, columns []string, n int, row func(int) []interface{}) *errors.RestErr {
+	if n == 0 {
+		return nil
+	}
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to start copy, error: ", err)
+		return errors.NewInternalServerError("database error when trying to import users")
+	}
+	defer stmt.Close()
+
+	for i := 0; i < n; i++ {
+		if _, err := stmt.ExecContext(ctx, row(i)...); err != nil {
+			logger.Error("failed to copy row, error: ", err)
+			return errors.NewInternalServerError("database error when trying to import users")
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to complete copy, error: ", err)
+		return errors.NewInternalServerError("database error when trying to import users")
+	}
+	return nil
+}
+
+// ExportUsers calls fn for every user in id order, reading them one row at a time. The user
+// handed to fn is reused for the next row. Soft deleted users are only included if includeDeleted is set.
+func ExportUsers(ctx context.Context, includeDeleted bool, fn func(*User) *errors.RestErr) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpExport, queryExportUsers)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, includeDeleted)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to execute export query, error: ", err)
+		return errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	u := new(User)
+	for rows.Next() {
+		var deletedAt sql.NullString
+		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.DateCreated, &u.Status, &deletedAt, &u.Version); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return errors.NewInternalServerError("database error")
+		}
+		u.DeletedAt = deletedAt.String
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("Row error: ", err)
+		return errors.NewInternalServerError("database error")
+	}
+	return nil
+}
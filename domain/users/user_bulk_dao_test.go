@@ -0,0 +1,56 @@
+package users
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCopyUsers(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	active := saveTestUser(t, "active")
+	deleted := saveTestUser(t, "deleted")
+	deleteTestUser(t, deleted)
+
+	fresh := newTestUser("fresh")
+	tests := []struct {
+		name    string
+		email   string
+		taken   bool
+		created bool
+	}{
+		{"email of an active user", active.Email, true, false},
+		{"email of a soft deleted user", deleted.Email, false, true},
+		{"new email", fresh.Email, false, true},
+	}
+
+	emails := make([]string, len(tests))
+	for i, tt := range tests {
+		emails[i] = tt.email
+	}
+	existing, err := FindExistingEmails(ctx, emails)
+	if err != nil {
+		t.Fatalf("FindExistingEmails: %v", err.Message)
+	}
+
+	imported := make([]*User, len(tests))
+	for i, tt := range tests {
+		u := newTestUser("imported")
+		u.Email = tt.email
+		imported[i] = u
+	}
+	if err := CopyUsers(ctx, imported, testCaller); err != nil {
+		t.Fatalf("CopyUsers: %v", err.Message)
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if existing[tt.email] != tt.taken {
+				t.Errorf("FindExistingEmails reported taken %v, want %v", existing[tt.email], tt.taken)
+			}
+			if got := imported[i].ID != 0; got != tt.created {
+				t.Errorf("CopyUsers created %v, want %v", got, tt.created)
+			}
+		})
+	}
+}
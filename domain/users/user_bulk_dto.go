@@ -0,0 +1,50 @@
+package users
+
+const (
+	// BulkCreated rows were inserted
+	BulkCreated = "created"
+	// BulkWouldCreate rows passed every check of a dry run
+	BulkWouldCreate = "would_create"
+	// BulkInvalid rows could not be parsed or failed validation
+	BulkInvalid = "invalid"
+	// BulkDuplicate rows reuse an email already taken, in the import or the db
+	BulkDuplicate = "duplicate"
+	// BulkFailed rows were rejected by the db
+	BulkFailed = "failed"
+
+	// BulkModeCopy loads the rows with COPY in a single transaction
+	BulkModeCopy = "copy"
+	// BulkModeRows creates the rows one at a time, as POST /users would
+	BulkModeRows = "rows"
+)
+
+// BulkRow is a parsed import row, Error is set if it could not be parsed
+type BulkRow struct {
+	Row   int
+	User  User
+	Error string
+}
+
+// BulkResult is the outcome of an import row
+type BulkResult struct {
+	Row    int    `json:"row"`
+	ID     int    `json:"id,omitempty"`
+	Email  string `json:"email,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkReport summarizes an import
+type BulkReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Mode    string        `json:"mode"`
+	Total   int           `json:"total"`
+	Created int           `json:"created"`
+	Failed  int           `json:"failed"`
+	Results []*BulkResult `json:"results"`
+}
+
+// IsValidBulkMode reports whether mode is a known import mode
+func IsValidBulkMode(mode string) bool {
+	return mode == BulkModeCopy || mode == BulkModeRows
+}
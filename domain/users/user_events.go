@@ -0,0 +1,199 @@
+package users
+
+import "encoding/json"
+
+const (
+	// EventUserCreated is emitted when a user signs up
+	EventUserCreated = "UserCreated"
+	// EventUserUpdated is emitted when user fields change
+	EventUserUpdated = "UserUpdated"
+	// EventUserEmailChanged is emitted when the user email changes
+	EventUserEmailChanged = "UserEmailChanged"
+	// EventUserStatusChanged is emitted on every lifecycle transition
+	EventUserStatusChanged = "UserStatusChanged"
+	// EventUserDeleted is emitted when a user is soft deleted
+	EventUserDeleted = "UserDeleted"
+	// EventUserRestored is emitted when a soft deleted user is restored
+	EventUserRestored = "UserRestored"
+	// EventUserErased is emitted when the personal data of a user is erased
+	EventUserErased = "UserErased"
+)
+
+// EventTypes lists every domain event type
+var EventTypes = []string{
+	EventUserCreated,
+	EventUserUpdated,
+	EventUserEmailChanged,
+	EventUserStatusChanged,
+	EventUserDeleted,
+	EventUserRestored,
+	EventUserErased,
+}
+
+// EventStatus returns the status a user is left in by the event of eventType encoded in payload,
+// empty for events not telling it
+func EventStatus(eventType string, payload []byte) string {
+	var fields struct {
+		Status string `json:"status"`
+		To     string `json:"to"`
+	}
+	switch eventType {
+	case EventUserCreated, EventUserRestored:
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return ""
+		}
+		return fields.Status
+	case EventUserStatusChanged:
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return ""
+		}
+		return fields.To
+	case EventUserDeleted:
+		return StatusDeleted
+	}
+	return ""
+}
+
+// DomainEvent is a change to a user other services may react to
+type DomainEvent interface {
+	EventType() string
+	AggregateID() int
+}
+
+// UserCreated event
+type UserCreated struct {
+	UserID      int    `json:"user_id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Email       string `json:"email"`
+	Status      string `json:"status"`
+	DateCreated string `json:"date_created"`
+}
+
+// UserUpdated event
+type UserUpdated struct {
+	UserID  int                    `json:"user_id"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+// UserEmailChanged event
+type UserEmailChanged struct {
+	UserID   int    `json:"user_id"`
+	OldEmail string `json:"old_email"`
+	NewEmail string `json:"new_email"`
+}
+
+// UserStatusChanged event
+type UserStatusChanged struct {
+	UserID int    `json:"user_id"`
+	Action string `json:"action"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// UserDeleted event
+type UserDeleted struct {
+	UserID    int    `json:"user_id"`
+	DeletedAt string `json:"deleted_at"`
+}
+
+// UserRestored event
+type UserRestored struct {
+	UserID int    `json:"user_id"`
+	Status string `json:"status"`
+}
+
+// UserErased event
+type UserErased struct {
+	UserID   int    `json:"user_id"`
+	ErasedAt string `json:"erased_at"`
+}
+
+// EventType of the event
+func (e UserCreated) EventType() string { return EventUserCreated }
+
+// AggregateID of the event
+func (e UserCreated) AggregateID() int { return e.UserID }
+
+// EventType of the event
+func (e UserUpdated) EventType() string { return EventUserUpdated }
+
+// AggregateID of the event
+func (e UserUpdated) AggregateID() int { return e.UserID }
+
+// EventType of the event
+func (e UserEmailChanged) EventType() string { return EventUserEmailChanged }
+
+// AggregateID of the event
+func (e UserEmailChanged) AggregateID() int { return e.UserID }
+
+// EventType of the event
+func (e UserStatusChanged) EventType() string { return EventUserStatusChanged }
+
+// AggregateID of the event
+func (e UserStatusChanged) AggregateID() int { return e.UserID }
+
+// EventType of the event
+func (e UserDeleted) EventType() string { return EventUserDeleted }
+
+// AggregateID of the event
+func (e UserDeleted) AggregateID() int { return e.UserID }
+
+// EventType of the event
+func (e UserRestored) EventType() string { return EventUserRestored }
+
+// AggregateID of the event
+func (e UserRestored) AggregateID() int { return e.UserID }
+
+// EventType of the event
+func (e UserErased) EventType() string { return EventUserErased }
+
+// AggregateID of the event
+func (e UserErased) AggregateID() int { return e.UserID }
+
+// eventsFor returns the domain events describing the change recorded by entry,
+// u holding the user state after the change
+func eventsFor(u *User, entry *AuditEntry) []DomainEvent {
+	switch entry.Action {
+	case AuditActionCreate:
+		return []DomainEvent{UserCreated{
+			UserID:      u.ID,
+			FirstName:   u.FirstName,
+			LastName:    u.LastName,
+			Email:       u.Email,
+			Status:      u.Status,
+			DateCreated: u.DateCreated,
+		}}
+
+	case AuditActionUpdate:
+		if len(entry.Changes) == 0 {
+			return nil
+		}
+		events := []DomainEvent{UserUpdated{UserID: u.ID, Changes: entry.Changes}}
+		if c, ok := entry.Changes["email"]; ok {
+			old, _ := c.From.(string)
+			events = append(events, UserEmailChanged{UserID: u.ID, OldEmail: old, NewEmail: u.Email})
+		}
+		return events
+	}
+
+	// erasing an already deleted user leaves its status unchanged
+	var events []DomainEvent
+	c, ok := entry.Changes["status"]
+	from, _ := c.From.(string)
+	to, _ := c.To.(string)
+	if ok {
+		events = append(events, UserStatusChanged{UserID: u.ID, Action: entry.Action, From: from, To: to})
+	}
+
+	switch entry.Action {
+	case ActionDelete:
+		deletedAt, _ := entry.Changes["deleted_at"].To.(string)
+		events = append(events, UserDeleted{UserID: u.ID, DeletedAt: deletedAt})
+	case ActionRestore:
+		events = append(events, UserRestored{UserID: u.ID, Status: to})
+	case ActionErase:
+		events = append(events, UserErased{UserID: u.ID, ErasedAt: entry.DateCreated})
+	}
+	return events
+}
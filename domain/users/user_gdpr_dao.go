@@ -0,0 +1,126 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	queryEraseUser = `UPDATE users SET first_name=($1), last_name=($2), email=($3), password=($4), status=($5), deleted_at=($6), erased_at=($7), version=version+1
+		WHERE ID=($8) AND version=($9) AND erased_at IS NULL RETURNING version;`
+	queryDeleteUserIdentities = `DELETE FROM user_identities WHERE USER_ID=($1);`
+	queryDeleteUserProfile    = `DELETE FROM user_profiles WHERE USER_ID=($1);`
+	queryDeleteUserAddresses  = `DELETE FROM user_addresses WHERE USER_ID=($1);`
+	// the audit log only accepts redactions while this is set, until the transaction ends
+	queryAllowErasure        = `SET LOCAL users.erasing = 'on';`
+	queryRedactAuditLog      = `UPDATE user_audit_log SET changes=user_redact_pii(changes) WHERE USER_ID=($1);`
+	queryRedactOutbox        = `UPDATE user_outbox SET payload=user_redact_pii(payload) WHERE AGGREGATE_ID=($1);`
+	queryFindAllAuditEntries = `SELECT ID, USER_ID, ACTOR, ACTION, CHANGES, REQUEST_ID, DATE_CREATED FROM user_audit_log WHERE USER_ID=($1) ORDER BY ID;`
+)
+
+// Erase stores the anonymized user if it is still at u.Version, then removes the personal data
+// held elsewhere: linked identities, the profile and addresses are deleted, audit entries and
+// domain events are redacted. Avatar blobs are left to the caller.
+// sc and entry are recorded in the same transaction. There is no way back.
+func (u *User) Erase(ctx context.Context, sc *StatusChange, entry *AuditEntry) *errors.RestErr {
+	var version int
+	err := runInTx(ctx, usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		update, stmtErr := usersdb.PrepareIn(ctx, tx, queryEraseUser)
+		if stmtErr != nil {
+			return stmtErr
+		}
+		row := update.QueryRowContext(ctx, u.FirstName, u.LastName, u.Email, u.Password, u.Status, u.DeletedAt, u.ErasedAt, u.ID, u.Version)
+		if err := row.Scan(&version); err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewPreconditionFailedError("user was modified concurrently")
+			}
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			logger.Error("failed to execute erase query, error: ", err)
+			return errors.NewInternalServerError("database error when trying to erase user")
+		}
+
+		if _, err := tx.ExecContext(ctx, queryAllowErasure); err != nil {
+			logger.Error("failed to allow audit log redaction, error: ", err)
+			return errors.NewInternalServerError("database error when trying to erase user")
+		}
+		for _, query := range []string{queryDeleteUserIdentities, queryDeleteUserProfile, queryDeleteUserAddresses, queryRedactAuditLog, queryRedactOutbox} {
+			if err := execIn(ctx, tx, query, u.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := sc.save(ctx, tx, u.ID); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, u, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	sc.UserID = u.ID
+	u.Version = version
+	return nil
+}
+
+// execIn runs the erasure statement query for the user within tx
+func execIn(ctx context.Context, tx *sql.Tx, query string, userID int) *errors.RestErr {
+	stmt, stmtErr := usersdb.PrepareIn(ctx, tx, query)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	if _, err := stmt.ExecContext(ctx, userID); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to erase personal data, error: ", err)
+		return errors.NewInternalServerError("database error when trying to erase user")
+	}
+	return nil
+}
+
+// FindAllAuditEntries returns the whole audit log of the user, oldest first
+func (u *User) FindAllAuditEntries(ctx context.Context) (AuditEntries, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpExport, queryFindAllAuditEntries)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, u.ID)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to execute audit query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	entries := make(AuditEntries, 0)
+	for rows.Next() {
+		var changes []byte
+		e := new(AuditEntry)
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Actor, &e.Action, &changes, &e.RequestID, &e.DateCreated); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		if err := json.Unmarshal(changes, &e.Changes); err != nil {
+			logger.Error("failed to decode audit changes, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return entries, nil
+}
@@ -0,0 +1,40 @@
+package users
+
+import "fmt"
+
+// erasedName replaces the names of an erased user
+const erasedName = "erased"
+
+// DataExport holds everything stored about a user, in answer to a data subject access
+// request. Sessions and consents are not kept by this service, so they are not part of it.
+type DataExport struct {
+	UserID        int           `json:"user_id"`
+	GeneratedAt   string        `json:"generated_at"`
+	Account       Document      `json:"account"`
+	Profile       *Profile      `json:"profile"`
+	Addresses     Addresses     `json:"addresses"`
+	Identities    Identities    `json:"identities"`
+	StatusHistory StatusChanges `json:"status_history"`
+	AuditLog      AuditEntries  `json:"audit_log"`
+	// Avatar is only part of archived exports, the profile links to it otherwise
+	Avatar []byte `json:"-"`
+}
+
+// IsErased returns true if the personal data of the user has been erased
+func (u *User) IsErased() bool {
+	return u.ErasedAt != ""
+}
+
+// Anonymize replaces the personal data of the user with tombstones and marks it erased at
+// now. The ID is kept, and the user is soft deleted so it can no longer sign in.
+func (u *User) Anonymize(now string) {
+	u.FirstName = erasedName
+	u.LastName = erasedName
+	u.Email = fmt.Sprintf("erased-%d@erased.invalid", u.ID)
+	u.Password = ""
+	u.Status = StatusDeleted
+	if u.DeletedAt == "" {
+		u.DeletedAt = now
+	}
+	u.ErasedAt = now
+}
@@ -0,0 +1,185 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/domain/webhooks"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+func TestAnonymize(t *testing.T) {
+	const now = "2020-02-03 04:05:06"
+	tests := []struct {
+		name          string
+		user          User
+		wantDeletedAt string
+	}{
+		{
+			name:          "active user",
+			user:          User{ID: 7, FirstName: "Alice", LastName: "Liddell", Email: "alice@test.invalid", Password: "hash", Status: StatusActive},
+			wantDeletedAt: now,
+		},
+		{
+			name:          "deleted user keeps its deletion date",
+			user:          User{ID: 7, FirstName: "Alice", Email: "alice@test.invalid", Status: StatusDeleted, DeletedAt: "2020-01-01 00:00:00"},
+			wantDeletedAt: "2020-01-01 00:00:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := tt.user
+			u.Anonymize(now)
+			want := User{
+				ID:        7,
+				FirstName: erasedName,
+				LastName:  erasedName,
+				Email:     "erased-7@erased.invalid",
+				Status:    StatusDeleted,
+				DeletedAt: tt.wantDeletedAt,
+				ErasedAt:  now,
+			}
+			if u != want {
+				t.Errorf("Anonymize = %+v, want %+v", u, want)
+			}
+			if !u.IsErased() {
+				t.Error("anonymized user is not erased")
+			}
+		})
+	}
+}
+
+func TestEraseAuditEntry(t *testing.T) {
+	before := User{ID: 7, FirstName: "Alice", LastName: "Liddell", Email: "alice@test.invalid", Password: "hash", Status: StatusActive}
+	after := before
+	after.Anonymize("2020-02-03 04:05:06")
+
+	entry := NewAuditEntry(ActionErase, testCaller, &before, &after)
+	entry.RedactPersonalData()
+	for _, name := range []string{"first_name", "last_name", "email", "password"} {
+		if c, ok := entry.Changes[name]; !ok || c.From != redacted || c.To != redacted {
+			t.Errorf("change of %s = %+v, want it redacted", name, c)
+		}
+	}
+	if c := entry.Changes["status"]; c.From != StatusActive || c.To != StatusDeleted {
+		t.Errorf("change of status = %+v, want it kept", c)
+	}
+
+	events := eventsFor(&after, entry)
+	if len(events) != 2 || events[0].EventType() != EventUserStatusChanged || events[1].EventType() != EventUserErased {
+		t.Fatalf("events of erasing an active user = %+v, want a status change and an erasure", events)
+	}
+	payload, _ := json.Marshal(events)
+	if strings.Contains(string(payload), "alice") || strings.Contains(string(payload), "Liddell") {
+		t.Errorf("erasure events hold personal data: %s", payload)
+	}
+
+	// erasing an already deleted user leaves its status unchanged
+	before.Status, before.DeletedAt = StatusDeleted, "2020-01-01 00:00:00"
+	after = before
+	after.Anonymize("2020-02-03 04:05:06")
+	if events := eventsFor(&after, NewAuditEntry(ActionErase, testCaller, &before, &after)); len(events) != 1 || events[0].EventType() != EventUserErased {
+		t.Errorf("events of erasing a deleted user = %+v, want only the erasure", events)
+	}
+}
+
+// testQueryRow runs query on the primary
+func testQueryRow(t *testing.T, query string, args ...interface{}) *sql.Row {
+	t.Helper()
+	stmt, err := usersdb.DB.Prepare(context.Background(), query)
+	if err != nil {
+		t.Fatalf("preparing %s: %v", query, err)
+	}
+	return stmt.QueryRow(args...)
+}
+
+// enqueueTestDelivery delivers the creation event of the user to a new subscription, as the
+// webhook fanout does, returning the event id and the subscription
+func enqueueTestDelivery(t *testing.T, userID int) (int64, *webhooks.Subscription) {
+	t.Helper()
+	ctx := context.Background()
+	sub := &webhooks.Subscription{URL: "http://127.0.0.1:1/hook", EventTypes: []string{EventUserCreated}, Secret: "secret", DateCreated: dates.GetNowDBString()}
+	if err := sub.Save(ctx); err != nil {
+		t.Fatalf("saving subscription: %v", err.Message)
+	}
+
+	var (
+		id      int64
+		payload []byte
+	)
+	if err := testQueryRow(t, `SELECT id, payload FROM user_outbox WHERE aggregate_id=($1) AND event_type=($2);`, userID, EventUserCreated).Scan(&id, &payload); err != nil {
+		t.Fatalf("reading the creation event: %v", err)
+	}
+	envelope, _ := json.Marshal(map[string]interface{}{"id": id, "type": EventUserCreated, "key": userID, "payload": json.RawMessage(payload)})
+	if err := webhooks.EnqueueEvent(ctx, id, EventUserCreated, envelope, dates.GetNowDBString()); err != nil {
+		t.Fatalf("EnqueueEvent: %v", err.Message)
+	}
+	return id, sub
+}
+
+// eraseTestUser erases u as the erase action does
+func eraseTestUser(u *User) *errors.RestErr {
+	before := *u
+	u.Anonymize(dates.GetNowDBString())
+	sc := &StatusChange{Action: ActionErase, From: before.Status, To: u.Status, Actor: testCaller.Actor, DateCreated: u.ErasedAt}
+	entry := NewAuditEntry(ActionErase, testCaller, &before, u)
+	entry.RedactPersonalData()
+	entry.DateCreated = u.ErasedAt
+	return u.Erase(context.Background(), sc, entry)
+}
+
+func TestErase(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	u := saveTestUser(t, "erase")
+	email, version := u.Email, u.Version
+	eventID, sub := enqueueTestDelivery(t, u.ID)
+	defer sub.Delete(ctx)
+
+	stale := *u
+	stale.Version--
+	if err := eraseTestUser(&stale); err == nil || err.Status != http.StatusPreconditionFailed {
+		t.Fatalf("erasing a stale version = %v, want precondition failed", err)
+	}
+	if err := eraseTestUser(u); err != nil {
+		t.Fatalf("Erase: %v", err.Message)
+	}
+	if err := webhooks.RedactUser(ctx, u.ID); err != nil {
+		t.Fatalf("RedactUser: %v", err.Message)
+	}
+	if u.Version != version+1 {
+		t.Errorf("erased user version = %d, want %d", u.Version, version+1)
+	}
+
+	stored := User{}
+	if err := stored.Get(ctx, u.ID, true); err != nil {
+		t.Fatalf("Get: %v", err.Message)
+	}
+	if !stored.IsErased() || stored.Email != u.Email || stored.Status != StatusDeleted {
+		t.Errorf("stored user = %+v, want it erased", stored)
+	}
+	entries, err := stored.FindAllAuditEntries(ctx)
+	if err != nil {
+		t.Fatalf("FindAllAuditEntries: %v", err.Message)
+	}
+	if log, _ := json.Marshal(entries); strings.Contains(string(log), email) {
+		t.Errorf("audit log still holds the email: %s", log)
+	}
+	var delivery string
+	if err := testQueryRow(t, `SELECT payload FROM webhook_deliveries WHERE event_id=($1);`, eventID).Scan(&delivery); err != nil {
+		t.Fatalf("reading the delivery: %v", err)
+	}
+	if strings.Contains(delivery, email) || !strings.Contains(delivery, `"email": "[REDACTED]"`) {
+		t.Errorf("delivery still holds the email: %s", delivery)
+	}
+
+	if err := eraseTestUser(&stored); err == nil || err.Status != http.StatusPreconditionFailed {
+		t.Errorf("erasing an erased user = %v, want precondition failed", err)
+	}
+}
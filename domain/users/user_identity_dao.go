@@ -0,0 +1,154 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	queryInsertIdentity         = `INSERT INTO user_identities(user_id, provider, subject, email, date_created) VALUES($1, $2, $3, $4, $5) RETURNING ID;`
+	queryFindIdentityBySubject  = `SELECT ID, USER_ID, PROVIDER, SUBJECT, EMAIL, DATE_CREATED FROM user_identities WHERE PROVIDER=($1) AND SUBJECT=($2);`
+	queryFindIdentitiesByUserID = `SELECT ID, USER_ID, PROVIDER, SUBJECT, EMAIL, DATE_CREATED FROM user_identities WHERE USER_ID=($1) ORDER BY ID;`
+	queryDeleteIdentity         = `DELETE FROM user_identities WHERE USER_ID=($1) AND PROVIDER=($2);`
+	queryLockIdentities         = `SELECT PROVIDER FROM user_identities WHERE USER_ID=($1) FOR UPDATE;`
+)
+
+// Save links the identity to its user
+func (i *Identity) Save(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryInsertIdentity)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	if err := stmt.QueryRowContext(ctx, i.UserID, i.Provider, i.Subject, i.Email, i.DateCreated).Scan(&i.ID); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to save identity, error: ", err)
+		return errors.NewInternalServerError("database error when trying to save identity")
+	}
+	return nil
+}
+
+// FindByProviderSubject populates the identity issued by provider for subject
+func (i *Identity) FindByProviderSubject(ctx context.Context, provider, subject string) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, queryFindIdentityBySubject)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	row := stmt.QueryRowContext(ctx, provider, subject)
+	if err := row.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.DateCreated); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		return errors.NewNotFoundError(fmt.Sprintf("no %s identity found for subject", provider))
+	}
+	return nil
+}
+
+// FindByUserID returns all identities linked to a user
+func (i *Identity) FindByUserID(ctx context.Context, userID int) (Identities, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpSearch, queryFindIdentitiesByUserID)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to execute identity query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	identities := make(Identities, 0)
+	for rows.Next() {
+		id := new(Identity)
+		if err := rows.Scan(&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.DateCreated); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		identities = append(identities, id)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return identities, nil
+}
+
+// Delete unlinks the identity from its user. The last identity of the user is only unlinked if
+// allowLast is set, concurrent unlinks of the user waiting on one another.
+func (i *Identity) Delete(ctx context.Context, allowLast bool) *errors.RestErr {
+	return runInTx(ctx, usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		if !allowLast {
+			providers, err := lockIdentities(ctx, tx, i.UserID)
+			if err != nil {
+				return err
+			}
+			if len(providers) == 1 && providers[0] == i.Provider {
+				return errors.NewConflictError(fmt.Sprintf("%s is the only way this user signs in and cannot be unlinked", i.Provider))
+			}
+		}
+
+		stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryDeleteIdentity)
+		if stmtErr != nil {
+			return stmtErr
+		}
+		res, err := stmt.ExecContext(ctx, i.UserID, i.Provider)
+		if err != nil {
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			logger.Error("failed to execute delete query, error: ", err)
+			return errors.NewInternalServerError("database error when trying to unlink identity")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return errors.NewNotFoundError(fmt.Sprintf("no %s identity linked to user", i.Provider))
+		}
+		return nil
+	})
+}
+
+// lockIdentities returns the providers linked to the user, locking them until tx ends
+func lockIdentities(ctx context.Context, tx *sql.Tx, userID int) ([]string, *errors.RestErr) {
+	stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryLockIdentities)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	rows, err := stmt.QueryContext(ctx, userID)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to lock identities, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to unlink identity")
+	}
+	defer rows.Close()
+
+	var providers []string
+	for rows.Next() {
+		var provider string
+		if err := rows.Scan(&provider); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		providers = append(providers, provider)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return providers, nil
+}
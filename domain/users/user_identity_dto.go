@@ -0,0 +1,14 @@
+package users
+
+// Identity links a user to an account at an external identity provider
+type Identity struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"user_id"`
+	Provider    string `json:"provider"`
+	Subject     string `json:"subject"`
+	Email       string `json:"email"`
+	DateCreated string `json:"date_created"`
+}
+
+// Identities is a slice of identities
+type Identities []*Identity
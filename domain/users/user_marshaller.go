@@ -1,70 +1,229 @@
 package users
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	// Version1 is the original representation of users, kept as is for existing clients
+	Version1 = 1
+	// Version2 formats dates as ISO 8601 and nests the profile of the user
+	Version2 = 2
+	// LatestVersion is the newest representation
+	LatestVersion = Version2
 )
 
-// Marshaller interface
-type Marshaller interface {
-	IsMarshalled() bool
+const (
+	// ViewPublic is what anyone may see of a user
+	ViewPublic = "public"
+	// ViewSelf is what a user sees of their own account
+	ViewSelf = "self"
+	// ViewSupport is what customer support sees, lifecycle markers included
+	ViewSupport = "support"
+	// ViewAdmin exposes every field
+	ViewAdmin = "admin"
+)
+
+// Field is a user field a view may expose
+type Field struct {
+	Name string
+	// OmitEmpty leaves the field out of documents while it holds its zero value
+	OmitEmpty bool
+	// Since is the first version representing the field
+	Since int
+	// Date fields are reformatted as ISO 8601 from version 2 on
+	Date  bool
+	value func(*User) interface{}
 }
 
-// PublicUser for external usage
-type PublicUser struct {
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Status    string `json:"status"`
+// Value of the field for u
+func (f Field) Value(u *User) interface{} {
+	return f.value(u)
+}
+
+var (
+	// userFields in the order documents list them
+	userFields = []Field{
+		{Name: "id", value: func(u *User) interface{} { return u.ID }},
+		{Name: "first_name", value: func(u *User) interface{} { return u.FirstName }},
+		{Name: "last_name", value: func(u *User) interface{} { return u.LastName }},
+		{Name: "email", value: func(u *User) interface{} { return u.Email }},
+		{Name: "date_created", Date: true, value: func(u *User) interface{} { return u.DateCreated }},
+		{Name: "status", value: func(u *User) interface{} { return u.Status }},
+		{Name: "deleted_at", OmitEmpty: true, Date: true, value: func(u *User) interface{} { return u.DeletedAt }},
+		{Name: "erased_at", OmitEmpty: true, Date: true, value: func(u *User) interface{} { return u.ErasedAt }},
+		{Name: "version", value: func(u *User) interface{} { return u.Version }},
+		// profile is only filled in by handlers representing a single user
+		{Name: "profile", OmitEmpty: true, Since: Version2, value: func(u *User) interface{} {
+			if u.Profile == nil {
+				return nil
+			}
+			return u.Profile.document()
+		}},
+	}
+
+	// views lists the fields each view exposes, the password is never part of one
+	views = map[string][]string{
+		ViewPublic:  {"first_name", "last_name", "status"},
+		ViewSelf:    {"id", "first_name", "last_name", "email", "date_created", "status", "profile"},
+		ViewSupport: {"id", "first_name", "last_name", "email", "date_created", "status", "deleted_at", "erased_at", "profile"},
+		ViewAdmin:   {"id", "first_name", "last_name", "email", "date_created", "status", "deleted_at", "erased_at", "version", "profile"},
+	}
+
+	// Views lists every view, narrowest first
+	Views = []string{ViewPublic, ViewSelf, ViewSupport, ViewAdmin}
+)
+
+// IsValidView returns true if view is one of the enumerated views
+func IsValidView(view string) bool {
+	_, ok := views[view]
+	return ok
 }
 
-// IsMarshalled returns true if it can be marshalled
-func (pu PublicUser) IsMarshalled() bool {
-	return true
+// Projection is the set of fields a representation of a user holds
+type Projection struct {
+	View    string
+	Version int
+	fields  []Field
 }
 
-// PrivateUser for internal usage
-type PrivateUser struct {
-	ID          int64  `json:"id"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	Email       string `json:"email"`
-	DateCreated string `json:"date_created"`
-	Status      string `json:"status"`
+// NewProjection returns the fields exposed by view in version 1, narrowed down to names if any
+// are given. Names the view does not expose are rejected, as are unknown views.
+func NewProjection(view string, names []string) (Projection, *errors.RestErr) {
+	return NewVersionedProjection(view, Version1, names)
 }
 
-// IsMarshalled returns true if it can be marshalled
-func (pu PrivateUser) IsMarshalled() bool {
-	return true
+// NewVersionedProjection returns the fields exposed by view in version, narrowed down to names
+// as NewProjection does
+func NewVersionedProjection(view string, version int, names []string) (Projection, *errors.RestErr) {
+	visible, ok := views[view]
+	if !ok {
+		return Projection{}, errors.NewBadRequestError(fmt.Sprintf("unknown view %q", view))
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			wanted[name] = true
+		}
+	}
+
+	sparse := len(wanted) > 0
+	p := Projection{View: view, Version: version}
+	for _, f := range userFields {
+		if !contains(visible, f.Name) || f.Since > version {
+			continue
+		}
+		if !sparse || wanted[f.Name] {
+			p.fields = append(p.fields, f)
+			delete(wanted, f.Name)
+		}
+	}
+	for _, name := range names {
+		if name = strings.TrimSpace(name); wanted[name] {
+			if contains(visible, name) {
+				return Projection{}, errors.NewBadRequestError(fmt.Sprintf("field %q is not available in version %d", name, version))
+			}
+			return Projection{}, errors.NewBadRequestError(fmt.Sprintf("field %q is not available in the %s view", name, view))
+		}
+	}
+	return p, nil
 }
 
-// Marshall maps user to either public or private user
-func (u User) Marshall(isPublic bool) Marshaller {
-	uJSON, err := json.Marshal(u)
+// ViewProjection returns every field of a known view
+func ViewProjection(view string) Projection {
+	p, err := NewProjection(view, nil)
 	if err != nil {
-		fmt.Printf("Error while Marshalling\n")
-		return nil
+		panic(err.Message)
+	}
+	return p
+}
+
+// Fields of the projection, in document order
+func (p Projection) Fields() []Field {
+	return p.fields
+}
+
+// Has returns true if the projection holds the named field
+func (p Projection) Has(name string) bool {
+	for _, f := range p.fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
 	}
-	if isPublic {
-		var pubUser PublicUser
-		if err := json.Unmarshal(uJSON, &pubUser); err != nil {
-			fmt.Printf("Error while unmarshalling: %s\n", err.Error())
-			return nil
+	return false
+}
+
+// Document is a user projected on a set of fields, encoded with the fields in order
+type Document struct {
+	keys   []string
+	values []interface{}
+}
+
+// MarshalJSON encodes the document as a JSON object
+func (d Document) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range d.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(d.values[i])
+		if err != nil {
+			return nil, err
 		}
-		return pubUser
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
 	}
-	var priUser PrivateUser
-	if err := json.Unmarshal(uJSON, &priUser); err != nil {
-		fmt.Printf("Error while unmarshalling: %s\n", err.Error())
-		return nil
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Marshall projects the user on p
+func (u *User) Marshall(p Projection) Document {
+	d := Document{
+		keys:   make([]string, 0, len(p.fields)),
+		values: make([]interface{}, 0, len(p.fields)),
 	}
-	return priUser
+	for _, f := range p.fields {
+		v := f.value(u)
+		if f.OmitEmpty && (v == nil || v == "" || v == 0) {
+			continue
+		}
+		if s, ok := v.(string); ok && f.Date && p.Version >= Version2 {
+			v = dates.ToISO8601(s)
+		}
+		d.keys = append(d.keys, f.Name)
+		d.values = append(d.values, v)
+	}
+	return d
 }
 
-// Marshall returns a slice of Marshallers
-func (u Users) Marshall(isPublic bool) []Marshaller {
-	result := make([]Marshaller, len(u))
+// Documents is a list of projected users
+type Documents []Document
+
+// Marshall projects every user on p
+func (u Users) Marshall(p Projection) Documents {
+	result := make(Documents, len(u))
 	for i, user := range u {
-		result[i] = user.Marshall(isPublic)
+		result[i] = user.Marshall(p)
 	}
 	return result
 }
@@ -0,0 +1,161 @@
+package users
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fieldNames(p Projection) []string {
+	names := make([]string, 0, len(p.Fields()))
+	for _, f := range p.Fields() {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestNewVersionedProjection(t *testing.T) {
+	tests := []struct {
+		name    string
+		view    string
+		version int
+		names   []string
+		want    string
+		wantErr string
+	}{
+		{name: "public v1", view: ViewPublic, version: Version1, want: "first_name,last_name,status"},
+		{name: "public v2", view: ViewPublic, version: Version2, want: "first_name,last_name,status"},
+		{name: "self v1", view: ViewSelf, version: Version1, want: "id,first_name,last_name,email,date_created,status"},
+		{name: "self v2", view: ViewSelf, version: Version2, want: "id,first_name,last_name,email,date_created,status,profile"},
+		{name: "support v1", view: ViewSupport, version: Version1, want: "id,first_name,last_name,email,date_created,status,deleted_at,erased_at"},
+		{name: "support v2", view: ViewSupport, version: Version2, want: "id,first_name,last_name,email,date_created,status,deleted_at,erased_at,profile"},
+		{name: "admin v1", view: ViewAdmin, version: Version1, want: "id,first_name,last_name,email,date_created,status,deleted_at,erased_at,version"},
+		{name: "admin v2", view: ViewAdmin, version: Version2, want: "id,first_name,last_name,email,date_created,status,deleted_at,erased_at,version,profile"},
+		{name: "sparse fields in document order", view: ViewSelf, version: Version2, names: []string{"profile", "email", "id"}, want: "id,email,profile"},
+		{name: "sparse fields trimmed", view: ViewPublic, version: Version1, names: []string{" status ", "", "first_name"}, want: "first_name,status"},
+		{name: "sparse fields repeated", view: ViewAdmin, version: Version1, names: []string{"version", "version"}, want: "version"},
+		{name: "blank sparse fields", view: ViewPublic, version: Version1, names: []string{" ", ""}, want: "first_name,last_name,status"},
+		{name: "unknown view", view: "owner", version: Version1, wantErr: `unknown view "owner"`},
+		{name: "field outside the view", view: ViewPublic, version: Version2, names: []string{"email"}, wantErr: `field "email" is not available in the public view`},
+		{name: "deleted_at outside the self view", view: ViewSelf, version: Version1, names: []string{"deleted_at"}, wantErr: `field "deleted_at" is not available in the self view`},
+		{name: "version outside the support view", view: ViewSupport, version: Version2, names: []string{"version"}, wantErr: `field "version" is not available in the support view`},
+		{name: "profile in version 1", view: ViewSelf, version: Version1, names: []string{"id", "profile"}, wantErr: `field "profile" is not available in version 1`},
+		{name: "profile outside the public view", view: ViewPublic, version: Version2, names: []string{"profile"}, wantErr: `field "profile" is not available in the public view`},
+		{name: "password", view: ViewAdmin, version: Version2, names: []string{"password"}, wantErr: `field "password" is not available in the admin view`},
+		{name: "unknown field", view: ViewAdmin, version: Version2, names: []string{"id", "nickname"}, wantErr: `field "nickname" is not available in the admin view`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewVersionedProjection(tt.view, tt.version, tt.names)
+			if tt.wantErr != "" {
+				if err == nil || err.Status != http.StatusBadRequest || err.Message != tt.wantErr {
+					t.Fatalf("NewVersionedProjection = %v, %+v, want 400 %q", fieldNames(p), err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewVersionedProjection: %v", err.Message)
+			}
+			if got := strings.Join(fieldNames(p), ","); got != tt.want {
+				t.Errorf("fields = %s, want %s", got, tt.want)
+			}
+			if p.View != tt.view || p.Version != tt.version {
+				t.Errorf("projection = %s v%d, want %s v%d", p.View, p.Version, tt.view, tt.version)
+			}
+		})
+	}
+}
+
+func TestNewProjection(t *testing.T) {
+	for _, view := range Views {
+		p, err := NewProjection(view, []string{"first_name"})
+		if err != nil {
+			t.Fatalf("NewProjection(%s): %v", view, err.Message)
+		}
+		if p.Version != Version1 || !p.Has("first_name") || p.Has("last_name") {
+			t.Errorf("NewProjection(%s) = v%d %v", view, p.Version, fieldNames(p))
+		}
+
+		full := ViewProjection(view)
+		want, _ := NewVersionedProjection(view, Version1, nil)
+		if !reflect.DeepEqual(fieldNames(full), fieldNames(want)) {
+			t.Errorf("ViewProjection(%s) = %v, want %v", view, fieldNames(full), fieldNames(want))
+		}
+	}
+	if _, err := NewProjection(ViewSelf, []string{"profile"}); err == nil {
+		t.Error("NewProjection accepted profile, which version 1 lacks")
+	}
+}
+
+func TestUserMarshall(t *testing.T) {
+	u := &User{
+		ID:          1,
+		FirstName:   "Alice",
+		LastName:    "Liddell",
+		Email:       "alice@test.invalid",
+		DateCreated: "2020-01-02 03:04:05",
+		Status:      StatusActive,
+		Password:    "secret",
+		Version:     3,
+		Profile:     &Profile{Locale: "en-GB"},
+	}
+	deleted := *u
+	deleted.Status = StatusDeleted
+	deleted.DeletedAt = "2020-02-03 04:05:06"
+	deleted.Profile = nil
+
+	tests := []struct {
+		name    string
+		user    *User
+		view    string
+		version int
+		names   []string
+		want    string
+	}{
+		{
+			name: "public", user: u, view: ViewPublic, version: Version2,
+			want: `{"first_name":"Alice","last_name":"Liddell","status":"active"}`,
+		},
+		{
+			name: "self v1", user: u, view: ViewSelf, version: Version1,
+			want: `{"id":1,"first_name":"Alice","last_name":"Liddell","email":"alice@test.invalid","date_created":"2020-01-02 03:04:05","status":"active"}`,
+		},
+		{
+			name: "sparse self v2", user: u, view: ViewSelf, version: Version2, names: []string{"date_created", "profile"},
+			want: `{"date_created":"2020-01-02T03:04:05Z","profile":{"phone":"","date_of_birth":"","locale":"en-GB","timezone":""}}`,
+		},
+		{
+			name: "support omitting empty fields", user: u, view: ViewSupport, version: Version1, names: []string{"id", "deleted_at", "erased_at"},
+			want: `{"id":1}`,
+		},
+		{
+			name: "admin of a deleted user", user: &deleted, view: ViewAdmin, version: Version2,
+			want: `{"id":1,"first_name":"Alice","last_name":"Liddell","email":"alice@test.invalid","date_created":"2020-01-02T03:04:05Z","status":"deleted","deleted_at":"2020-02-03T04:05:06Z","version":3}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewVersionedProjection(tt.view, tt.version, tt.names)
+			if err != nil {
+				t.Fatalf("NewVersionedProjection: %v", err.Message)
+			}
+			got, jsonErr := json.Marshal(tt.user.Marshall(p))
+			if jsonErr != nil {
+				t.Fatalf("Marshal: %v", jsonErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshall = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	docs, err := json.Marshal(Users{u, &deleted}.Marshall(ViewProjection(ViewPublic)))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want := `[{"first_name":"Alice","last_name":"Liddell","status":"active"},{"first_name":"Alice","last_name":"Liddell","status":"deleted"}]`; string(docs) != want {
+		t.Errorf("Users.Marshall = %s, want %s", docs, want)
+	}
+}
@@ -0,0 +1,293 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// outboxLockKey is the advisory lock held by the relay so a single replica publishes at a time
+const outboxLockKey = 7311
+
+// EventsChannel is the channel the ids of published messages are notified on, comma separated
+const EventsChannel = "user_events"
+
+const (
+	// notifyBatchSize bounds the ids sent in a notification, well below the 8000 bytes payload limit
+	notifyBatchSize = 300
+	// listenerPingInterval is how often an idle listener checks its connection is still alive
+	listenerPingInterval = time.Minute
+)
+
+const (
+	queryInsertOutbox = `INSERT INTO user_outbox(aggregate_id, event_type, payload, date_created) VALUES($1, $2, $3, $4);`
+	queryLockOutbox   = `SELECT pg_try_advisory_xact_lock($1);`
+	queryClaimOutbox  = `WITH due AS (
+			SELECT o.ID FROM user_outbox o
+			WHERE o.PUBLISHED_AT IS NULL AND o.DEAD_AT IS NULL AND (o.NEXT_ATTEMPT_AT IS NULL OR o.NEXT_ATTEMPT_AT <= ($1))
+			AND NOT EXISTS (
+				SELECT 1 FROM user_outbox e WHERE e.AGGREGATE_ID = o.AGGREGATE_ID AND e.ID < o.ID
+				AND e.PUBLISHED_AT IS NULL AND e.DEAD_AT IS NULL AND e.NEXT_ATTEMPT_AT > ($1)
+			)
+			ORDER BY o.ID LIMIT ($3)
+		)
+		UPDATE user_outbox o SET next_attempt_at=($2) FROM due WHERE o.id = due.id
+		RETURNING o.id, o.aggregate_id, o.event_type, o.payload, o.attempts, o.date_created;`
+	queryMarkPublished     = `UPDATE user_outbox SET published_at=($1), next_attempt_at=NULL WHERE ID = ANY($2);`
+	queryRecordFailure     = `UPDATE user_outbox SET attempts=($1), next_attempt_at=($2), last_error=($3), dead_at=($4) WHERE ID=($5);`
+	queryReleaseOutbox     = `UPDATE user_outbox SET next_attempt_at=NULL WHERE ID = ANY($1) AND PUBLISHED_AT IS NULL;`
+	queryNotifyPublished   = `SELECT pg_notify($1, $2);`
+	queryFindPublished     = `SELECT ID, AGGREGATE_ID, EVENT_TYPE, PAYLOAD, ATTEMPTS, DATE_CREATED FROM user_outbox WHERE ID = ANY($1) AND PUBLISHED_AT IS NOT NULL;`
+	queryFindLastPublished = `SELECT ID, AGGREGATE_ID, EVENT_TYPE, PAYLOAD, ATTEMPTS, DATE_CREATED FROM user_outbox WHERE PUBLISHED_AT IS NOT NULL ORDER BY PUBLISHED_AT DESC, ID DESC LIMIT ($1);`
+)
+
+// recordChange writes the audit entry and the domain events it implies within tx
+func recordChange(ctx context.Context, tx *sql.Tx, u *User, entry *AuditEntry) *errors.RestErr {
+	if err := entry.save(ctx, tx); err != nil {
+		return err
+	}
+
+	events := eventsFor(u, entry)
+	if len(events) == 0 {
+		return nil
+	}
+	stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryInsertOutbox)
+	if stmtErr != nil {
+		return stmtErr
+	}
+
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			logger.Error("failed to encode domain event: ", err)
+			return errors.NewInternalServerError("failed to record domain event")
+		}
+		if _, err := stmt.ExecContext(ctx, event.AggregateID(), event.EventType(), string(payload), entry.DateCreated); err != nil {
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			logger.Error("failed to record domain event, error: ", err)
+			return errors.NewInternalServerError("database error when trying to record domain event")
+		}
+	}
+	return nil
+}
+
+// ClaimOutbox leases up to limit due messages, oldest first, until leaseUntil so they are
+// published outside of a transaction. While a message of a user waits for a retry, the later
+// messages of that user are held back. It returns no messages if another relay is claiming.
+func ClaimOutbox(ctx context.Context, now, leaseUntil string, limit int) (OutboxMessages, *errors.RestErr) {
+	var msgs OutboxMessages
+	err := runInTx(ctx, usersdb.OpMaintenance, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		// claims are serialized, a concurrent one would not see the lease of the messages held back
+		var locked bool
+		if err := tx.QueryRowContext(ctx, queryLockOutbox, outboxLockKey).Scan(&locked); err != nil {
+			logger.Error("failed to lock outbox, error: ", err)
+			return errors.NewInternalServerError("database error")
+		}
+		if !locked {
+			return nil
+		}
+
+		rows, err := tx.QueryContext(ctx, queryClaimOutbox, now, leaseUntil, limit)
+		if err != nil {
+			logger.Error("failed to execute outbox query, error: ", err)
+			return errors.NewInternalServerError("database error when trying to execute query")
+		}
+		var scanErr *errors.RestErr
+		msgs, scanErr = scanOutbox(rows, limit)
+		return scanErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(msgs, func(i, j int) bool { return msgs[i].ID < msgs[j].ID })
+	return msgs, nil
+}
+
+// RecordRelay marks the published messages and notifies the listeners, stores the attempts and
+// next attempt, or dead letter date, of the failed ones and releases the lease of the messages
+// that were not attempted
+func RecordRelay(ctx context.Context, published []int64, failed OutboxMessages, released []int64) *errors.RestErr {
+	return runInTx(ctx, usersdb.OpMaintenance, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		if len(published) > 0 {
+			if _, err := tx.ExecContext(ctx, queryMarkPublished, dates.GetNowDBString(), pq.Array(published)); err != nil {
+				logger.Error("failed to mark outbox messages published, error: ", err)
+				return errors.NewInternalServerError("database error")
+			}
+			if err := notifyPublished(ctx, tx, published); err != nil {
+				return err
+			}
+		}
+
+		if len(failed) > 0 {
+			stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryRecordFailure)
+			if stmtErr != nil {
+				return stmtErr
+			}
+			for _, m := range failed {
+				nextAttemptAt := sql.NullString{String: m.NextAttemptAt, Valid: m.NextAttemptAt != ""}
+				dateDead := sql.NullString{String: m.DateDead, Valid: m.DateDead != ""}
+				if _, err := stmt.ExecContext(ctx, m.Attempts, nextAttemptAt, m.LastError, dateDead, m.ID); err != nil {
+					logger.Error("failed to update outbox attempts, error: ", err)
+					return errors.NewInternalServerError("database error")
+				}
+			}
+		}
+
+		if len(released) > 0 {
+			if _, err := tx.ExecContext(ctx, queryReleaseOutbox, pq.Array(released)); err != nil {
+				logger.Error("failed to release outbox messages, error: ", err)
+				return errors.NewInternalServerError("database error")
+			}
+		}
+		return nil
+	})
+}
+
+// notifyPublished tells the listeners on EventsChannel which messages were published, once tx
+// commits
+func notifyPublished(ctx context.Context, tx *sql.Tx, ids []int64) *errors.RestErr {
+	for start := 0; start < len(ids); start += notifyBatchSize {
+		end := start + notifyBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		parts := make([]string, 0, end-start)
+		for _, id := range ids[start:end] {
+			parts = append(parts, strconv.FormatInt(id, 10))
+		}
+		if _, err := tx.ExecContext(ctx, queryNotifyPublished, EventsChannel, strings.Join(parts, ",")); err != nil {
+			logger.Error("failed to notify published outbox messages, error: ", err)
+			return errors.NewInternalServerError("database error")
+		}
+	}
+	return nil
+}
+
+// ListenPublished calls fn with the ids of the messages published by the relay of any replica, in
+// publishing order. fn is called with no ids after the connection was lost, as ids published
+// meanwhile were missed.
+func ListenPublished(fn func([]int64)) *errors.RestErr {
+	l, err := usersdb.DB.Listen(EventsChannel)
+	if err != nil {
+		logger.Error("failed to listen for published outbox messages, error: ", err)
+		return errors.NewInternalServerError("database error")
+	}
+
+	go func() {
+		ticker := time.NewTicker(listenerPingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case n := <-l.Notify:
+				if n == nil {
+					fn(nil)
+					continue
+				}
+				ids, err := parseNotifiedIDs(n.Extra)
+				if err != nil {
+					logger.Error("invalid notification of published outbox messages: ", err)
+					continue
+				}
+				fn(ids)
+			case <-ticker.C:
+				go l.Ping()
+			}
+		}
+	}()
+	return nil
+}
+
+func parseNotifiedIDs(payload string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(payload, ",") {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// FindPublished returns the published messages among ids, in the order of ids. It reads the
+// primary, replicas may not have caught up with a notification yet.
+func FindPublished(ctx context.Context, ids []int64) (OutboxMessages, *errors.RestErr) {
+	msgs, err := findOutbox(ctx, queryFindPublished, len(ids), pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]*OutboxMessage, len(msgs))
+	for _, m := range msgs {
+		byID[m.ID] = m
+	}
+	result := make(OutboxMessages, 0, len(msgs))
+	for _, id := range ids {
+		if m, ok := byID[id]; ok {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// FindLastPublished returns up to limit of the messages published last, in publishing order
+func FindLastPublished(ctx context.Context, limit int) (OutboxMessages, *errors.RestErr) {
+	msgs, err := findOutbox(ctx, queryFindLastPublished, limit, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, nil
+}
+
+func findOutbox(ctx context.Context, query string, size int, args ...interface{}) (OutboxMessages, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpMaintenance, query)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to execute outbox query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	return scanOutbox(rows, size)
+}
+
+// scanOutbox reads and closes rows of outbox messages
+func scanOutbox(rows *sql.Rows, size int) (OutboxMessages, *errors.RestErr) {
+	defer rows.Close()
+
+	msgs := make(OutboxMessages, 0, size)
+	for rows.Next() {
+		m := new(OutboxMessage)
+		if err := rows.Scan(&m.ID, &m.AggregateID, &m.EventType, &m.Payload, &m.Attempts, &m.DateCreated); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return msgs, nil
+}
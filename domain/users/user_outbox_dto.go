@@ -0,0 +1,17 @@
+package users
+
+// OutboxMessage is a domain event waiting in the outbox to be published
+type OutboxMessage struct {
+	ID            int64  `json:"id"`
+	AggregateID   int    `json:"aggregate_id"`
+	EventType     string `json:"event_type"`
+	Payload       []byte `json:"payload"`
+	Attempts      int    `json:"attempts"`
+	NextAttemptAt string `json:"next_attempt_at,omitempty"`
+	LastError     string `json:"last_error,omitempty"`
+	DateDead      string `json:"date_dead,omitempty"`
+	DateCreated   string `json:"date_created"`
+}
+
+// OutboxMessages is a slice of outbox messages
+type OutboxMessages []*OutboxMessage
@@ -0,0 +1,102 @@
+package users
+
+import (
+	"bytes"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/jsonpatch"
+)
+
+const (
+	// PatchMerge is the media type of JSON Merge Patch documents
+	PatchMerge = "application/merge-patch+json"
+	// PatchJSON is the media type of JSON Patch documents
+	PatchJSON = "application/json-patch+json"
+)
+
+// patchableFields may be changed by a patch, the others of the patched document are read only
+var patchableFields = map[string]func(u *User, value string){
+	"first_name": func(u *User, value string) { u.FirstName = value },
+	"last_name":  func(u *User, value string) { u.LastName = value },
+	"email":      func(u *User, value string) { u.Email = value },
+}
+
+// Patch is a merge patch or JSON Patch document to apply to a user
+type Patch struct {
+	MediaType string
+	Document  []byte
+}
+
+// Apply returns a copy of u with the patch applied to its support view document. Removing a
+// patchable field clears it, touching a read only one is rejected with 422, as are values
+// of the wrong type. A failed test operation answers 409.
+func (p Patch) Apply(u *User) (*User, *errors.RestErr) {
+	doc, err := json.Marshal(u.Marshall(ViewProjection(ViewSupport)))
+	if err != nil {
+		return nil, errors.NewInternalServerError("failed to encode user")
+	}
+
+	var patched []byte
+	switch p.MediaType {
+	case PatchMerge:
+		patched, err = jsonpatch.MergePatch(doc, p.Document)
+	case PatchJSON:
+		patched, err = jsonpatch.Apply(doc, p.Document)
+	default:
+		return nil, errors.NewUnsupportedMediaTypeError(fmt.Sprintf("unsupported patch type %q", p.MediaType))
+	}
+	switch {
+	case goerrors.Is(err, jsonpatch.ErrTestFailed):
+		return nil, errors.NewConflictError(err.Error())
+	case goerrors.Is(err, jsonpatch.ErrPath):
+		return nil, errors.NewUnprocessableEntityError(err.Error())
+	case err != nil:
+		return nil, errors.NewBadRequestError(err.Error())
+	}
+
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &before); err != nil {
+		return nil, errors.NewInternalServerError("failed to decode user")
+	}
+	if err := json.Unmarshal(patched, &after); err != nil {
+		return nil, errors.NewUnprocessableEntityError("patched user must be an object")
+	}
+
+	result := *u
+	for name, value := range after {
+		set, ok := patchableFields[name]
+		if !ok {
+			if old, existed := before[name]; !existed || !bytes.Equal(old, value) {
+				return nil, errors.NewUnprocessableEntityError(fmt.Sprintf("field %q cannot be changed", name))
+			}
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return nil, errors.NewUnprocessableEntityError(fmt.Sprintf("field %q must be a string", name))
+		}
+		set(&result, s)
+	}
+	for name := range before {
+		if _, kept := after[name]; kept {
+			continue
+		}
+		set, ok := patchableFields[name]
+		if !ok {
+			return nil, errors.NewUnprocessableEntityError(fmt.Sprintf("field %q cannot be removed", name))
+		}
+		set(&result, "")
+	}
+
+	result.FirstName = strings.TrimSpace(result.FirstName)
+	result.LastName = strings.TrimSpace(result.LastName)
+	result.Email = strings.TrimSpace(strings.ToLower(result.Email))
+	if result.Email == "" {
+		return nil, errors.NewUnprocessableEntityError("email is required")
+	}
+	return &result, nil
+}
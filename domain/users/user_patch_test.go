@@ -0,0 +1,93 @@
+package users
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPatchApply(t *testing.T) {
+	u := &User{
+		ID:          1,
+		FirstName:   "Alice",
+		LastName:    "Liddell",
+		Email:       "alice@test.invalid",
+		DateCreated: "2020-01-02 03:04:05",
+		Status:      StatusActive,
+		Version:     3,
+	}
+
+	tests := []struct {
+		name      string
+		mediaType string
+		document  string
+		want      User
+		wantErr   int
+	}{
+		{
+			name:      "merge patch",
+			mediaType: PatchMerge,
+			document:  `{"first_name":" Alicia ","email":"ALICIA@test.invalid"}`,
+			want:      User{FirstName: "Alicia", LastName: "Liddell", Email: "alicia@test.invalid"},
+		},
+		{
+			name:      "merge patch clearing a field",
+			mediaType: PatchMerge,
+			document:  `{"last_name":null}`,
+			want:      User{FirstName: "Alice", Email: "alice@test.invalid"},
+		},
+		{
+			name:      "merge patch restating a read only field",
+			mediaType: PatchMerge,
+			document:  `{"id":1,"status":"active","last_name":"L"}`,
+			want:      User{FirstName: "Alice", LastName: "L", Email: "alice@test.invalid"},
+		},
+		{name: "merge patch changing a read only field", mediaType: PatchMerge, document: `{"status":"suspended"}`, wantErr: http.StatusUnprocessableEntity},
+		{name: "merge patch adding a field", mediaType: PatchMerge, document: `{"password":"secret"}`, wantErr: http.StatusUnprocessableEntity},
+		{name: "merge patch removing a read only field", mediaType: PatchMerge, document: `{"date_created":null}`, wantErr: http.StatusUnprocessableEntity},
+		{name: "merge patch of the wrong type", mediaType: PatchMerge, document: `{"first_name":1}`, wantErr: http.StatusUnprocessableEntity},
+		{name: "merge patch clearing the email", mediaType: PatchMerge, document: `{"email":null}`, wantErr: http.StatusUnprocessableEntity},
+		{name: "merge patch replacing the document", mediaType: PatchMerge, document: `["alice"]`, wantErr: http.StatusUnprocessableEntity},
+		{name: "malformed merge patch", mediaType: PatchMerge, document: `{`, wantErr: http.StatusBadRequest},
+		{
+			name:      "JSON patch",
+			mediaType: PatchJSON,
+			document:  `[{"op":"test","path":"/email","value":"alice@test.invalid"},{"op":"copy","from":"/first_name","path":"/last_name"}]`,
+			want:      User{FirstName: "Alice", LastName: "Alice", Email: "alice@test.invalid"},
+		},
+		{
+			name:      "JSON patch removing a field",
+			mediaType: PatchJSON,
+			document:  `[{"op":"remove","path":"/first_name"}]`,
+			want:      User{LastName: "Liddell", Email: "alice@test.invalid"},
+		},
+		{name: "JSON patch failing a test", mediaType: PatchJSON, document: `[{"op":"test","path":"/first_name","value":"Bob"}]`, wantErr: http.StatusConflict},
+		{name: "JSON patch on a missing path", mediaType: PatchJSON, document: `[{"op":"replace","path":"/nickname","value":"Al"}]`, wantErr: http.StatusUnprocessableEntity},
+		{name: "JSON patch moving a read only field", mediaType: PatchJSON, document: `[{"op":"move","from":"/status","path":"/first_name"}]`, wantErr: http.StatusUnprocessableEntity},
+		{name: "malformed JSON patch", mediaType: PatchJSON, document: `[{"op":"add","path":"/first_name"}]`, wantErr: http.StatusBadRequest},
+		{name: "unsupported media type", mediaType: "application/json", document: `{}`, wantErr: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Patch{MediaType: tt.mediaType, Document: []byte(tt.document)}.Apply(u)
+			if tt.wantErr != 0 {
+				if err == nil || err.Status != tt.wantErr {
+					t.Fatalf("Apply = %+v, %v, want status %d", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err.Message)
+			}
+			if got.FirstName != tt.want.FirstName || got.LastName != tt.want.LastName || got.Email != tt.want.Email {
+				t.Errorf("Apply = %q %q %q, want %q %q %q",
+					got.FirstName, got.LastName, got.Email, tt.want.FirstName, tt.want.LastName, tt.want.Email)
+			}
+			if got.ID != u.ID || got.Status != u.Status || got.Version != u.Version || got.DateCreated != u.DateCreated {
+				t.Errorf("Apply changed read only fields: %+v", got)
+			}
+		})
+	}
+	if u.FirstName != "Alice" || u.LastName != "Liddell" {
+		t.Errorf("Apply changed the patched user to %+v", u)
+	}
+}
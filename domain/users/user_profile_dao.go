@@ -0,0 +1,74 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	querySelectProfile = `SELECT PHONE, COALESCE(TO_CHAR(DATE_OF_BIRTH, 'YYYY-MM-DD'), ''), LOCALE, TIMEZONE, AVATAR_CONTENT_TYPE, DATE_UPDATED FROM user_profiles WHERE USER_ID=($1);`
+	queryUpsertProfile = `INSERT INTO user_profiles(user_id, phone, date_of_birth, locale, timezone, date_updated) VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET phone=EXCLUDED.phone, date_of_birth=EXCLUDED.date_of_birth, locale=EXCLUDED.locale, timezone=EXCLUDED.timezone, date_updated=EXCLUDED.date_updated;`
+	queryUpsertAvatar = `INSERT INTO user_profiles(user_id, avatar_content_type, date_updated) VALUES($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET avatar_content_type=EXCLUDED.avatar_content_type, date_updated=EXCLUDED.date_updated;`
+)
+
+// Get populates the profile of p.UserID, returning not found if it was never filled in
+func (p *Profile) Get(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, querySelectProfile)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	row := stmt.QueryRowContext(ctx, p.UserID)
+	if err := row.Scan(&p.Phone, &p.DateOfBirth, &p.Locale, &p.Timezone, &p.AvatarType, &p.DateUpdated); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		return errors.NewNotFoundError(fmt.Sprintf("no profile for user %d", p.UserID))
+	}
+	return nil
+}
+
+// Save stores the profile fields, leaving the avatar untouched
+func (p *Profile) Save(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryUpsertProfile)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	dob := sql.NullString{String: p.DateOfBirth, Valid: p.DateOfBirth != ""}
+	if _, err := stmt.ExecContext(ctx, p.UserID, p.Phone, dob, p.Locale, p.Timezone, p.DateUpdated); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to save profile, error: ", err)
+		return errors.NewInternalServerError("database error when trying to save profile")
+	}
+	return nil
+}
+
+// SaveAvatar stores the content type of the avatar, an empty one meaning the user has none
+func (p *Profile) SaveAvatar(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryUpsertAvatar)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	if _, err := stmt.ExecContext(ctx, p.UserID, p.AvatarType, p.DateUpdated); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to save avatar, error: ", err)
+		return errors.NewInternalServerError("database error when trying to save avatar")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package users
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const dateOfBirthLayout = "2006-01-02"
+
+var (
+	// phonePattern is the E.164 format, a + followed by at most 15 digits
+	phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+	// phoneSeparators may be used to group the digits of a phone number
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+	// localePattern is a BCP 47 language tag, such as en or pt-BR
+	localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+)
+
+// Profile holds the contact details and preferences of a user
+type Profile struct {
+	UserID      int    `json:"user_id"`
+	Phone       string `json:"phone"`
+	DateOfBirth string `json:"date_of_birth"`
+	Locale      string `json:"locale"`
+	Timezone    string `json:"timezone"`
+	// AvatarType is the content type of the avatar, empty if the user has none
+	AvatarType         string `json:"-"`
+	AvatarURL          string `json:"avatar_url,omitempty"`
+	AvatarThumbnailURL string `json:"avatar_thumbnail_url,omitempty"`
+	DateUpdated        string `json:"date_updated,omitempty"`
+}
+
+// Validate normalizes the profile fields and checks they are accepted. Empty fields are unset.
+func (p *Profile) Validate() *errors.RestErr {
+	p.Phone = phoneSeparators.Replace(strings.TrimSpace(p.Phone))
+	if p.Phone != "" && !phonePattern.MatchString(p.Phone) {
+		return errors.NewBadRequestError("phone must be in E.164 format, such as +14155552671")
+	}
+
+	p.DateOfBirth = strings.TrimSpace(p.DateOfBirth)
+	if p.DateOfBirth != "" {
+		dob, err := time.Parse(dateOfBirthLayout, p.DateOfBirth)
+		if err != nil {
+			return errors.NewBadRequestError("date_of_birth must be formatted as YYYY-MM-DD")
+		}
+		if dob.After(dates.GetNow()) || dob.Year() < 1900 {
+			return errors.NewBadRequestError(fmt.Sprintf("invalid date_of_birth %s", p.DateOfBirth))
+		}
+	}
+
+	p.Locale = strings.TrimSpace(p.Locale)
+	if p.Locale != "" && !localePattern.MatchString(p.Locale) {
+		return errors.NewBadRequestError(fmt.Sprintf("invalid locale %q", p.Locale))
+	}
+
+	p.Timezone = strings.TrimSpace(p.Timezone)
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil || p.Timezone == "Local" {
+			return errors.NewBadRequestError(fmt.Sprintf("unknown timezone %q", p.Timezone))
+		}
+	}
+	return nil
+}
+
+// HasAvatar returns true if the user uploaded an avatar
+func (p *Profile) HasAvatar() bool {
+	return p.AvatarType != ""
+}
+
+// SetAvatarURLs points the avatar urls at the routes of the API version, the unversioned ones
+// for version 0. They are left empty if the user has no avatar.
+func (p *Profile) SetAvatarURLs(version int) {
+	if !p.HasAvatar() {
+		return
+	}
+	prefix := ""
+	if version != 0 {
+		prefix = fmt.Sprintf("/v%d", version)
+	}
+	p.AvatarURL = fmt.Sprintf("%s/users/%d/avatar", prefix, p.UserID)
+	p.AvatarThumbnailURL = p.AvatarURL + "?size=thumbnail"
+}
+
+// document is the profile as nested in version 2 representations of its user
+func (p *Profile) document() Document {
+	d := Document{
+		keys:   []string{"phone", "date_of_birth", "locale", "timezone"},
+		values: []interface{}{p.Phone, p.DateOfBirth, p.Locale, p.Timezone},
+	}
+	if p.HasAvatar() {
+		d.keys = append(d.keys, "avatar_url", "avatar_thumbnail_url")
+		d.values = append(d.values, p.AvatarURL, p.AvatarThumbnailURL)
+	}
+	if p.DateUpdated != "" {
+		d.keys = append(d.keys, "date_updated")
+		d.values = append(d.values, dates.ToISO8601(p.DateUpdated))
+	}
+	return d
+}
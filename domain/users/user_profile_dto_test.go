@@ -0,0 +1,78 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+)
+
+func TestProfileValidate(t *testing.T) {
+	tomorrow := dates.GetNow().AddDate(0, 0, 1).Format(dateOfBirthLayout)
+
+	tests := []struct {
+		name    string
+		profile Profile
+		want    Profile
+		wantErr bool
+	}{
+		{name: "empty", profile: Profile{}, want: Profile{}},
+		{
+			name:    "normalized",
+			profile: Profile{Phone: " +1 (415) 555-2671 ", DateOfBirth: " 1990-02-03 ", Locale: " pt-BR ", Timezone: " Europe/Lisbon "},
+			want:    Profile{Phone: "+14155552671", DateOfBirth: "1990-02-03", Locale: "pt-BR", Timezone: "Europe/Lisbon"},
+		},
+		{name: "phone of 15 digits", profile: Profile{Phone: "+123456789012345"}, want: Profile{Phone: "+123456789012345"}},
+		{name: "phone of 16 digits", profile: Profile{Phone: "+1234567890123456"}, wantErr: true},
+		{name: "phone without plus", profile: Profile{Phone: "14155552671"}, wantErr: true},
+		{name: "phone with leading zero", profile: Profile{Phone: "+04155552671"}, wantErr: true},
+		{name: "phone with letters", profile: Profile{Phone: "+1415CALLNOW"}, wantErr: true},
+		{name: "phone of a single digit", profile: Profile{Phone: "+1"}, wantErr: true},
+		{name: "date of birth not in YYYY-MM-DD", profile: Profile{DateOfBirth: "03/02/1990"}, wantErr: true},
+		{name: "date of birth in the future", profile: Profile{DateOfBirth: tomorrow}, wantErr: true},
+		{name: "date of birth before 1900", profile: Profile{DateOfBirth: "1899-12-31"}, wantErr: true},
+		{name: "locale", profile: Profile{Locale: "en"}, want: Profile{Locale: "en"}},
+		{name: "invalid locale", profile: Profile{Locale: "english!"}, wantErr: true},
+		{name: "unknown timezone", profile: Profile{Timezone: "Mars/Olympus"}, wantErr: true},
+		{name: "local timezone", profile: Profile{Timezone: "Local"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.profile
+			err := p.Validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Validate(%+v) = nil, want an error", tt.profile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate(%+v): %s", tt.profile, err.Message)
+			}
+			if p != tt.want {
+				t.Errorf("Validate(%+v) = %+v, want %+v", tt.profile, p, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileSetAvatarURLs(t *testing.T) {
+	tests := []struct {
+		version       int
+		avatarType    string
+		wantURL       string
+		wantThumbnail string
+	}{
+		{version: Version1, avatarType: "image/png", wantURL: "/v1/users/7/avatar", wantThumbnail: "/v1/users/7/avatar?size=thumbnail"},
+		{version: Version2, avatarType: "image/png", wantURL: "/v2/users/7/avatar", wantThumbnail: "/v2/users/7/avatar?size=thumbnail"},
+		{version: 0, avatarType: "image/jpeg", wantURL: "/users/7/avatar", wantThumbnail: "/users/7/avatar?size=thumbnail"},
+		{version: Version2},
+	}
+	for _, tt := range tests {
+		p := &Profile{UserID: 7, AvatarType: tt.avatarType}
+		p.SetAvatarURLs(tt.version)
+		if p.AvatarURL != tt.wantURL || p.AvatarThumbnailURL != tt.wantThumbnail {
+			t.Errorf("version %d, avatar %q: urls = %q, %q, want %q, %q",
+				tt.version, tt.avatarType, p.AvatarURL, p.AvatarThumbnailURL, tt.wantURL, tt.wantThumbnail)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package users
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	usersv1 "github.com/sauravgsh16/bookstore_users-api/proto/users/v1"
+)
+
+// MarshalProto encodes the document as a users.v1.User message
+func (d Document) MarshalProto() ([]byte, error) {
+	return proto.Marshal(d.Proto())
+}
+
+// Proto returns the document as a users.v1.User message
+func (d Document) Proto() *usersv1.User {
+	m := new(usersv1.User)
+	for i, key := range d.keys {
+		switch v := d.values[i].(type) {
+		case int:
+			switch key {
+			case "id":
+				m.Id = int64(v)
+			case "version":
+				m.Version = int64(v)
+			}
+		case string:
+			switch key {
+			case "first_name":
+				m.FirstName = v
+			case "last_name":
+				m.LastName = v
+			case "email":
+				m.Email = v
+			case "date_created":
+				m.DateCreated = v
+			case "status":
+				m.Status = v
+			case "deleted_at":
+				m.DeletedAt = v
+			case "erased_at":
+				m.ErasedAt = v
+			}
+		}
+	}
+	return m
+}
+
+// MarshalProto encodes the documents as a users.v1.Users message
+func (d Documents) MarshalProto() ([]byte, error) {
+	m := &usersv1.Users{Users: make([]*usersv1.User, len(d))}
+	for i, doc := range d {
+		m.Users[i] = doc.Proto()
+	}
+	return proto.Marshal(m)
+}
+
+// UnmarshalProto decodes the user from a users.v1.User message. As with JSON bodies the
+// version is left alone, it only ever comes from If-Match.
+func (u *User) UnmarshalProto(data []byte) error {
+	var m usersv1.User
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	u.FromProto(&m)
+	return nil
+}
+
+// FromProto sets the user from a users.v1.User message, but for its version
+func (u *User) FromProto(m *usersv1.User) {
+	u.ID = int(m.Id)
+	u.FirstName = m.FirstName
+	u.LastName = m.LastName
+	u.Email = m.Email
+	u.DateCreated = m.DateCreated
+	u.Status = m.Status
+	u.DeletedAt = m.DeletedAt
+	u.ErasedAt = m.ErasedAt
+	u.Password = m.Password
+}
+
+// UnmarshalProto decodes the credentials from the email and password of a users.v1.User message
+func (r *LoginRequest) UnmarshalProto(data []byte) error {
+	var m usersv1.User
+	if err := proto.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	r.Email = m.Email
+	r.Password = m.Password
+	return nil
+}
@@ -0,0 +1,85 @@
+package users
+
+const (
+	// ActionActivate moves a pending user to active
+	ActionActivate = "activate"
+	// ActionSuspend moves an active user to suspended
+	ActionSuspend = "suspend"
+	// ActionReactivate moves a suspended or deactivated user back to active
+	ActionReactivate = "reactivate"
+	// ActionDeactivate moves a user to deactivated
+	ActionDeactivate = "deactivate"
+	// ActionDelete soft deletes a user
+	ActionDelete = "delete"
+	// ActionRestore returns a soft deleted user to the status held before deletion
+	ActionRestore = "restore"
+	// ActionErase anonymizes a user for good. It is not a transition, see User.Erase.
+	ActionErase = "erase"
+)
+
+// Transition describes the statuses an action may be applied from and the status it leads to
+type Transition struct {
+	From []string
+	To   string
+}
+
+var (
+	// Statuses lists every user status
+	Statuses = []string{StatusPending, StatusActive, StatusSuspended, StatusDeactivated, StatusDeleted}
+
+	transitions = map[string]Transition{
+		ActionActivate:   {From: []string{StatusPending}, To: StatusActive},
+		ActionSuspend:    {From: []string{StatusActive}, To: StatusSuspended},
+		ActionReactivate: {From: []string{StatusSuspended, StatusDeactivated}, To: StatusActive},
+		ActionDeactivate: {From: []string{StatusPending, StatusActive, StatusSuspended}, To: StatusDeactivated},
+		ActionDelete:     {From: []string{StatusPending, StatusActive, StatusSuspended, StatusDeactivated}, To: StatusDeleted},
+		// To is the fallback when the status held before deletion is unknown
+		ActionRestore: {From: []string{StatusDeleted}, To: StatusActive},
+	}
+)
+
+// IsValidStatus returns true if s is one of the enumerated user statuses
+func IsValidStatus(s string) bool {
+	for _, status := range Statuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTransition returns the transition for action
+func GetTransition(action string) (Transition, bool) {
+	t, ok := transitions[action]
+	return t, ok
+}
+
+// Allows returns true if the transition may be applied to a user in status
+func (t Transition) Allows(status string) bool {
+	for _, from := range t.From {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CanLogin returns true if the user status permits signing in
+func (u *User) CanLogin() bool {
+	return u.Status == StatusActive
+}
+
+// StatusChange records a single status transition of a user
+type StatusChange struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"user_id"`
+	Action      string `json:"action"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Reason      string `json:"reason"`
+	Actor       string `json:"actor"`
+	DateCreated string `json:"date_created"`
+}
+
+// StatusChanges is a slice of status changes
+type StatusChanges []*StatusChange
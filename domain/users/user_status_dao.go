@@ -0,0 +1,132 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	queryUpdateStatus       = `UPDATE users SET status=($1), deleted_at=($4), version=version+1 WHERE ID=($2) AND version=($3) RETURNING version;`
+	queryInsertStatusChange = `INSERT INTO user_status_changes(user_id, action, from_status, to_status, reason, actor, date_created) VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING ID;`
+	queryFindLastDeletion   = `SELECT FROM_STATUS FROM user_status_changes WHERE USER_ID=($1) AND TO_STATUS=($2) ORDER BY ID DESC LIMIT 1;`
+	queryFindStatusChanges  = `SELECT ID, USER_ID, ACTION, FROM_STATUS, TO_STATUS, REASON, ACTOR, DATE_CREATED FROM user_status_changes WHERE USER_ID=($1) ORDER BY ID;`
+)
+
+// ChangeStatus moves the user to sc.To, recording the change and the audit entry in the
+// same transaction. The update only applies if the user is still at u.Version. Moving to
+// deleted marks the user as soft deleted, any other status clears the marker.
+func (u *User) ChangeStatus(ctx context.Context, sc *StatusChange, entry *AuditEntry) *errors.RestErr {
+	var deletedAt interface{}
+	if sc.To == StatusDeleted {
+		deletedAt = sc.DateCreated
+	}
+
+	var version int
+	err := runInTx(ctx, usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		update, stmtErr := usersdb.PrepareIn(ctx, tx, queryUpdateStatus)
+		if stmtErr != nil {
+			return stmtErr
+		}
+		if err := update.QueryRowContext(ctx, sc.To, u.ID, u.Version, deletedAt).Scan(&version); err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewPreconditionFailedError("user was modified concurrently")
+			}
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			logger.Error("failed to execute status update, error: ", err)
+			return errors.NewInternalServerError("database error when trying to change status")
+		}
+
+		if err := sc.save(ctx, tx, u.ID); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, u, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	sc.UserID = u.ID
+	u.Status = sc.To
+	u.Version = version
+	if deletedAt != nil {
+		u.DeletedAt = sc.DateCreated
+	} else {
+		u.DeletedAt = ""
+	}
+	return nil
+}
+
+// save records the status change of the user within tx
+func (sc *StatusChange) save(ctx context.Context, tx *sql.Tx, userID int) *errors.RestErr {
+	stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryInsertStatusChange)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	row := stmt.QueryRowContext(ctx, userID, sc.Action, sc.From, sc.To, sc.Reason, sc.Actor, sc.DateCreated)
+	if err := row.Scan(&sc.ID); err != nil {
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to record status change, error: ", err)
+		return errors.NewInternalServerError("database error when trying to change status")
+	}
+	return nil
+}
+
+// FindStatusBeforeDeletion returns the status the user held before it was last deleted
+func (u *User) FindStatusBeforeDeletion(ctx context.Context) (string, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, queryFindLastDeletion)
+	if stmtErr != nil {
+		return "", stmtErr
+	}
+	defer cancel()
+
+	var status string
+	if err := stmt.QueryRowContext(ctx, u.ID, StatusDeleted).Scan(&status); err != nil {
+		if err := handleDBError(err); err != nil {
+			return "", err
+		}
+		return "", errors.NewNotFoundError("no deletion recorded for user")
+	}
+	return status, nil
+}
+
+// FindStatusChanges returns the status history of the user, oldest first
+func (u *User) FindStatusChanges(ctx context.Context) (StatusChanges, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpSearch, queryFindStatusChanges)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, u.ID)
+	if err != nil {
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to execute status history query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	changes := make(StatusChanges, 0)
+	for rows.Next() {
+		sc := new(StatusChange)
+		if err := rows.Scan(&sc.ID, &sc.UserID, &sc.Action, &sc.From, &sc.To, &sc.Reason, &sc.Actor, &sc.DateCreated); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		changes = append(changes, sc)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return changes, nil
+}
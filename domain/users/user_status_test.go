@@ -0,0 +1,42 @@
+package users
+
+import "testing"
+
+func TestTransitions(t *testing.T) {
+	tests := []struct {
+		action string
+		from   string
+		want   bool
+	}{
+		{ActionDelete, StatusPending, true},
+		{ActionDelete, StatusActive, true},
+		{ActionDelete, StatusSuspended, true},
+		{ActionDelete, StatusDeactivated, true},
+		{ActionDelete, StatusDeleted, false},
+		{ActionRestore, StatusDeleted, true},
+		{ActionRestore, StatusActive, false},
+		{ActionRestore, StatusSuspended, false},
+		{ActionActivate, StatusDeleted, false},
+		{ActionReactivate, StatusDeleted, false},
+	}
+	for _, tt := range tests {
+		tr, ok := GetTransition(tt.action)
+		if !ok {
+			t.Fatalf("no transition for %s", tt.action)
+		}
+		if got := tr.Allows(tt.from); got != tt.want {
+			t.Errorf("%s from %s allowed = %v, want %v", tt.action, tt.from, got, tt.want)
+		}
+	}
+
+	if tr, _ := GetTransition(ActionDelete); tr.To != StatusDeleted {
+		t.Errorf("delete leads to %s, want %s", tr.To, StatusDeleted)
+	}
+	// restore falls back to active when the status held before deletion is unknown
+	if tr, _ := GetTransition(ActionRestore); tr.To != StatusActive {
+		t.Errorf("restore leads to %s, want %s", tr.To, StatusActive)
+	}
+	if _, ok := GetTransition(ActionErase); ok {
+		t.Error("erase is a transition, want it applied by User.Erase only")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math/rand"
+	"time"
 
 	"github.com/sauravgsh16/bookstore_users-api/logger"
 
@@ -18,117 +20,228 @@ import (
 )
 
 const (
-	queryInsertUser       = `INSERT INTO users(first_name, last_name, email, date_created, status, password) VALUES($1, $2, $3, $4, $5, $6) RETURNING ID;`
-	querySelectUser       = `SELECT ID, first_name, last_name, email, date_created, status FROM users WHERE ID=($1);`
-	queryUpdateuser       = `UPDATE users SET first_name=($1), last_name=($2), email=($3) WHERE ID=($4);`
-	queryDeleteUser       = `DELETE FROM users WHERE ID=($1);`
-	queryFindUserByStatus = `SELECT ID, FIRST_NAME, LAST_NAME, EMAIL, DATE_CREATED, STATUS FROM users WHERE STATUS=($1);`
-	queryFindByEmailPwd   = `SELECT ID, FIRST_NAME, LAST_NAME, EMAIL, DATE_CREATED, STATUS FROM users WHERE EMAIL=($1) AND PASSWORD=($2);`
+	queryInsertUser       = `INSERT INTO users(first_name, last_name, email, date_created, status, password) VALUES($1, $2, $3, $4, $5, $6) RETURNING ID, version;`
+	querySelectUser       = `SELECT ID, first_name, last_name, email, date_created, status, deleted_at, erased_at, version FROM users WHERE ID=($1) AND (($2) OR deleted_at IS NULL);`
+	queryUpdateuser       = `UPDATE users SET first_name=($1), last_name=($2), email=($3), version=version+1 WHERE ID=($4) AND version=($5) AND deleted_at IS NULL RETURNING version;`
+	queryPurgeDeleted     = `DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ($1) AND erased_at IS NULL RETURNING ID;`
+	queryFindUserByStatus = `SELECT ID, FIRST_NAME, LAST_NAME, EMAIL, DATE_CREATED, STATUS, DELETED_AT, VERSION FROM users WHERE STATUS=($1) AND (($2) OR DELETED_AT IS NULL);`
+	queryFindByEmailPwd   = `SELECT ID, FIRST_NAME, LAST_NAME, EMAIL, DATE_CREATED, STATUS, VERSION FROM users WHERE EMAIL=($1) AND PASSWORD=($2) AND DELETED_AT IS NULL;`
+	queryFindByEmail      = `SELECT ID, FIRST_NAME, LAST_NAME, EMAIL, DATE_CREATED, STATUS, VERSION FROM users WHERE EMAIL=($1) AND DELETED_AT IS NULL;`
 )
 
 var (
 	userDB = make(map[int64]*User)
 )
 
-func getConn() (*sql.Conn, context.Context) {
-	ctx := context.Background()
-	return usersdb.DB.GetConn(ctx), ctx
+// RunInTx runs fn as a single unit of work. DAO methods called with the ctx handed to fn join
+// its transaction, which commits only if fn succeeds. Nested calls run in a savepoint, and the
+// outermost call is retried when aborted by a concurrent transaction, so fn may run more than once.
+func RunInTx(ctx context.Context, fn func(context.Context) *errors.RestErr) *errors.RestErr {
+	return runInTx(ctx, usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		return fn(ctx)
+	})
 }
 
-func handleDBError(err error) *errors.RestErr {
-	err = postgres.ParseError(err)
-	dbErr, ok := err.(*pq.Error)
-	if ok {
-		logger.Error("Query failed with error: ", err)
-		return errors.NewBadRequestError(dbErr.Message)
+// InTx returns true if ctx carries the transaction of a RunInTx call
+func InTx(ctx context.Context) bool {
+	_, ok := usersdb.FromContext(ctx)
+	return ok
+}
+
+// AfterCommit runs fn once the transaction of ctx commits, right away outside of one
+func AfterCommit(ctx context.Context, fn func()) {
+	usersdb.OnCommit(ctx, fn)
+}
+
+// ReadFromPrimary returns a copy of ctx whose reads are never served by a replica
+func ReadFromPrimary(ctx context.Context) context.Context {
+	return usersdb.WithPrimary(ctx)
+}
+
+// runInTx runs fn in the ambient transaction of ctx, or a new one bounded by the timeout
+// configured for op
+func runInTx(ctx context.Context, op string, fn func(context.Context, *sql.Tx) *errors.RestErr) *errors.RestErr {
+	if tx, ok := usersdb.FromContext(ctx); ok {
+		return runInSavepoint(ctx, tx, fn)
 	}
-	return nil
+
+	var err *errors.RestErr
+	for attempt := 1; attempt <= usersdb.MaxTxAttempts; attempt++ {
+		if err = runOnce(ctx, op, fn); err == nil || err.Error != "serialization_failure" {
+			return err
+		}
+		logger.Info(fmt.Sprintf("transaction aborted by a concurrent one, attempt %d of %d", attempt, usersdb.MaxTxAttempts))
+		if attempt < usersdb.MaxTxAttempts {
+			select {
+			case <-time.After(txBackoff(attempt)):
+			case <-ctx.Done():
+				return errors.NewServiceUnavailableError("database timeout, try again later")
+			}
+		}
+	}
+	return err
 }
 
-// Get populates the user pointer or returns error if not found
-func (u *User) Get(userID int) *errors.RestErr {
-	conn, ctx := getConn()
-	defer conn.Close()
+func runOnce(ctx context.Context, op string, fn func(context.Context, *sql.Tx) *errors.RestErr) *errors.RestErr {
+	ctx, cancel := usersdb.WithTimeout(ctx, op)
+	defer cancel()
 
-	stmt, err := conn.PrepareContext(ctx, querySelectUser)
+	tx, err := usersdb.DB.BeginTx(ctx, usersdb.TxOptions)
 	if err != nil {
-		logger.Error("failed to prepare statement: ", err)
-		return errors.NewInternalServerError("database error")
+		return usersdb.PrepareError(err)
 	}
+	defer tx.Rollback()
 
-	row := stmt.QueryRowContext(ctx, userID)
-
-	if err := row.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.DateCreated, &u.Status); err != nil {
+	txCtx := usersdb.NewContext(ctx, tx)
+	if err := fn(txCtx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
 		if err := handleDBError(err); err != nil {
 			return err
 		}
-		return errors.NewNotFoundError(fmt.Sprintf("failed to retrieve rows: %s", err.Error()))
+		logger.Error("failed to commit transaction: ", err)
+		return errors.NewInternalServerError("database error")
+	}
+	if ambient, ok := usersdb.FromContext(txCtx); ok {
+		ambient.Committed()
 	}
 	return nil
 }
 
-// Save the user to the db
-func (u *User) Save() *errors.RestErr {
-	conn, ctx := getConn()
-	defer conn.Close()
-
-	stmt, err := conn.PrepareContext(ctx, queryInsertUser)
+// runInSavepoint runs fn in a savepoint of tx, undoing only its work if it fails
+func runInSavepoint(ctx context.Context, tx *usersdb.Tx, fn func(context.Context, *sql.Tx) *errors.RestErr) *errors.RestErr {
+	name, ctx, err := tx.Savepoint(ctx)
 	if err != nil {
-		logger.Error("failed to prepare statement: ", err)
+		if err := handleDBError(err); err != nil {
+			return err
+		}
+		logger.Error("failed to open savepoint: ", err)
 		return errors.NewInternalServerError("database error")
 	}
 
-	var returnedID int
-
-	if err = stmt.QueryRowContext(ctx, u.FirstName, u.LastName, u.Email, u.DateCreated, u.Status, u.Password).Scan(&returnedID); err != nil {
+	if restErr := fn(ctx, tx.Tx); restErr != nil {
+		if err := tx.RollbackTo(ctx, name); err != nil {
+			logger.Error("failed to roll back to savepoint: ", err)
+		}
+		return restErr
+	}
+	if err := tx.Release(ctx, name); err != nil {
 		if err := handleDBError(err); err != nil {
 			return err
 		}
-		return errors.NewInternalServerError(fmt.Sprintf("error when trying to save user: %s", err.Error()))
+		logger.Error("failed to release savepoint: ", err)
+		return errors.NewInternalServerError("database error")
 	}
-
-	u.ID = returnedID
 	return nil
 }
 
-// Update the user in the db
-func (u *User) Update() *errors.RestErr {
-	conn, ctx := getConn()
-	defer conn.Close()
+// txBackoff returns a jittered delay before the given retry
+func txBackoff(attempt int) time.Duration {
+	d := 10 * time.Millisecond << uint(attempt-1)
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
 
-	stmt, err := conn.PrepareContext(ctx, queryUpdateuser)
-	if err != nil {
-		logger.Error("failed to prepare statement: ", err)
-		return errors.NewInternalServerError("database error")
+func handleDBError(err error) *errors.RestErr {
+	if usersdb.IsRetryable(err) {
+		logger.Error("Transaction aborted by a concurrent one: ", err)
+		return errors.NewSerializationError("conflicting concurrent update, try again")
+	}
+	if usersdb.IsTimeout(err) {
+		logger.Error("Query timed out or was cancelled: ", err)
+		return errors.NewServiceUnavailableError("database timeout, try again later")
+	}
+	err = postgres.ParseError(err)
+	dbErr, ok := err.(*pq.Error)
+	if ok {
+		logger.Error("Query failed with error: ", err)
+		return errors.NewBadRequestError(dbErr.Message)
+	}
+	return nil
+}
+
+// Get populates the user pointer or returns error if not found.
+// Soft deleted users are only returned if includeDeleted is set.
+func (u *User) Get(ctx context.Context, userID int, includeDeleted bool) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, querySelectUser)
+	if stmtErr != nil {
+		return stmtErr
 	}
+	defer cancel()
+
+	row := stmt.QueryRowContext(ctx, userID, includeDeleted)
 
-	if _, err = stmt.ExecContext(ctx, u.FirstName, u.LastName, u.Email, u.ID); err != nil {
+	var deletedAt, erasedAt sql.NullString
+	if err := row.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.DateCreated, &u.Status, &deletedAt, &erasedAt, &u.Version); err != nil {
 		if err := handleDBError(err); err != nil {
 			return err
 		}
-		logger.Error("failed to execute update query, error: ", err)
-		return errors.NewInternalServerError("database error when trying to update")
+		return errors.NewNotFoundError(fmt.Sprintf("failed to retrieve rows: %s", err.Error()))
 	}
+	u.DeletedAt = deletedAt.String
+	u.ErasedAt = erasedAt.String
 	return nil
 }
 
-// FindByEmailPassword finds user by email and password
-func (u *User) FindByEmailPassword(email, pwd string) *errors.RestErr {
-	conn, ctx := getConn()
-	defer conn.Close()
+// Save the user to the db, recording entry in the audit log
+func (u *User) Save(ctx context.Context, entry *AuditEntry) *errors.RestErr {
+	return runInTx(ctx, usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		var returnedID int
 
-	stmt, err := conn.PrepareContext(ctx, queryFindByEmailPwd)
-	if err != nil {
-		logger.Error("failed to prepare statement: ", err)
-	}
+		stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryInsertUser)
+		if stmtErr != nil {
+			return stmtErr
+		}
 
-	fmt.Printf("%#v\n", stmt)
+		row := stmt.QueryRowContext(ctx, u.FirstName, u.LastName, u.Email, u.DateCreated, u.Status, u.Password)
+		if err := row.Scan(&returnedID, &u.Version); err != nil {
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			return errors.NewInternalServerError(fmt.Sprintf("error when trying to save user: %s", err.Error()))
+		}
 
-	row := stmt.QueryRowContext(ctx, email, pwd)
+		u.ID = returnedID
+		entry.UserID = returnedID
+		return recordChange(ctx, tx, u, entry)
+	})
+}
+
+// Update the user in the db if it is still at u.Version, which is then incremented.
+// The entry is recorded in the audit log.
+func (u *User) Update(ctx context.Context, entry *AuditEntry) *errors.RestErr {
+	return runInTx(ctx, usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+		stmt, stmtErr := usersdb.PrepareIn(ctx, tx, queryUpdateuser)
+		if stmtErr != nil {
+			return stmtErr
+		}
+
+		row := stmt.QueryRowContext(ctx, u.FirstName, u.LastName, u.Email, u.ID, u.Version)
+		if err := row.Scan(&u.Version); err != nil {
+			if err == sql.ErrNoRows {
+				return errors.NewPreconditionFailedError("user was modified concurrently")
+			}
+			if err := handleDBError(err); err != nil {
+				return err
+			}
+			logger.Error("failed to execute update query, error: ", err)
+			return errors.NewInternalServerError("database error when trying to update")
+		}
+		return recordChange(ctx, tx, u, entry)
+	})
+}
 
-	fmt.Printf("%#v\n", row)
+// FindByEmailPassword finds user by email and password
+func (u *User) FindByEmailPassword(ctx context.Context, email, pwd string) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, queryFindByEmailPwd)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
 
-	if err := row.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.DateCreated, &u.Status); err != nil {
+	row := stmt.QueryRowContext(ctx, email, pwd)
+
+	if err := row.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.DateCreated, &u.Status, &u.Version); err != nil {
 		if err := handleDBError(err); err != nil {
 			return err
 		}
@@ -137,39 +250,69 @@ func (u *User) FindByEmailPassword(email, pwd string) *errors.RestErr {
 	return nil
 }
 
-// Delete user from db
-func (u *User) Delete() *errors.RestErr {
-	conn, ctx := getConn()
-	defer conn.Close()
-
-	stmt, err := conn.PrepareContext(ctx, queryDeleteUser)
-	if err != nil {
-		logger.Error("failed to prepare statement: ", err)
-		return errors.NewInternalServerError("database error")
+// FindByEmail finds user by email
+func (u *User) FindByEmail(ctx context.Context, email string) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, queryFindByEmail)
+	if stmtErr != nil {
+		return stmtErr
 	}
+	defer cancel()
 
-	if _, err = stmt.ExecContext(ctx, u.ID); err != nil {
+	row := stmt.QueryRowContext(ctx, email)
+	if err := row.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.DateCreated, &u.Status, &u.Version); err != nil {
 		if err := handleDBError(err); err != nil {
 			return err
 		}
-		logger.Error("failed to execute delete query, error: ", err)
-		return errors.NewInternalServerError("database error when trying to delete user")
+		return errors.NewNotFoundError(fmt.Sprintf("failed to retrieve rows: %s", err.Error()))
 	}
 	return nil
 }
 
-// FindByStatus retusn a list of user where status is passed as an agrument
-func (u *User) FindByStatus(status string) ([]*User, *errors.RestErr) {
-	conn, ctx := getConn()
-	defer conn.Close()
+// PurgeDeleted hard deletes users soft deleted before the given time and returns their ids.
+// Erased users are kept.
+func PurgeDeleted(ctx context.Context, before string) ([]int, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpMaintenance, queryPurgeDeleted)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
 
-	stmt, err := conn.PrepareContext(ctx, queryFindUserByStatus)
+	rows, err := stmt.QueryContext(ctx, before)
 	if err != nil {
-		logger.Error("failed to prepare statement: ", err)
+		if err := handleDBError(err); err != nil {
+			return nil, err
+		}
+		logger.Error("failed to execute purge query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to purge users")
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
 		return nil, errors.NewInternalServerError("database error")
 	}
+	return ids, nil
+}
+
+// FindByStatus retusn a list of user where status is passed as an agrument.
+// Soft deleted users are only returned if includeDeleted is set.
+func (u *User) FindByStatus(ctx context.Context, status string, includeDeleted bool) ([]*User, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpSearch, queryFindUserByStatus)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
 
-	rows, err := stmt.QueryContext(ctx, status)
+	rows, err := stmt.QueryContext(ctx, status, includeDeleted)
 	if err != nil {
 		if err := handleDBError(err); err != nil {
 			return nil, err
@@ -183,13 +326,15 @@ func (u *User) FindByStatus(status string) ([]*User, *errors.RestErr) {
 
 	for rows.Next() {
 		u := new(User)
-		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.DateCreated, &u.Status); err != nil {
+		var deletedAt sql.NullString
+		if err := rows.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Email, &u.DateCreated, &u.Status, &deletedAt, &u.Version); err != nil {
 			if err := handleDBError(err); err != nil {
 				return nil, err
 			}
 			logger.Error("failed to scan rows, error: ", err)
 			return nil, errors.NewInternalServerError("database error")
 		}
+		u.DeletedAt = deletedAt.String
 		users = append(users, u)
 	}
 	if err := rows.Err(); err != nil {
@@ -0,0 +1,110 @@
+package users
+
+import (
+	"context"
+	"testing"
+)
+
+func BenchmarkUserGet(b *testing.B) {
+	requireDB(b)
+	ctx := context.Background()
+	saved := saveTestUser(b, "bench")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var u User
+		if err := u.Get(ctx, saved.ID, false); err != nil {
+			b.Fatal(err.Message)
+		}
+	}
+}
+
+func BenchmarkUserSave(b *testing.B) {
+	requireDB(b)
+	ctx := context.Background()
+	us := make([]*User, b.N)
+	for i := range us {
+		us[i] = newTestUser("bench")
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for _, u := range us {
+		if err := u.Save(ctx, NewAuditEntry(AuditActionCreate, testCaller, nil, u)); err != nil {
+			b.Fatal(err.Message)
+		}
+	}
+}
+
+func BenchmarkFindByStatus(b *testing.B) {
+	requireDB(b)
+	ctx := context.Background()
+	suspended := saveTestUser(b, "bench")
+	sc := &StatusChange{Action: ActionSuspend, From: suspended.Status, To: StatusSuspended, Actor: testCaller.Actor, DateCreated: suspended.DateCreated}
+	after := *suspended
+	after.Status = sc.To
+	if err := suspended.ChangeStatus(ctx, sc, NewAuditEntry(ActionSuspend, testCaller, suspended, &after)); err != nil {
+		b.Fatal(err.Message)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var dao User
+		if _, err := dao.FindByStatus(ctx, StatusSuspended, false); err != nil {
+			b.Fatal(err.Message)
+		}
+	}
+}
+
+// backdateDeletion sets when u was soft deleted
+func backdateDeletion(t *testing.T, u *User, deletedAt string) {
+	t.Helper()
+	var id int
+	if err := testQueryRow(t, `UPDATE users SET deleted_at=($1) WHERE id=($2) RETURNING id;`, deletedAt, u.ID).Scan(&id); err != nil {
+		t.Fatalf("backdating the deletion of user %d: %v", u.ID, err)
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+
+	// deletions are backdated before any other test runs, so only these users can be purged
+	expired := saveTestUser(t, "expired")
+	deleteTestUser(t, expired)
+	backdateDeletion(t, expired, "2000-01-01 00:00:00")
+
+	retained := saveTestUser(t, "retained")
+	deleteTestUser(t, retained)
+	backdateDeletion(t, retained, "2000-01-03 00:00:00")
+
+	erased := saveTestUser(t, "erased")
+	deleteTestUser(t, erased)
+	if err := eraseTestUser(erased); err != nil {
+		t.Fatalf("erasing: %s", err.Message)
+	}
+	backdateDeletion(t, erased, "2000-01-01 00:00:00")
+
+	active := saveTestUser(t, "active")
+
+	ids, err := PurgeDeleted(ctx, "2000-01-02 00:00:00")
+	if err != nil {
+		t.Fatalf("PurgeDeleted: %s", err.Message)
+	}
+	if len(ids) != 1 || ids[0] != expired.ID {
+		t.Errorf("purged %v, want only the expired user %d", ids, expired.ID)
+	}
+
+	for _, tt := range []struct {
+		u    *User
+		want bool
+	}{{expired, false}, {retained, true}, {erased, true}, {active, true}} {
+		var got User
+		err := got.Get(ReadFromPrimary(ctx), tt.u.ID, true)
+		if exists := err == nil; exists != tt.want {
+			t.Errorf("user %s exists after the purge = %v, want %v", tt.u.FirstName, exists, tt.want)
+		}
+	}
+}
@@ -7,10 +7,16 @@ import (
 )
 
 const (
+	// StatusPending User awaiting activation status string
+	StatusPending = "pending"
 	// StatusActive User active status string
 	StatusActive = "active"
-	// StatusInactive User inactive status string
-	StatusInactive = "inactive"
+	// StatusSuspended User suspended status string
+	StatusSuspended = "suspended"
+	// StatusDeactivated User deactivated status string
+	StatusDeactivated = "deactivated"
+	// StatusDeleted User deleted status string
+	StatusDeleted = "deleted"
 )
 
 // User struct
@@ -21,7 +27,12 @@ type User struct {
 	Email       string `json:"email"`
 	DateCreated string `json:"date_created"`
 	Status      string `json:"status"`
+	DeletedAt   string `json:"deleted_at,omitempty"`
+	ErasedAt    string `json:"erased_at,omitempty"`
 	Password    string `json:"password"`
+	Version     int    `json:"-"`
+	// Profile is nested in version 2 representations when loaded
+	Profile *Profile `json:"-"`
 }
 
 // Users is a slice of users
@@ -0,0 +1,213 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// raise fails the statement with the SQLSTATE code, as a concurrent transaction would
+func raise(ctx context.Context, tx *sql.Tx, code string) *errors.RestErr {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`DO $$ BEGIN RAISE EXCEPTION 'aborted by the test' USING ERRCODE = '%s'; END $$`, code))
+	if err == nil {
+		return nil
+	}
+	if restErr := handleDBError(err); restErr != nil {
+		return restErr
+	}
+	return errors.NewInternalServerError(err.Error())
+}
+
+// userExists reports whether the user with email was committed
+func userExists(t *testing.T, email string) bool {
+	t.Helper()
+	u := &User{}
+	err := u.FindByEmail(ReadFromPrimary(context.Background()), email)
+	if err != nil && err.Error != "not_found" {
+		t.Fatalf("FindByEmail: %s", err.Message)
+	}
+	return err == nil
+}
+
+func TestRunInTxRetries(t *testing.T) {
+	requireDB(t)
+
+	tests := []struct {
+		name string
+		// failures is how many attempts abort with code before one succeeds
+		failures     int
+		code         string
+		wantAttempts int
+		wantErr      string
+	}{
+		{name: "serialization failure", failures: 1, code: "40001", wantAttempts: 2},
+		{name: "deadlock", failures: 1, code: "40P01", wantAttempts: 2},
+		{name: "aborted on every attempt", failures: usersdb.MaxTxAttempts, code: "40001", wantAttempts: usersdb.MaxTxAttempts, wantErr: "serialization_failure"},
+		{name: "not retryable", failures: 1, code: "23514", wantAttempts: 1, wantErr: "bad_request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			var emails []string
+			committed := 0
+
+			err := runInTx(context.Background(), usersdb.OpWrite, func(ctx context.Context, tx *sql.Tx) *errors.RestErr {
+				attempts++
+				u := newTestUser("retry")
+				emails = append(emails, u.Email)
+				if err := u.Save(ctx, NewAuditEntry(AuditActionCreate, testCaller, nil, u)); err != nil {
+					return err
+				}
+				AfterCommit(ctx, func() { committed++ })
+				if attempts <= tt.failures {
+					return raise(ctx, tx, tt.code)
+				}
+				return nil
+			})
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("runInTx: %s", err.Message)
+			case tt.wantErr != "" && (err == nil || err.Error != tt.wantErr):
+				t.Fatalf("runInTx = %v, want %s", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			// only the work of the last attempt is kept, if it succeeded
+			for i, email := range emails {
+				want := tt.wantErr == "" && i == len(emails)-1
+				if got := userExists(t, email); got != want {
+					t.Errorf("user of attempt %d committed = %v, want %v", i+1, got, want)
+				}
+			}
+			wantCommitted := 0
+			if tt.wantErr == "" {
+				wantCommitted = 1
+			}
+			if committed != wantCommitted {
+				t.Errorf("after commit functions run %d times, want %d", committed, wantCommitted)
+			}
+		})
+	}
+}
+
+func TestRunInTxSavepoints(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	existing := saveTestUser(t, "existing")
+
+	kept, rolledBack, duplicate, afterDuplicate := newTestUser("kept"), newTestUser("rolledback"), newTestUser("duplicate"), newTestUser("after")
+	duplicate.Email = existing.Email
+	var ran []string
+
+	err := RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+		if err := kept.Save(ctx, NewAuditEntry(AuditActionCreate, testCaller, nil, kept)); err != nil {
+			return err
+		}
+
+		// a nested unit of work failing undoes only its own work
+		nestedErr := RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+			if err := rolledBack.Save(ctx, NewAuditEntry(AuditActionCreate, testCaller, nil, rolledBack)); err != nil {
+				return err
+			}
+			AfterCommit(ctx, func() { ran = append(ran, "rolled back") })
+			return errors.NewBadRequestError("undo")
+		})
+		if nestedErr == nil || nestedErr.Message != "undo" {
+			t.Errorf("nested RunInTx = %v, want its own error", nestedErr)
+		}
+
+		// a statement failing in a savepoint leaves the transaction usable
+		if err := duplicate.Save(ctx, NewAuditEntry(AuditActionCreate, testCaller, nil, duplicate)); err == nil {
+			t.Error("saving a duplicate email succeeded")
+		}
+		if err := afterDuplicate.Save(ctx, NewAuditEntry(AuditActionCreate, testCaller, nil, afterDuplicate)); err != nil {
+			return err
+		}
+		AfterCommit(ctx, func() { ran = append(ran, "committed") })
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunInTx: %s", err.Message)
+	}
+
+	for _, tt := range []struct {
+		u    *User
+		want bool
+	}{{kept, true}, {rolledBack, false}, {afterDuplicate, true}} {
+		if got := userExists(t, tt.u.Email); got != tt.want {
+			t.Errorf("user %s committed = %v, want %v", tt.u.FirstName, got, tt.want)
+		}
+	}
+	if fmt.Sprint(ran) != "[committed]" {
+		t.Errorf("after commit functions run = %v, want only those of the committed work", ran)
+	}
+}
+
+func TestRunInTxContext(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	u := newTestUser("ambient")
+	committed := false
+
+	if InTx(ctx) {
+		t.Fatal("InTx outside of RunInTx")
+	}
+	err := RunInTx(ctx, func(txCtx context.Context) *errors.RestErr {
+		if !InTx(txCtx) {
+			t.Error("InTx in RunInTx = false")
+		}
+		if err := u.Save(txCtx, NewAuditEntry(AuditActionCreate, testCaller, nil, u)); err != nil {
+			return err
+		}
+
+		// the DAO reads through the ambient transaction, and only there
+		var got User
+		if err := got.Get(txCtx, u.ID, false); err != nil {
+			t.Errorf("Get in the transaction: %s", err.Message)
+		}
+		var outside User
+		if err := outside.Get(ReadFromPrimary(ctx), u.ID, false); err == nil || err.Status != http.StatusNotFound {
+			t.Errorf("Get outside of the transaction = %v, want not found before commit", err)
+		}
+
+		// nested calls join the same transaction
+		nestedErr := RunInTx(txCtx, func(nested context.Context) *errors.RestErr {
+			if !InTx(nested) {
+				t.Error("InTx in a nested RunInTx = false")
+			}
+			var got User
+			return got.Get(nested, u.ID, false)
+		})
+		if nestedErr != nil {
+			t.Errorf("Get in a nested RunInTx: %s", nestedErr.Message)
+		}
+
+		AfterCommit(txCtx, func() { committed = true })
+		if committed {
+			t.Error("after commit function run before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("RunInTx: %s", err.Message)
+	}
+	if !committed {
+		t.Error("after commit function not run once committed")
+	}
+	if !userExists(t, u.Email) {
+		t.Error("user not committed")
+	}
+
+	ran := false
+	AfterCommit(ctx, func() { ran = true })
+	if !ran {
+		t.Error("AfterCommit outside of a transaction did not run right away")
+	}
+}
@@ -0,0 +1,263 @@
+// DAO - domain access object: Provides the means to access the persistance layers
+// No other layer in the application is responsible for accessing the db
+
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	queryInsertSubscription = `INSERT INTO webhook_subscriptions(url, event_types, secret, date_created) VALUES($1, $2, $3, $4) RETURNING ID;`
+	querySelectSubscription = `SELECT ID, URL, EVENT_TYPES, DATE_CREATED FROM webhook_subscriptions WHERE ID=($1);`
+	queryFindSubscriptions  = `SELECT ID, URL, EVENT_TYPES, DATE_CREATED FROM webhook_subscriptions ORDER BY ID;`
+	queryDeleteSubscription = `DELETE FROM webhook_subscriptions WHERE ID=($1);`
+
+	queryEnqueueDeliveries = `INSERT INTO webhook_deliveries(subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, date_created)
+		SELECT ID, $1, $2, $3, 'pending', 0, $4, $4 FROM webhook_subscriptions WHERE $2 = ANY(EVENT_TYPES) OR '*' = ANY(EVENT_TYPES)
+		ON CONFLICT (subscription_id, event_id) DO NOTHING;`
+	queryClaimDeliveries = `WITH due AS (
+			SELECT ID FROM webhook_deliveries WHERE STATUS='pending' AND NEXT_ATTEMPT_AT <= ($1) ORDER BY ID LIMIT ($3) FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at=($2) FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.attempts, d.date_created, s.url, s.secret;`
+	queryUpdateDelivery = `UPDATE webhook_deliveries SET status=($1), attempts=($2), next_attempt_at=($3), last_status_code=($4), last_error=($5), date_delivered=($6) WHERE ID=($7);`
+	queryFindDeliveries = `SELECT ID, SUBSCRIPTION_ID, EVENT_ID, EVENT_TYPE, STATUS, ATTEMPTS, NEXT_ATTEMPT_AT, LAST_STATUS_CODE, LAST_ERROR, DATE_CREATED, DATE_DELIVERED
+		FROM webhook_deliveries WHERE SUBSCRIPTION_ID=($1) ORDER BY ID DESC LIMIT ($2) OFFSET ($3);`
+	queryRedeliver = `UPDATE webhook_deliveries SET status='pending', attempts=0, next_attempt_at=($1) WHERE ID=($2) AND SUBSCRIPTION_ID=($3);`
+	// deliveries hold the event envelope, the user fields being in its payload
+	queryRedactUser = `UPDATE webhook_deliveries d SET payload=jsonb_set(d.payload, '{payload}', user_redact_pii(d.payload->'payload'))
+		FROM user_outbox o WHERE d.event_id = o.id AND o.aggregate_id=($1) AND jsonb_typeof(d.payload->'payload') = 'object';`
+)
+
+// Save the subscription to the db
+func (s *Subscription) Save(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryInsertSubscription)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	if err := stmt.QueryRowContext(ctx, s.URL, pq.Array(s.EventTypes), s.Secret, s.DateCreated).Scan(&s.ID); err != nil {
+		logger.Error("failed to save subscription, error: ", err)
+		return errors.NewInternalServerError("database error when trying to save subscription")
+	}
+	return nil
+}
+
+// Get populates the subscription, without its secret
+func (s *Subscription) Get(ctx context.Context, id int) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpRead, querySelectSubscription)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	if err := stmt.QueryRowContext(ctx, id).Scan(&s.ID, &s.URL, pq.Array(&s.EventTypes), &s.DateCreated); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.NewNotFoundError(fmt.Sprintf("webhook subscription %d not found", id))
+		}
+		logger.Error("failed to get subscription, error: ", err)
+		return errors.NewInternalServerError("database error")
+	}
+	return nil
+}
+
+// FindAll returns every subscription, without their secrets
+func (s *Subscription) FindAll(ctx context.Context) (Subscriptions, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpSearch, queryFindSubscriptions)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		logger.Error("failed to execute subscription query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	result := make(Subscriptions, 0)
+	for rows.Next() {
+		sub := new(Subscription)
+		if err := rows.Scan(&sub.ID, &sub.URL, pq.Array(&sub.EventTypes), &sub.DateCreated); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		result = append(result, sub)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return result, nil
+}
+
+// Delete the subscription and its delivery log
+func (s *Subscription) Delete(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryDeleteSubscription)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	res, err := stmt.ExecContext(ctx, s.ID)
+	if err != nil {
+		logger.Error("failed to delete subscription, error: ", err)
+		return errors.NewInternalServerError("database error when trying to delete subscription")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.NewNotFoundError(fmt.Sprintf("webhook subscription %d not found", s.ID))
+	}
+	return nil
+}
+
+// EnqueueEvent creates a pending delivery of the event for every matching subscription.
+// Enqueueing the same event twice is a no-op.
+func EnqueueEvent(ctx context.Context, eventID int64, eventType string, payload []byte, now string) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryEnqueueDeliveries)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	if _, err := stmt.ExecContext(ctx, eventID, eventType, string(payload), now); err != nil {
+		logger.Error("failed to enqueue webhook deliveries, error: ", err)
+		return errors.NewInternalServerError("database error when trying to enqueue deliveries")
+	}
+	return nil
+}
+
+// RedactUser removes the personal data of the user from the payload of its event deliveries
+func RedactUser(ctx context.Context, userID int) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryRedactUser)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	if _, err := stmt.ExecContext(ctx, userID); err != nil {
+		logger.Error("failed to redact webhook deliveries, error: ", err)
+		return errors.NewInternalServerError("database error when trying to redact deliveries")
+	}
+	return nil
+}
+
+// ClaimDue returns up to limit deliveries due at now, pushing their next attempt to leaseUntil
+// so no other dispatcher picks them up while they are being sent
+func ClaimDue(ctx context.Context, now, leaseUntil string, limit int) (Deliveries, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpMaintenance, queryClaimDeliveries)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, now, leaseUntil, limit)
+	if err != nil {
+		logger.Error("failed to claim webhook deliveries, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to claim deliveries")
+	}
+	defer rows.Close()
+
+	result := make(Deliveries, 0)
+	for rows.Next() {
+		d := &Delivery{Status: DeliveryPending}
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &d.DateCreated, &d.URL, &d.Secret); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return result, nil
+}
+
+// RecordAttempt stores the outcome of the latest attempt
+func (d *Delivery) RecordAttempt(ctx context.Context) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryUpdateDelivery)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	_, err := stmt.ExecContext(ctx,
+		d.Status, d.Attempts, nullString(d.NextAttemptAt), d.LastStatusCode, d.LastError, nullString(d.DateDelivered), d.ID)
+	if err != nil {
+		logger.Error("failed to record webhook attempt, error: ", err)
+		return errors.NewInternalServerError("database error when trying to record attempt")
+	}
+	return nil
+}
+
+// FindDeliveries returns a page of the subscription delivery log, newest first
+func (s *Subscription) FindDeliveries(ctx context.Context, page, perPage int) (Deliveries, *errors.RestErr) {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpSearch, queryFindDeliveries)
+	if stmtErr != nil {
+		return nil, stmtErr
+	}
+	defer cancel()
+
+	rows, err := stmt.QueryContext(ctx, s.ID, perPage, (page-1)*perPage)
+	if err != nil {
+		logger.Error("failed to execute delivery query, error: ", err)
+		return nil, errors.NewInternalServerError("database error when trying to execute query")
+	}
+	defer rows.Close()
+
+	result := make(Deliveries, 0)
+	for rows.Next() {
+		var next, delivered sql.NullString
+		d := new(Delivery)
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &next, &d.LastStatusCode, &d.LastError, &d.DateCreated, &delivered); err != nil {
+			logger.Error("failed to scan rows, error: ", err)
+			return nil, errors.NewInternalServerError("database error")
+		}
+		d.NextAttemptAt = next.String
+		d.DateDelivered = delivered.String
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("Row error: ", err)
+		return nil, errors.NewInternalServerError("database error")
+	}
+	return result, nil
+}
+
+// Redeliver resets the delivery so it is sent again at now with a fresh retry budget
+func (s *Subscription) Redeliver(ctx context.Context, deliveryID int64, now string) *errors.RestErr {
+	stmt, ctx, cancel, stmtErr := usersdb.Prepare(ctx, usersdb.OpWrite, queryRedeliver)
+	if stmtErr != nil {
+		return stmtErr
+	}
+	defer cancel()
+
+	res, err := stmt.ExecContext(ctx, now, deliveryID, s.ID)
+	if err != nil {
+		logger.Error("failed to schedule redelivery, error: ", err)
+		return errors.NewInternalServerError("database error when trying to redeliver")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.NewNotFoundError(fmt.Sprintf("delivery %d not found for subscription %d", deliveryID, s.ID))
+	}
+	return nil
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,84 @@
+// DTO - domain transfer object - Provides the definitions of the database objects
+
+package webhooks
+
+import (
+	"net/url"
+	"strings"
+)
+
+const (
+	// DeliveryPending is waiting for its next attempt
+	DeliveryPending = "pending"
+	// DeliverySucceeded was acknowledged with a 2xx response
+	DeliverySucceeded = "succeeded"
+	// DeliveryDead exhausted its attempts and will only be retried on manual redelivery
+	DeliveryDead = "dead"
+
+	// AllEvents subscribes to every event type
+	AllEvents = "*"
+)
+
+// Subscription is a partner endpoint receiving user events
+type Subscription struct {
+	ID          int      `json:"id"`
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types"`
+	Secret      string   `json:"secret,omitempty"`
+	DateCreated string   `json:"date_created"`
+}
+
+// Subscriptions is a slice of subscriptions
+type Subscriptions []*Subscription
+
+// Delivery is a single event sent to a subscription
+type Delivery struct {
+	ID             int64  `json:"id"`
+	SubscriptionID int    `json:"subscription_id"`
+	EventID        int64  `json:"event_id"`
+	EventType      string `json:"event_type"`
+	Payload        []byte `json:"-"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	NextAttemptAt  string `json:"next_attempt_at,omitempty"`
+	LastStatusCode int    `json:"last_status_code,omitempty"`
+	LastError      string `json:"last_error,omitempty"`
+	DateCreated    string `json:"date_created"`
+	DateDelivered  string `json:"date_delivered,omitempty"`
+
+	// set when the delivery is claimed for sending
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// Deliveries is a slice of deliveries
+type Deliveries []*Delivery
+
+// Validate if the subscription fields are accepted against the known event types
+func (s *Subscription) Validate(known []string) bool {
+	s.URL = strings.TrimSpace(s.URL)
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+
+	if len(s.EventTypes) == 0 {
+		return false
+	}
+	for _, t := range s.EventTypes {
+		if t == AllEvents {
+			continue
+		}
+		found := false
+		for _, k := range known {
+			if t == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
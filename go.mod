@@ -3,9 +3,14 @@ module github.com/sauravgsh16/bookstore_users-api
 go 1.13
 
 require (
-	github.com/gin-gonic/gin v1.5.0
+	github.com/alicebob/miniredis/v2 v2.16.0
+	github.com/gin-gonic/gin v1.7.7
+	github.com/golang/protobuf v1.3.3
 	github.com/graphql-go/graphql v0.7.8
 	github.com/graphql-go/handler v0.2.3
 	github.com/lib/pq v1.2.0
+	github.com/ugorji/go/codec v1.1.7
 	go.uber.org/zap v1.13.0
+	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55
+	google.golang.org/grpc v1.27.1
 )
@@ -0,0 +1,86 @@
+// Package idempotency remembers the responses of requests made with an Idempotency-Key, so
+// retries of the same request get the same response instead of repeating its effects
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"go.uber.org/zap"
+)
+
+// ErrNotOwner is returned when completing or releasing a key claimed by another request
+var ErrNotOwner = errors.New("idempotency: key is not held by this request")
+
+// Response is a stored response, replayed to retries
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Key is a client chosen key, scoped to the caller and the route it was sent to so clients
+// never see the responses of each other
+type Key struct {
+	Actor  string
+	Method string
+	Route  string
+	Value  string
+}
+
+// Record is what is known of a key
+type Record struct {
+	Fingerprint string
+	// Response is nil while the first request is still in flight
+	Response *Response
+}
+
+// Store keeps keys and their responses until they expire
+type Store interface {
+	// Begin claims key for a request with fingerprint. It returns nil once the key is claimed,
+	// the existing record if another request already holds it. Expired keys are claimed
+	// again, as are keys whose request did not complete within the lock timeout.
+	Begin(ctx context.Context, key Key, fingerprint string) (*Record, error)
+	// Complete stores the response of the request holding key
+	Complete(ctx context.Context, key Key, fingerprint string, resp *Response) error
+	// Release frees key after its request failed, so it can be retried
+	Release(ctx context.Context, key Key, fingerprint string) error
+	// Purge removes the expired keys
+	Purge(ctx context.Context) error
+}
+
+// New returns the store selected by IDEMPOTENCY_STORE, postgres or memory. Keys expire after
+// IDEMPOTENCY_KEY_TTL, requests holding a key for longer than IDEMPOTENCY_LOCK_TIMEOUT are
+// considered lost.
+func New() (Store, error) {
+	ttl := config.GetDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	lockTimeout := config.GetDuration("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute)
+
+	switch kind := config.GetString("IDEMPOTENCY_STORE", "postgres"); kind {
+	case "postgres":
+		return NewPostgresStore(ttl, lockTimeout), nil
+	case "memory":
+		return NewMemoryStore(ttl, lockTimeout), nil
+	default:
+		return nil, fmt.Errorf("unknown idempotency store %q", kind)
+	}
+}
+
+// StartPurgeJob periodically removes the expired keys of store
+func StartPurgeJob(store Store, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := store.Purge(context.Background()); err != nil {
+				logger.Info("failed to purge idempotency keys", zap.String("error", err.Error()))
+			}
+		}
+	}()
+}
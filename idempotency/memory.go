@@ -0,0 +1,85 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps keys in process, for a single instance or development
+type MemoryStore struct {
+	ttl         time.Duration
+	lockTimeout time.Duration
+
+	mux     sync.Mutex
+	entries map[Key]*memoryEntry
+}
+
+type memoryEntry struct {
+	record      Record
+	lockedUntil time.Time
+	expiresAt   time.Time
+}
+
+// NewMemoryStore returns an empty store keeping keys for ttl
+func NewMemoryStore(ttl, lockTimeout time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, lockTimeout: lockTimeout, entries: make(map[Key]*memoryEntry)}
+}
+
+// Begin claims key unless a live request or response holds it
+func (s *MemoryStore) Begin(ctx context.Context, key Key, fingerprint string) (*Record, error) {
+	now := time.Now()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) && (e.record.Response != nil || now.Before(e.lockedUntil)) {
+		record := e.record
+		return &record, nil
+	}
+	s.entries[key] = &memoryEntry{
+		record:      Record{Fingerprint: fingerprint},
+		lockedUntil: now.Add(s.lockTimeout),
+		expiresAt:   now.Add(s.ttl),
+	}
+	return nil, nil
+}
+
+// Complete stores the response of key
+func (s *MemoryStore) Complete(ctx context.Context, key Key, fingerprint string, resp *Response) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.record.Fingerprint != fingerprint || e.record.Response != nil {
+		return ErrNotOwner
+	}
+	e.record.Response = resp
+	return nil
+}
+
+// Release forgets key while its request is in flight
+func (s *MemoryStore) Release(ctx context.Context, key Key, fingerprint string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || e.record.Fingerprint != fingerprint || e.record.Response != nil {
+		return ErrNotOwner
+	}
+	delete(s.entries, key)
+	return nil
+}
+
+// Purge removes the expired keys
+func (s *MemoryStore) Purge(ctx context.Context) error {
+	now := time.Now()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
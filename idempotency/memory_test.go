@@ -0,0 +1,129 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	key := Key{Actor: "user:1", Method: http.MethodPost, Route: "/users", Value: "a"}
+	resp := &Response{Status: http.StatusCreated, Body: []byte("created")}
+
+	tests := []struct {
+		name string
+		// steps runs against the store, returning the record of the last Begin of key
+		steps           func(*MemoryStore) (*Record, error)
+		wantFingerprint string
+		wantResponse    bool
+		wantErr         error
+	}{
+		{
+			name:  "claims a new key",
+			steps: func(s *MemoryStore) (*Record, error) { return s.Begin(ctx, key, "f1") },
+		},
+		{
+			name: "in flight",
+			steps: func(s *MemoryStore) (*Record, error) {
+				s.Begin(ctx, key, "f1")
+				return s.Begin(ctx, key, "f2")
+			},
+			wantFingerprint: "f1",
+		},
+		{
+			name: "completed",
+			steps: func(s *MemoryStore) (*Record, error) {
+				s.Begin(ctx, key, "f1")
+				s.Complete(ctx, key, "f1", resp)
+				return s.Begin(ctx, key, "f1")
+			},
+			wantFingerprint: "f1",
+			wantResponse:    true,
+		},
+		{
+			name: "released",
+			steps: func(s *MemoryStore) (*Record, error) {
+				s.Begin(ctx, key, "f1")
+				s.Release(ctx, key, "f1")
+				return s.Begin(ctx, key, "f1")
+			},
+		},
+		{
+			name: "other scopes are independent",
+			steps: func(s *MemoryStore) (*Record, error) {
+				for _, other := range []Key{
+					{Actor: "user:2", Method: key.Method, Route: key.Route, Value: key.Value},
+					{Actor: key.Actor, Method: http.MethodPut, Route: key.Route, Value: key.Value},
+					{Actor: key.Actor, Method: key.Method, Route: "/users/:user_id/suspend", Value: key.Value},
+				} {
+					s.Begin(ctx, other, "f1")
+					s.Complete(ctx, other, "f1", resp)
+				}
+				return s.Begin(ctx, key, "f1")
+			},
+		},
+		{
+			name: "lost request",
+			steps: func(s *MemoryStore) (*Record, error) {
+				s.Begin(ctx, key, "f1")
+				time.Sleep(20 * time.Millisecond)
+				return s.Begin(ctx, key, "f2")
+			},
+		},
+		{
+			name: "completed by another request",
+			steps: func(s *MemoryStore) (*Record, error) {
+				s.Begin(ctx, key, "f1")
+				return nil, s.Complete(ctx, key, "f2", resp)
+			},
+			wantErr: ErrNotOwner,
+		},
+		{
+			name: "released after completing",
+			steps: func(s *MemoryStore) (*Record, error) {
+				s.Begin(ctx, key, "f1")
+				s.Complete(ctx, key, "f1", resp)
+				return nil, s.Release(ctx, key, "f1")
+			},
+			wantErr: ErrNotOwner,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := tt.steps(NewMemoryStore(time.Hour, 10*time.Millisecond))
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantFingerprint == "" {
+				if record != nil {
+					t.Fatalf("got record %+v, want the key claimed", record)
+				}
+				return
+			}
+			if record == nil || record.Fingerprint != tt.wantFingerprint {
+				t.Fatalf("got record %+v, want fingerprint %s", record, tt.wantFingerprint)
+			}
+			if (record.Response != nil) != tt.wantResponse {
+				t.Errorf("got response %+v, want stored %v", record.Response, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10*time.Millisecond, time.Minute)
+	key := Key{Actor: "anonymous", Method: http.MethodPost, Route: "/users", Value: "a"}
+	s.Begin(ctx, key, "f1")
+	s.Complete(ctx, key, "f1", &Response{Status: http.StatusCreated})
+
+	time.Sleep(20 * time.Millisecond)
+	if err := s.Purge(ctx); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if len(s.entries) != 0 {
+		t.Errorf("got %d entries after purging, want 0", len(s.entries))
+	}
+}
@@ -0,0 +1,131 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+)
+
+const (
+	// queryClaimKey inserts the key, or takes over one that expired or whose request was lost
+	queryClaimKey = `INSERT INTO idempotency_keys(actor, method, route, key, fingerprint, locked_until, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (actor, method, route, key) DO UPDATE SET fingerprint=EXCLUDED.fingerprint, status=NULL, header=NULL, body=NULL,
+			locked_until=EXCLUDED.locked_until, expires_at=EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= ($8) OR (idempotency_keys.status IS NULL AND idempotency_keys.locked_until <= ($8))
+		RETURNING key;`
+	queryGetKey = `SELECT fingerprint, status, header, body FROM idempotency_keys
+		WHERE actor=($1) AND method=($2) AND route=($3) AND key=($4);`
+	queryCompleteKey = `UPDATE idempotency_keys SET status=($6), header=($7), body=($8)
+		WHERE actor=($1) AND method=($2) AND route=($3) AND key=($4) AND fingerprint=($5) AND status IS NULL;`
+	queryReleaseKey = `DELETE FROM idempotency_keys
+		WHERE actor=($1) AND method=($2) AND route=($3) AND key=($4) AND fingerprint=($5) AND status IS NULL;`
+	queryPurgeKeys = `DELETE FROM idempotency_keys WHERE expires_at <= ($1);`
+)
+
+// PostgresStore keeps keys in the users database, shared by every instance
+type PostgresStore struct {
+	ttl         time.Duration
+	lockTimeout time.Duration
+}
+
+// NewPostgresStore returns a store keeping keys for ttl
+func NewPostgresStore(ttl, lockTimeout time.Duration) *PostgresStore {
+	return &PostgresStore{ttl: ttl, lockTimeout: lockTimeout}
+}
+
+func exec(ctx context.Context, op, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := usersdb.WithTimeout(ctx, op)
+	defer cancel()
+
+	stmt, err := usersdb.DB.Prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+// Begin claims key unless a live request or response holds it
+func (s *PostgresStore) Begin(ctx context.Context, key Key, fingerprint string) (*Record, error) {
+	ctx, cancel := usersdb.WithTimeout(ctx, usersdb.OpWrite)
+	defer cancel()
+
+	claim, err := usersdb.DB.Prepare(ctx, queryClaimKey)
+	if err != nil {
+		return nil, err
+	}
+	now := dates.GetNow()
+	var claimed string
+	err = claim.QueryRowContext(ctx, key.Actor, key.Method, key.Route, key.Value, fingerprint,
+		dates.GetDBString(now.Add(s.lockTimeout)), dates.GetDBString(now.Add(s.ttl)), dates.GetDBString(now)).Scan(&claimed)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	get, err := usersdb.DB.Prepare(ctx, queryGetKey)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		record Record
+		status sql.NullInt64
+		header []byte
+		body   []byte
+	)
+	if err := get.QueryRowContext(ctx, key.Actor, key.Method, key.Route, key.Value).Scan(&record.Fingerprint, &status, &header, &body); err != nil {
+		return nil, err
+	}
+	if status.Valid {
+		record.Response = &Response{Status: int(status.Int64), Body: body}
+		if err := json.Unmarshal(header, &record.Response.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &record, nil
+}
+
+// Complete stores the response of key
+func (s *PostgresStore) Complete(ctx context.Context, key Key, fingerprint string, resp *Response) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	res, err := exec(ctx, usersdb.OpWrite, queryCompleteKey, key.Actor, key.Method, key.Route, key.Value, fingerprint, resp.Status, string(header), resp.Body)
+	if err != nil {
+		return err
+	}
+	return checkOwner(res)
+}
+
+// Release forgets key while its request is in flight
+func (s *PostgresStore) Release(ctx context.Context, key Key, fingerprint string) error {
+	res, err := exec(ctx, usersdb.OpWrite, queryReleaseKey, key.Actor, key.Method, key.Route, key.Value, fingerprint)
+	if err != nil {
+		return err
+	}
+	return checkOwner(res)
+}
+
+// Purge removes the expired keys
+func (s *PostgresStore) Purge(ctx context.Context) error {
+	_, err := exec(ctx, usersdb.OpMaintenance, queryPurgeKeys, dates.GetDBString(dates.GetNow()))
+	return err
+}
+
+func checkOwner(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotOwner
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/utils/auth"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+const (
+	// AccessTokenHeader carries the access token issued to a user on sign in
+	AccessTokenHeader = "X-Access-Token"
+	// CallerIDKey is the gin context key holding the ID of the signed in user making the request
+	CallerIDKey = "caller_id"
+
+	bearerPrefix = "Bearer "
+)
+
+// Authenticate resolves the signed in user from an Authorization: Bearer access token. Requests
+// without one are anonymous, those with an invalid or expired one are rejected.
+func Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		userID, ok := 0, false
+		if strings.HasPrefix(header, bearerPrefix) {
+			userID, ok = auth.ParseToken(strings.TrimSpace(header[len(bearerPrefix):]))
+		}
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			err := errors.NewUnauthorizedError("invalid or expired access token")
+			render.Respond(c, err.Status, err)
+			c.Abort()
+			return
+		}
+		c.Set(CallerIDKey, userID)
+		c.Next()
+	}
+}
@@ -0,0 +1,15 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+)
+
+// DBSession pins the reads of a request to the primary database once it has written,
+// so it never reads stale data from a lagging replica
+func DBSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(usersdb.WithSession(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,163 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/idempotency"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client chosen key of a retryable request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks responses replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// Idempotency makes POST requests carrying an Idempotency-Key safe to retry. Keys are kept per
+// caller, method and route. The first response is stored with a fingerprint of the request and
+// replayed to retries, a retry with another target, Accept header or body answers 422 and one
+// racing the first request 409. Server errors and panics are not stored, so the request can be
+// retried.
+func Idempotency(store idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || value == "" {
+			c.Next()
+			return
+		}
+		if len(value) > 255 {
+			abort(c, errors.NewBadRequestError("idempotency key must be at most 255 characters"))
+			return
+		}
+
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			abort(c, errors.NewBadRequestError("failed to read request body"))
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		key := keyOf(c, value)
+		fingerprint := fingerprintOf(c.Request, body)
+
+		ctx := c.Request.Context()
+		record, err := store.Begin(ctx, key, fingerprint)
+		if err != nil {
+			logger.Error("failed to claim idempotency key", err)
+			abort(c, errors.NewServiceUnavailableError("idempotency keys are unavailable"))
+			return
+		}
+		if record != nil {
+			switch {
+			case record.Fingerprint != fingerprint:
+				abort(c, errors.NewUnprocessableEntityError("idempotency key was used for a different request"))
+			case record.Response == nil:
+				abort(c, errors.NewConflictError("a request with this idempotency key is in progress"))
+			default:
+				replay(c, record.Response)
+			}
+			return
+		}
+
+		// a panicking handler sent no response to replay, the key is released for the retry
+		defer func() {
+			if p := recover(); p != nil {
+				if err := store.Release(context.Background(), key, fingerprint); err != nil {
+					logger.Info("failed to release idempotency key", zap.String("key", value), zap.String("error", err.Error()))
+				}
+				panic(p)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// the response is already sent, so the key is settled even if the client went away
+		ctx = context.Background()
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			err = store.Release(ctx, key, fingerprint)
+		} else {
+			header := recorder.Header().Clone()
+			header.Del(RequestIDHeader)
+			err = store.Complete(ctx, key, fingerprint, &idempotency.Response{
+				Status: status,
+				Header: header,
+				Body:   recorder.body.Bytes(),
+			})
+		}
+		if err != nil {
+			logger.Info("failed to settle idempotency key", zap.String("key", value), zap.String("error", err.Error()))
+		}
+	}
+}
+
+// keyOf scopes the key value of a request to its caller, method and route. Signed in callers
+// are told apart by the user set by Authenticate, the others by the actor they name.
+func keyOf(c *gin.Context, value string) idempotency.Key {
+	actor := "anonymous"
+	if id := c.GetInt(CallerIDKey); id != 0 {
+		actor = "user:" + strconv.Itoa(id)
+	} else if name := c.GetHeader("X-Actor"); name != "" {
+		actor = "actor:" + name
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return idempotency.Key{Actor: actor, Method: c.Request.Method, Route: route, Value: value}
+}
+
+// fingerprintOf identifies a request by its target, body and Accept header, which selects the
+// format and API version of the response replayed
+func fingerprintOf(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write([]byte(r.Header.Get("Accept")))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(c *gin.Context, resp *idempotency.Response) {
+	for name, values := range resp.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(resp.Status)
+	c.Writer.Write(resp.Body)
+	c.Abort()
+}
+
+func abort(c *gin.Context, err *errors.RestErr) {
+	render.Respond(c, err.Status, err)
+	c.Abort()
+}
+
+// responseRecorder keeps a copy of the body written through it
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
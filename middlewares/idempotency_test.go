@@ -0,0 +1,101 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/idempotency"
+	"github.com/sauravgsh16/bookstore_users-api/utils/auth"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	created := func(c *gin.Context) {
+		calls++
+		c.String(http.StatusCreated, strconv.Itoa(calls))
+	}
+	failures := 0
+	flaky := func(c *gin.Context) {
+		if failures++; failures == 1 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		created(c)
+	}
+	panics := 0
+	panicky := func(c *gin.Context) {
+		if panics++; panics == 1 {
+			panic("handler failed")
+		}
+		created(c)
+	}
+
+	engine := gin.New()
+	engine.Use(gin.RecoveryWithWriter(ioutil.Discard), Authenticate(), Idempotency(idempotency.NewMemoryStore(time.Hour, time.Minute)))
+	engine.POST("/users", created)
+	engine.POST("/users/:user_id/suspend", created)
+	engine.POST("/flaky", flaky)
+	engine.POST("/panicky", panicky)
+
+	signedIn := func(id int) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + auth.NewToken(id)}
+	}
+
+	// requests run in order against the same store
+	tests := []struct {
+		name     string
+		target   string
+		key      string
+		body     string
+		headers  map[string]string
+		status   int
+		response string
+		replayed bool
+	}{
+		{name: "first request", target: "/users", key: "a", body: "{}", status: http.StatusCreated, response: "1"},
+		{name: "retry", target: "/users", key: "a", body: "{}", status: http.StatusCreated, response: "1", replayed: true},
+		{name: "retry with another body", target: "/users", key: "a", body: `{"x":1}`, status: http.StatusUnprocessableEntity},
+		{name: "retry accepting another format", target: "/users", key: "a", body: "{}", headers: map[string]string{"Accept": "application/xml"}, status: http.StatusUnprocessableEntity},
+		{name: "without a key", target: "/users", body: "{}", status: http.StatusCreated, response: "2"},
+		{name: "another actor", target: "/users", key: "a", body: "{}", headers: map[string]string{"X-Actor": "admin"}, status: http.StatusCreated, response: "3"},
+		{name: "signed in", target: "/users", key: "a", body: "{}", headers: signedIn(1), status: http.StatusCreated, response: "4"},
+		{name: "signed in naming an actor", target: "/users", key: "a", body: "{}", headers: map[string]string{"Authorization": signedIn(1)["Authorization"], "X-Actor": "admin"}, status: http.StatusCreated, response: "4", replayed: true},
+		{name: "another user", target: "/users", key: "a", body: "{}", headers: signedIn(2), status: http.StatusCreated, response: "5"},
+		{name: "another route", target: "/users/1/suspend", key: "a", body: "{}", status: http.StatusCreated, response: "6"},
+		{name: "same route, another target", target: "/users/2/suspend", key: "a", body: "{}", status: http.StatusUnprocessableEntity},
+		{name: "server error", target: "/flaky", key: "b", body: "{}", status: http.StatusInternalServerError},
+		{name: "retry after a server error", target: "/flaky", key: "b", body: "{}", status: http.StatusCreated, response: "7"},
+		{name: "panic", target: "/panicky", key: "c", body: "{}", status: http.StatusInternalServerError},
+		{name: "retry after a panic", target: "/panicky", key: "c", body: "{}", status: http.StatusCreated, response: "8"},
+		{name: "retry after a panic completed", target: "/panicky", key: "c", body: "{}", status: http.StatusCreated, response: "8", replayed: true},
+		{name: "key too long", target: "/users", key: strings.Repeat("k", 256), body: "{}", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+		if tt.key != "" {
+			req.Header.Set(IdempotencyKeyHeader, tt.key)
+		}
+		for name, value := range tt.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+			continue
+		}
+		if tt.response != "" && rec.Body.String() != tt.response {
+			t.Errorf("%s: got response %q, want %q", tt.name, rec.Body.String(), tt.response)
+		}
+		if replayed := rec.Header().Get(IdempotentReplayedHeader) == "true"; replayed != tt.replayed {
+			t.Errorf("%s: replayed = %v, want %v", tt.name, replayed, tt.replayed)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/utils/metrics"
+)
+
+// unmatchedRoute labels requests no route matched, so their paths never become label values
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = metrics.NewCounter("http_requests_total",
+		"HTTP requests served, by method, route and status", "method", "route", "status")
+	httpDuration = metrics.NewHistogram("http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by method and route", metrics.DefBuckets, "method", "route")
+	httpInFlight = metrics.NewGauge("http_requests_in_flight", "HTTP requests being served")
+
+	knownMethods = map[string]bool{
+		http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+		http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+	}
+)
+
+// Metrics records the rate, errors and duration of requests by route template, such as
+// /users/:user_id, rather than by path
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		httpInFlight.Add(1)
+		defer httpInFlight.Add(-1)
+
+		c.Next()
+
+		method := c.Request.Method
+		if !knownMethods[method] {
+			method = metrics.Overflow
+		}
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		httpRequests.Inc(method, route, strconv.Itoa(c.Writer.Status()))
+		httpDuration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
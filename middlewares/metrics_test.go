@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/utils/metrics"
+)
+
+func TestMetricsLabels(t *testing.T) {
+	engine := gin.New()
+	engine.Use(Metrics())
+	for _, method := range []string{http.MethodGet, "PROPFIND"} {
+		engine.Handle(method, "/metrics-test/:user_id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+
+	for _, r := range []struct{ method, path string }{
+		{http.MethodGet, "/metrics-test/1"},
+		{http.MethodGet, "/metrics-test/2"},
+		{"PROPFIND", "/metrics-test/3"},
+		{http.MethodGet, "/metrics-test/1/unknown"},
+	} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.path, nil))
+	}
+
+	var buf bytes.Buffer
+	if _, err := metrics.DefaultRegistry.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	for _, want := range []string{
+		// paths are labelled by the route matching them
+		`http_requests_total{method="GET",route="/metrics-test/:user_id",status="204"} 2`,
+		`http_requests_total{method="other",route="/metrics-test/:user_id",status="204"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/metrics-test/:user_id"} 2`,
+	} {
+		if !strings.Contains(buf.String(), want+"\n") {
+			t.Errorf("exposition lacks %s", want)
+		}
+	}
+	if strings.Contains(buf.String(), "/metrics-test/1") {
+		t.Error("exposition labels a request path")
+	}
+}
@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/ratelimit"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+	"go.uber.org/zap"
+)
+
+const (
+	// APIKeyHeader carries the API key of machine clients
+	APIKeyHeader = "X-API-Key"
+
+	// IdentityIP limits each client address
+	IdentityIP = "ip"
+	// IdentityUser limits each signed in user, falling back to the address
+	IdentityUser = "user"
+	// IdentityAPIKey limits each API key, falling back to the address
+	IdentityAPIKey = "api_key"
+)
+
+// RateLimitRule applies a policy to a group of routes, counting requests per identity
+type RateLimitRule struct {
+	ratelimit.Policy
+	// Routes are "METHOD /path" patterns of gin route paths. A path ending in * matches
+	// every route under it and * as the method matches any method.
+	Routes   []string
+	Identity string
+	// Exempt lets the matching requests through without counting them
+	Exempt bool
+}
+
+func (r RateLimitRule) matches(method, path string) bool {
+	for _, route := range r.Routes {
+		parts := strings.SplitN(route, " ", 2)
+		if len(parts) != 2 || (parts[0] != "*" && parts[0] != method) {
+			continue
+		}
+		if pattern := parts[1]; pattern == path || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(path, pattern[:len(pattern)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RateLimitRule) identify(c *gin.Context) string {
+	switch r.Identity {
+	case IdentityUser:
+		// the caller is set by Authenticate, the client cannot pick it
+		if id := c.GetInt(CallerIDKey); id != 0 {
+			return "user:" + strconv.Itoa(id)
+		}
+	case IdentityAPIKey:
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			return "key:" + key
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimit applies the first rule matching the route of a request, answering 429 once its
+// limit is reached. Responses carry the RateLimit-* headers of the rule, rejections a
+// Retry-After. Requests are let through when the store fails, rate limiting being best effort.
+func RateLimit(store ratelimit.Store, rules ...RateLimitRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		method, path := c.Request.Method, c.FullPath()
+		for _, rule := range rules {
+			if (rule.Limit <= 0 && !rule.Exempt) || !rule.matches(method, path) {
+				continue
+			}
+			if rule.Exempt {
+				break
+			}
+			result, err := store.Take(c.Request.Context(), rule.identify(c), rule.Policy)
+			if err != nil {
+				logger.Info("failed to apply rate limit", zap.String("policy", rule.Name), zap.String("error", err.Error()))
+				break
+			}
+
+			c.Header("RateLimit-Policy", rule.Policy.String())
+			c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+			c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Header("RateLimit-Reset", seconds(result.Reset))
+			if !result.Allowed {
+				c.Header("Retry-After", seconds(result.RetryAfter))
+				err := errors.NewTooManyRequestsError("rate limit exceeded, retry later")
+				render.Respond(c, err.Status, err)
+				c.Abort()
+				return
+			}
+			break
+		}
+		c.Next()
+	}
+}
+
+// seconds rounds d up to whole seconds, as rate limit headers expect
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/ratelimit"
+	"github.com/sauravgsh16/bookstore_users-api/utils/auth"
+)
+
+func TestRateLimitIdentity(t *testing.T) {
+	engine := gin.New()
+	engine.Use(Authenticate(), RateLimit(ratelimit.NewMemoryStore(), RateLimitRule{
+		Policy:   ratelimit.Policy{Name: "test", Algorithm: ratelimit.TokenBucket, Limit: 1, Window: time.Hour},
+		Routes:   []string{"* /*"},
+		Identity: IdentityUser,
+	}))
+	engine.GET("/users/:user_id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// requests run in order against the same limits
+	tests := []struct {
+		name    string
+		addr    string
+		headers map[string]string
+		status  int
+	}{
+		{name: "anonymous", addr: "10.0.0.1:1000", status: http.StatusOK},
+		{name: "anonymous again", addr: "10.0.0.1:1001", status: http.StatusTooManyRequests},
+		{name: "anonymous naming an actor", addr: "10.0.0.1:1002", headers: map[string]string{"X-Actor": "someone"}, status: http.StatusTooManyRequests},
+		{name: "anonymous from another address", addr: "10.0.0.2:1000", status: http.StatusOK},
+		{name: "signed in", addr: "10.0.0.1:1003", headers: map[string]string{"Authorization": "Bearer " + auth.NewToken(1)}, status: http.StatusOK},
+		{name: "signed in from another address", addr: "10.0.0.3:1000", headers: map[string]string{"Authorization": "Bearer " + auth.NewToken(1)}, status: http.StatusTooManyRequests},
+		{name: "another user", addr: "10.0.0.1:1004", headers: map[string]string{"Authorization": "Bearer " + auth.NewToken(2)}, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.RemoteAddr = tt.addr
+		for name, value := range tt.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
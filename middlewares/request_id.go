@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+)
+
+const (
+	// RequestIDHeader carries the request id in requests and responses
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the gin context key holding the request id
+	RequestIDKey = "request_id"
+)
+
+// RequestID reuses the caller supplied request id or generates one, and echoes it in the response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = crypto.GetRandomToken(16)
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/openapi"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+// ValidateRequest rejects with 400 the requests whose parameters or JSON body do not match
+// the OpenAPI document. Routes the document does not describe are let through.
+func ValidateRequest() gin.HandlerFunc {
+	// requests are the same in every version, only representations differ
+	doc := openapi.Spec(users.LatestVersion)
+	return func(c *gin.Context) {
+		op := doc.Find(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, p := range c.Params {
+			params[p.Key] = p.Value
+		}
+		if err := doc.ValidateRequest(op, c.Request, params); err != nil {
+			restErr := errors.NewBadRequestError(err.Error())
+			render.Respond(c, restErr.Status, restErr)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
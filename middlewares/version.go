@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/render"
+)
+
+const (
+	// VersionKey is the gin context key holding the API version of the request
+	VersionKey = "api_version"
+	// VersionParameter is the Accept media type parameter selecting a version on unversioned
+	// routes, as in application/json; version=2
+	VersionParameter = "version"
+)
+
+var (
+	// V1DeprecatedAt is when version 1 was deprecated, announced in the Deprecation header
+	V1DeprecatedAt = config.GetString("API_V1_DEPRECATED_AT", "2026-10-01T00:00:00Z")
+	// V1Sunset is when version 1 stops being served, announced in the Sunset header
+	V1Sunset = config.GetString("API_V1_SUNSET", "2027-10-01T00:00:00Z")
+)
+
+// Version pins the routes of a group to version. A version of 0 resolves it from the version
+// parameter of Accept instead, defaulting to version 1. Version 1 responses announce its
+// deprecation and sunset, with a link to the same route in the latest version.
+func Version(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v := version
+		if v == 0 {
+			var err *errors.RestErr
+			if v, err = acceptedVersion(c.GetHeader("Accept")); err != nil {
+				render.Respond(c, err.Status, err)
+				c.Abort()
+				return
+			}
+		}
+		c.Set(VersionKey, v)
+		if v == users.Version1 {
+			deprecate(c, version != 0)
+		}
+		c.Next()
+	}
+}
+
+// acceptedVersion returns the version parameter of the Accept media types
+func acceptedVersion(accept string) (int, *errors.RestErr) {
+	for _, part := range strings.Split(accept, ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		value, ok := params[VersionParameter]
+		if !ok {
+			continue
+		}
+		v, convErr := strconv.Atoi(strings.TrimPrefix(value, "v"))
+		if convErr != nil || v < users.Version1 || v > users.LatestVersion {
+			return 0, errors.NewNotAcceptableError(fmt.Sprintf("unsupported api version %q", value))
+		}
+		return v, nil
+	}
+	return users.Version1, nil
+}
+
+// deprecate sets the Deprecation (RFC 9745), Sunset (RFC 8594) and successor Link headers,
+// prefixed tells whether the request path starts with the version
+func deprecate(c *gin.Context, prefixed bool) {
+	if t, err := time.Parse(time.RFC3339, V1DeprecatedAt); err == nil {
+		c.Header("Deprecation", "@"+strconv.FormatInt(t.Unix(), 10))
+	}
+	if t, err := time.Parse(time.RFC3339, V1Sunset); err == nil {
+		c.Header("Sunset", t.UTC().Format(http.TimeFormat))
+	}
+	path := c.Request.URL.Path
+	if prefixed {
+		path = strings.TrimPrefix(path, fmt.Sprintf("/v%d", users.Version1))
+	}
+	c.Header("Link", fmt.Sprintf(`</v%d%s>; rel="successor-version"`, users.LatestVersion, path))
+}
@@ -0,0 +1,33 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+)
+
+const (
+	// ViewHeader names the view the gateway granted the caller
+	ViewHeader = "X-View"
+	// ViewKey is the gin context key holding the view of the request
+	ViewKey = "view"
+)
+
+// DefaultView is used when the gateway does not name one
+var DefaultView = config.GetString("USERS_DEFAULT_VIEW", users.ViewSupport)
+
+// View resolves the view users are presented in from X-View, X-Public: true standing for the
+// public one. Unknown views are left for the handlers to reject.
+func View() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		view := c.GetHeader(ViewHeader)
+		if view == "" {
+			view = DefaultView
+			if c.GetHeader("X-Public") == "true" {
+				view = users.ViewPublic
+			}
+		}
+		c.Set(ViewKey, view)
+		c.Next()
+	}
+}
@@ -0,0 +1,141 @@
+// Package openapi generates the OpenAPI 3.1 document of the REST API and validates requests
+// against it. The document is built from a table of the routes, checked against those the
+// router serves when the application starts.
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+)
+
+// versionPrefix matches the version a served path starts with
+var versionPrefix = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+var (
+	specsMux sync.Mutex
+	specs    = make(map[int]*Document)
+)
+
+// Spec returns the document of an API version, built on first use
+func Spec(version int) *Document {
+	specsMux.Lock()
+	defer specsMux.Unlock()
+
+	if doc, ok := specs[version]; ok {
+		return doc
+	}
+	doc := build(version)
+	specs[version] = doc
+	return doc
+}
+
+func build(version int) *Document {
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info: Info{
+			Title: "Bookstore users API",
+			Description: "Users of the bookstore. Responses are negotiated with Accept as JSON, XML, MessagePack " +
+				"or protobuf, the fields of users depend on the view granted by X-View.",
+			Version: fmt.Sprintf("v%d", version),
+		},
+		Servers: []Server{{URL: fmt.Sprintf("/v%d", version)}},
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: components(version),
+			SecuritySchemes: map[string]*SecurityScheme{bearerAuth: {
+				Type:        "http",
+				Scheme:      "bearer",
+				Description: "Access token returned in the X-Access-Token header on login",
+			}},
+		},
+		operations: make(map[string]*Operation),
+	}
+
+	tags := make(map[string]bool)
+	for _, r := range routes() {
+		op := r.operation()
+		op.Deprecated = version == users.Version1
+		item := doc.Paths[r.path]
+		if item == nil {
+			item = &PathItem{}
+			if r.unversioned {
+				item.Servers = []Server{{URL: "/"}}
+			}
+			doc.Paths[r.path] = item
+		}
+		item.set(r.method, op)
+		doc.operations[key(r.method, r.path)] = op
+		if !tags[r.tag] {
+			tags[r.tag] = true
+			doc.Tags = append(doc.Tags, Tag{Name: r.tag})
+		}
+	}
+	return doc
+}
+
+// Find returns the operation of a gin route, nil if it is not documented
+func (d *Document) Find(method, fullPath string) *Operation {
+	return d.operations[key(method, documentedPath(fullPath))]
+}
+
+// documentedPath turns a gin route path into its unversioned OpenAPI form
+func documentedPath(fullPath string) string {
+	if loc := versionPrefix.FindStringIndex(fullPath); loc != nil {
+		fullPath = "/" + fullPath[loc[1]:]
+	}
+	parts := strings.Split(fullPath, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// CheckRoutes reports the routes the document leaves out and the operations no route serves.
+// Paths listed in undocumented are served without being part of the document.
+func CheckRoutes(served gin.RoutesInfo, undocumented ...string) error {
+	doc := Spec(users.LatestVersion)
+	skip := make(map[string]bool, len(undocumented))
+	for _, path := range undocumented {
+		skip[path] = true
+	}
+
+	var missing, stale []string
+	seen := make(map[string]bool)
+	for _, r := range served {
+		if skip[r.Path] {
+			continue
+		}
+		k := key(r.Method, documentedPath(r.Path))
+		seen[k] = true
+		if doc.operations[k] == nil {
+			missing = append(missing, key(r.Method, r.Path))
+		}
+	}
+	for k := range doc.operations {
+		if !seen[k] {
+			stale = append(stale, k)
+		}
+	}
+	if len(missing) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	sort.Strings(stale)
+	var msgs []string
+	if len(missing) > 0 {
+		msgs = append(msgs, "undocumented routes: "+strings.Join(missing, ", "))
+	}
+	if len(stale) > 0 {
+		msgs = append(msgs, "documented operations without a route: "+strings.Join(stale, ", "))
+	}
+	return fmt.Errorf("openapi document and routes drifted, %s", strings.Join(msgs, "; "))
+}
@@ -0,0 +1,278 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/pagination"
+)
+
+const (
+	mimeJSON        = "application/json"
+	mimeNDJSON      = "application/x-ndjson"
+	mimeCSV         = "text/csv"
+	mimeEventStream = "text/event-stream"
+	mimeMultipart   = "multipart/form-data"
+	mimeMergePatch  = users.PatchMerge
+	mimeJSONPatch   = users.PatchJSON
+)
+
+// bearerAuth names the access token security scheme
+const bearerAuth = "bearerAuth"
+
+// pathParam matches the parameters of an OpenAPI path
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// route is an operation of the table the document is built from
+type route struct {
+	method, path string
+	id, summary  string
+	tag          string
+	params       []*Parameter
+	body         *RequestBody
+	// responses maps status codes to their body schema, nil for none
+	responses map[int]*Schema
+	// errors are the error statuses the operation answers with, besides 429 and 500
+	errors []int
+	// media overrides the media type of the successful response
+	media string
+	// unversioned routes are served outside of the version prefixes
+	unversioned bool
+	// security lists the schemes the caller may authenticate with, none if unset
+	security []map[string][]string
+}
+
+func query(name, typ, description string) *Parameter {
+	return &Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: typ}}
+}
+
+func queryEnum(name, description string, values ...interface{}) *Parameter {
+	return &Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string", Enum: values}}
+}
+
+func header(name, description string, required bool) *Parameter {
+	return &Parameter{Name: name, In: "header", Description: description, Required: required, Schema: &Schema{Type: "string"}}
+}
+
+func jsonBody(schema *Schema, required bool) *RequestBody {
+	return &RequestBody{Required: required, Content: map[string]*MediaType{mimeJSON: {Schema: schema}}}
+}
+
+var (
+	fields         = query("fields", "string", "Comma separated fields to return, out of those of the view")
+	includeDeleted = query("include_deleted", "boolean", "Include soft deleted users")
+	ifMatch        = header("If-Match", "Entity tag of the version the change applies to", false)
+	page           = &Parameter{Name: "page", In: "query", Schema: &Schema{Type: "integer", Minimum: float(1)}}
+	perPage        = &Parameter{Name: "per_page", In: "query", Schema: &Schema{Type: "integer", Minimum: float(1), Maximum: float(pagination.MaxPerPage)}}
+	statusBody     = jsonBody(ref("StatusRequest"), false)
+	deleted        = map[int]*Schema{http.StatusOK: ref("Status")}
+
+	// signedIn requires an access token
+	signedIn = []map[string][]string{{bearerAuth: {}}}
+)
+
+func float(f float64) *float64 { return &f }
+
+// routes lists every route of the API, paths as served without a version prefix
+func routes() []route {
+	user := map[int]*Schema{http.StatusOK: ref("PrivateUser")}
+	lifecycle := func(action, summary string) route {
+		return route{
+			method: http.MethodPost, path: "/users/{user_id}/" + action, id: action + "User", summary: summary, tag: "lifecycle",
+			params: []*Parameter{fields}, body: statusBody, responses: user, errors: []int{400, 404, 409},
+		}
+	}
+
+	return []route{
+		{method: http.MethodGet, path: "/ping", id: "ping", summary: "Check the service is up", tag: "health", unversioned: true,
+			responses: map[int]*Schema{http.StatusOK: {Type: "object", Properties: map[string]*Schema{"message": {Type: "string"}}}}},
+		{method: http.MethodGet, path: "/openapi.json", id: "getOpenAPI", summary: "This document", tag: "health",
+			responses: map[int]*Schema{http.StatusOK: {Type: "object"}}},
+
+		{method: http.MethodGet, path: "/users/{user_id}", id: "getUser", summary: "Get a user", tag: "users",
+			params:    []*Parameter{fields, includeDeleted, header("If-None-Match", "Entity tag of a cached version", false)},
+			responses: map[int]*Schema{http.StatusOK: ref("PrivateUser"), http.StatusNotModified: nil}, errors: []int{400, 404}},
+		{method: http.MethodPost, path: "/users", id: "createUser", summary: "Sign up a user", tag: "users",
+			params:    []*Parameter{fields, header("Idempotency-Key", "Makes retries of the request safe", false)},
+			body:      jsonBody(&Schema{AllOf: []*Schema{ref("User")}, Required: []string{"email", "password"}}, true),
+			responses: map[int]*Schema{http.StatusCreated: ref("PrivateUser")}, errors: []int{400, 409, 422}},
+		{method: http.MethodPut, path: "/users/{user_id}", id: "replaceUser", summary: "Replace the fields of a user", tag: "users",
+			params: []*Parameter{fields, ifMatch}, body: jsonBody(ref("User"), true),
+			responses: user, errors: []int{400, 404, 409, 412, 428}},
+		{method: http.MethodPatch, path: "/users/{user_id}", id: "patchUser", summary: "Update some fields of a user", tag: "users",
+			params: []*Parameter{fields, ifMatch},
+			body: &RequestBody{Required: true, Content: map[string]*MediaType{
+				mimeJSON:       {Schema: ref("User")},
+				mimeMergePatch: {Schema: &Schema{Type: "object"}},
+				mimeJSONPatch: {Schema: arrayOf(&Schema{
+					Type:     "object",
+					Required: []string{"op", "path"},
+					Properties: map[string]*Schema{
+						"op":    {Type: "string", Enum: []interface{}{"add", "remove", "replace", "move", "copy", "test"}},
+						"path":  {Type: "string"},
+						"from":  {Type: "string"},
+						"value": {},
+					},
+				})},
+			}},
+			responses: user, errors: []int{400, 404, 409, 412, 415, 422, 428}},
+		{method: http.MethodDelete, path: "/users/{user_id}", id: "deleteUser", summary: "Soft delete a user", tag: "users",
+			params: []*Parameter{ifMatch}, responses: deleted, errors: []int{400, 404, 409, 412, 428}},
+		{method: http.MethodPost, path: "/users/login", id: "login", summary: "Log a user in with their password", tag: "users",
+			params: []*Parameter{fields}, body: jsonBody(ref("LoginRequest"), true),
+			responses: user, errors: []int{400, 401, 404}},
+
+		{method: http.MethodGet, path: "/internal/users/search", id: "searchUsers", summary: "Find users by status", tag: "internal",
+			params: []*Parameter{fields, includeDeleted, queryEnum("status", "Status of the users",
+				users.StatusPending, users.StatusActive, users.StatusSuspended, users.StatusDeactivated, users.StatusDeleted)},
+			responses: map[int]*Schema{http.StatusOK: arrayOf(ref("PrivateUser"))}, errors: []int{400, 404}},
+		{method: http.MethodPost, path: "/internal/users/bulk", id: "importUsers", summary: "Create users in bulk", tag: "internal",
+			params: []*Parameter{
+				queryEnum("mode", "Load the rows with COPY or one at a time", users.BulkModeCopy, users.BulkModeRows),
+				query("dry_run", "boolean", "Only validate the rows"),
+			},
+			body: &RequestBody{Required: true, Content: map[string]*MediaType{
+				mimeJSON:   {Schema: arrayOf(ref("User"))},
+				mimeNDJSON: {Schema: ref("User")},
+				mimeCSV:    {Schema: &Schema{Type: "string"}},
+			}},
+			responses: map[int]*Schema{http.StatusOK: ref("BulkReport")}, errors: []int{400, 415}},
+		{method: http.MethodGet, path: "/internal/users/export", id: "exportUsers", summary: "Stream every user", tag: "internal",
+			params:    []*Parameter{fields, includeDeleted, queryEnum("format", "Format of the export", "ndjson", "csv")},
+			responses: map[int]*Schema{http.StatusOK: ref("PrivateUser")}, errors: []int{400}, media: mimeNDJSON},
+		{method: http.MethodGet, path: "/internal/users/events", id: "streamUserEvents", summary: "Stream the events of users as Server-Sent Events", tag: "internal",
+			params: []*Parameter{
+				query("type", "string", "Comma separated event types to stream"),
+				query("status", "string", "Comma separated statuses, streaming the events leaving users in one of them"),
+				header("Last-Event-ID", "Resume after this event, if still retained", false),
+			},
+			responses: map[int]*Schema{http.StatusOK: {Type: "string"}}, errors: []int{400, 410}, media: mimeEventStream},
+
+		lifecycle(users.ActionActivate, "Activate a pending user"),
+		lifecycle(users.ActionSuspend, "Suspend an active user"),
+		lifecycle(users.ActionReactivate, "Reactivate a suspended user"),
+		lifecycle(users.ActionDeactivate, "Deactivate a user at their request"),
+		lifecycle(users.ActionRestore, "Restore a soft deleted user"),
+		{method: http.MethodGet, path: "/users/{user_id}/status-history", id: "getStatusHistory", summary: "List the status changes of a user", tag: "lifecycle",
+			responses: map[int]*Schema{http.StatusOK: arrayOf(ref("StatusChange"))}, errors: []int{400, 404}},
+
+		{method: http.MethodGet, path: "/users/{user_id}/profile", id: "getProfile", summary: "Get the profile of a user", tag: "profile",
+			responses: map[int]*Schema{http.StatusOK: ref("Profile")}, errors: []int{400, 404}},
+		{method: http.MethodPut, path: "/users/{user_id}/profile", id: "replaceProfile", summary: "Replace the profile of a user", tag: "profile",
+			body: jsonBody(ref("Profile"), true), responses: map[int]*Schema{http.StatusOK: ref("Profile")}, errors: []int{400, 404}},
+		{method: http.MethodPatch, path: "/users/{user_id}/profile", id: "patchProfile", summary: "Update the fields of the profile sent", tag: "profile",
+			body: jsonBody(ref("Profile"), true), responses: map[int]*Schema{http.StatusOK: ref("Profile")}, errors: []int{400, 404}},
+		{method: http.MethodGet, path: "/users/{user_id}/addresses", id: "getAddresses", summary: "List the addresses of a user", tag: "profile",
+			responses: map[int]*Schema{http.StatusOK: arrayOf(ref("Address"))}, errors: []int{400, 404}},
+		{method: http.MethodPost, path: "/users/{user_id}/addresses", id: "createAddress", summary: "Add an address", tag: "profile",
+			body: jsonBody(ref("Address"), true), responses: map[int]*Schema{http.StatusCreated: ref("Address")}, errors: []int{400, 404}},
+		{method: http.MethodGet, path: "/users/{user_id}/addresses/{address_id}", id: "getAddress", summary: "Get an address", tag: "profile",
+			responses: map[int]*Schema{http.StatusOK: ref("Address")}, errors: []int{400, 404}},
+		{method: http.MethodPut, path: "/users/{user_id}/addresses/{address_id}", id: "replaceAddress", summary: "Replace an address", tag: "profile",
+			body: jsonBody(ref("Address"), true), responses: map[int]*Schema{http.StatusOK: ref("Address")}, errors: []int{400, 404}},
+		{method: http.MethodDelete, path: "/users/{user_id}/addresses/{address_id}", id: "deleteAddress", summary: "Delete an address", tag: "profile",
+			responses: deleted, errors: []int{400, 404}},
+		{method: http.MethodGet, path: "/users/{user_id}/avatar", id: "getAvatar", summary: "Download the avatar of a user", tag: "profile",
+			params:    []*Parameter{queryEnum("size", "Download the thumbnail instead", "thumbnail")},
+			responses: map[int]*Schema{http.StatusOK: {Type: "string", Format: "binary"}}, errors: []int{400, 404}, media: "image/*"},
+		{method: http.MethodPut, path: "/users/{user_id}/avatar", id: "uploadAvatar", summary: "Upload an avatar", tag: "profile",
+			body: &RequestBody{Required: true, Content: map[string]*MediaType{
+				mimeMultipart: {Schema: &Schema{Type: "object", Properties: map[string]*Schema{"avatar": {Type: "string", Format: "binary"}}}},
+				"image/*":     {Schema: &Schema{Type: "string", Format: "binary"}},
+			}},
+			responses: map[int]*Schema{http.StatusOK: ref("Profile")}, errors: []int{400, 404, 413, 415}},
+		{method: http.MethodDelete, path: "/users/{user_id}/avatar", id: "deleteAvatar", summary: "Delete the avatar of a user", tag: "profile",
+			responses: deleted, errors: []int{400, 404}},
+
+		{method: http.MethodGet, path: "/users/{user_id}/data-export", id: "exportUserData", summary: "Export everything stored about a user", tag: "privacy",
+			params:    []*Parameter{queryEnum("format", "Format of the export", "json", "zip")},
+			responses: map[int]*Schema{http.StatusOK: ref("DataExport")}, errors: []int{400, 404}},
+		{method: http.MethodPost, path: "/users/{user_id}/erase", id: "eraseUser", summary: "Erase the personal data of a user", tag: "privacy",
+			params: []*Parameter{fields, ifMatch}, body: statusBody, responses: user, errors: []int{400, 404, 409, 412, 428}},
+
+		{method: http.MethodGet, path: "/users/{user_id}/audit", id: "getAuditLog", summary: "Page through the changes made to a user", tag: "audit",
+			params: []*Parameter{page, perPage}, responses: map[int]*Schema{http.StatusOK: ref("AuditPage")}, errors: []int{400, 404}},
+
+		{method: http.MethodGet, path: "/oidc/{provider}/login", id: "providerLogin", summary: "Redirect to an identity provider", tag: "identities",
+			responses: map[int]*Schema{http.StatusFound: nil}, errors: []int{404}},
+		{method: http.MethodGet, path: "/oidc/{provider}/callback", id: "providerCallback", summary: "Complete a login with an identity provider", tag: "identities",
+			params: []*Parameter{fields, query("code", "string", "Authorization code"), query("state", "string", "State of the login"),
+				query("error", "string", "Error reported by the provider")},
+			responses: user, errors: []int{400, 401, 403, 404, 409}},
+		{method: http.MethodGet, path: "/users/{user_id}/identities", id: "getIdentities", summary: "List the identities linked to a user", tag: "identities",
+			responses: map[int]*Schema{http.StatusOK: arrayOf(ref("Identity"))}, errors: []int{400, 401, 403, 404}, security: signedIn},
+		{method: http.MethodPost, path: "/users/{user_id}/identities/{provider}", id: "linkIdentity", summary: "Start linking an identity", tag: "identities",
+			responses: map[int]*Schema{http.StatusOK: {Type: "object", Properties: map[string]*Schema{"authorization_url": {Type: "string", Format: "uri"}}}},
+			errors:    []int{400, 401, 403, 404}, security: signedIn},
+		{method: http.MethodDelete, path: "/users/{user_id}/identities/{provider}", id: "unlinkIdentity", summary: "Unlink an identity", tag: "identities",
+			responses: deleted, errors: []int{400, 401, 403, 404, 409}, security: signedIn},
+
+		{method: http.MethodPost, path: "/webhooks", id: "createWebhook", summary: "Subscribe to user events", tag: "webhooks",
+			body: jsonBody(ref("Subscription"), true), responses: map[int]*Schema{http.StatusCreated: ref("Subscription")}, errors: []int{400}},
+		{method: http.MethodGet, path: "/webhooks", id: "listWebhooks", summary: "List the subscriptions", tag: "webhooks",
+			responses: map[int]*Schema{http.StatusOK: arrayOf(ref("Subscription"))}},
+		{method: http.MethodGet, path: "/webhooks/{webhook_id}", id: "getWebhook", summary: "Get a subscription", tag: "webhooks",
+			responses: map[int]*Schema{http.StatusOK: ref("Subscription")}, errors: []int{400, 404}},
+		{method: http.MethodDelete, path: "/webhooks/{webhook_id}", id: "deleteWebhook", summary: "Unsubscribe", tag: "webhooks",
+			responses: deleted, errors: []int{400, 404}},
+		{method: http.MethodGet, path: "/webhooks/{webhook_id}/deliveries", id: "listDeliveries", summary: "Page through the deliveries of a subscription", tag: "webhooks",
+			params: []*Parameter{page, perPage}, responses: map[int]*Schema{http.StatusOK: arrayOf(ref("Delivery"))}, errors: []int{400, 404}},
+		{method: http.MethodPost, path: "/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver", id: "redeliver", summary: "Deliver an event again", tag: "webhooks",
+			responses: map[int]*Schema{http.StatusAccepted: ref("Status")}, errors: []int{400, 404}},
+
+		{method: http.MethodGet, path: "/graphql", id: "queryGraphQL", summary: "Run a GraphQL query", tag: "graphql", unversioned: true,
+			params:    []*Parameter{query("query", "string", "The GraphQL query")},
+			responses: map[int]*Schema{http.StatusOK: {Type: "object"}}},
+		{method: http.MethodPost, path: "/graphql", id: "postGraphQL", summary: "Run a GraphQL query or mutation", tag: "graphql", unversioned: true,
+			body:      jsonBody(&Schema{Type: "object", Properties: map[string]*Schema{"query": {Type: "string"}, "variables": {Type: "object"}, "operationName": {Type: "string"}}}, true),
+			responses: map[int]*Schema{http.StatusOK: {Type: "object"}}},
+	}
+}
+
+// operation builds the operation of r, its path parameters declared from the path
+func (r route) operation() *Operation {
+	op := &Operation{
+		OperationID: r.id,
+		Summary:     r.summary,
+		Tags:        []string{r.tag},
+		RequestBody: r.body,
+		Responses:   make(map[string]*Response),
+		Security:    r.security,
+	}
+	for _, m := range pathParam.FindAllStringSubmatch(r.path, -1) {
+		schema := &Schema{Type: "string"}
+		if strings.HasSuffix(m[1], "_id") {
+			schema = &Schema{Type: "integer"}
+		}
+		op.Parameters = append(op.Parameters, &Parameter{Name: m[1], In: "path", Required: true, Schema: schema})
+	}
+	op.Parameters = append(op.Parameters, r.params...)
+
+	media := r.media
+	if media == "" {
+		media = mimeJSON
+	}
+	for status, schema := range r.responses {
+		resp := &Response{Description: http.StatusText(status)}
+		if schema != nil {
+			resp.Content = map[string]*MediaType{media: {Schema: schema}}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+	}
+	for _, status := range append(r.errors, http.StatusTooManyRequests, http.StatusInternalServerError) {
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]*MediaType{mimeJSON: {Schema: ref("RestErr")}},
+		}
+	}
+	return op
+}
+
+// key identifies an operation by method and path
+func key(method, path string) string {
+	return fmt.Sprintf("%s %s", method, path)
+}
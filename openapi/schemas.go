@@ -0,0 +1,185 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/domain/webhooks"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// ref returns a reference to a component schema
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func arrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+func intPtr(i int) *int { return &i }
+
+// components builds the named schemas of version. Users are described from the projections of
+// their views, the other bodies from the JSON encoding of their Go types.
+func components(version int) map[string]*Schema {
+	g := &generator{schemas: make(map[string]*Schema)}
+
+	g.schemaOf(reflect.TypeOf(errors.RestErr{}))
+	g.schemas["RestErr"].Required = []string{"message", "status", "error"}
+
+	g.schemaOf(reflect.TypeOf(users.LoginRequest{}))
+	g.schemas["LoginRequest"].Required = []string{"email", "password"}
+	g.schemas["LoginRequest"].Properties["email"].Format = "email"
+
+	g.schemas["User"] = &Schema{
+		Type:        "object",
+		Description: "The writable fields of a user",
+		Properties: map[string]*Schema{
+			"first_name": {Type: "string"},
+			"last_name":  {Type: "string"},
+			"email":      {Type: "string", Format: "email"},
+			"password":   {Type: "string", MinLength: intPtr(1)},
+		},
+	}
+	g.schemas["PublicUser"] = projectionSchema(users.ViewPublic, version,
+		"A user as anyone may see them")
+	g.schemas["PrivateUser"] = projectionSchema(users.ViewAdmin, version,
+		"A user in the view granted by X-View. Narrower views and the fields query parameter leave fields out.")
+	if version >= users.Version2 {
+		g.schemas["UserProfile"] = &Schema{
+			Type:        "object",
+			Description: "The profile nested in a user",
+			Properties: map[string]*Schema{
+				"phone":                {Type: "string"},
+				"date_of_birth":        {Type: "string", Format: "date"},
+				"locale":               {Type: "string"},
+				"timezone":             {Type: "string"},
+				"avatar_url":           {Type: "string", Format: "uri-reference"},
+				"avatar_thumbnail_url": {Type: "string", Format: "uri-reference"},
+				"date_updated":         {Type: "string", Format: "date-time"},
+			},
+		}
+	}
+
+	for _, v := range []interface{}{
+		users.Profile{}, users.Address{}, users.StatusChange{}, users.AuditPage{}, users.Identity{},
+		users.BulkReport{}, users.DataExport{}, webhooks.Subscription{}, webhooks.Delivery{},
+	} {
+		g.schemaOf(reflect.TypeOf(v))
+	}
+	g.schemas["StatusRequest"] = &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"reason": {Type: "string"}},
+	}
+	g.schemas["Status"] = &Schema{
+		Type:       "object",
+		Required:   []string{"status"},
+		Properties: map[string]*Schema{"status": {Type: "string"}},
+	}
+	return g.schemas
+}
+
+// projectionSchema describes the documents of a view in version
+func projectionSchema(view string, version int, description string) *Schema {
+	p, err := users.NewVersionedProjection(view, version, nil)
+	if err != nil {
+		panic(err.Message)
+	}
+	s := &Schema{Type: "object", Description: description, Properties: make(map[string]*Schema)}
+	var zero users.User
+	for _, f := range p.Fields() {
+		var prop *Schema
+		switch {
+		case f.Name == "profile":
+			prop = ref("UserProfile")
+		case f.Name == "status":
+			prop = &Schema{Type: "string", Enum: []interface{}{
+				users.StatusPending, users.StatusActive, users.StatusSuspended, users.StatusDeactivated, users.StatusDeleted,
+			}}
+		case f.Date && version >= users.Version2:
+			prop = &Schema{Type: "string", Format: "date-time"}
+		default:
+			prop = scalarSchema(reflect.TypeOf(f.Value(&zero)))
+		}
+		s.Properties[f.Name] = prop
+		if !f.OmitEmpty && view == users.ViewPublic {
+			s.Required = append(s.Required, f.Name)
+		}
+	}
+	return s
+}
+
+func scalarSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	}
+	return &Schema{}
+}
+
+// generator describes Go types as they encode to JSON, named structs becoming components
+// referenced by their type name
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Implements(marshalerType) {
+		// encoded its own way, such as projected user documents
+		return &Schema{Type: "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return arrayOf(g.schemaOf(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+	default:
+		return scalarSchema(t)
+	}
+
+	name := t.Name()
+	if _, ok := g.schemas[name]; ok {
+		return ref(name)
+	}
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if name != "" {
+		g.schemas[name] = s
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		field := strings.Split(tag, ",")[0]
+		if field == "" {
+			field = f.Name
+		}
+		s.Properties[field] = g.schemaOf(f.Type)
+	}
+	if name == "" {
+		return s
+	}
+	return ref(name)
+}
@@ -0,0 +1,237 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateRequest checks the parameters and JSON body of a request against op, params holding
+// the path parameters. Bodies in other formats are left for the handlers to decode. A JSON
+// body is read and put back for the handlers.
+func (d *Document) ValidateRequest(op *Operation, r *http.Request, params map[string]string) error {
+	for _, p := range op.Parameters {
+		var (
+			value   string
+			present bool
+		)
+		switch p.In {
+		case "path":
+			value, present = params[p.Name]
+		case "query":
+			var values []string
+			values, present = r.URL.Query()[p.Name]
+			if present {
+				value = values[0]
+			}
+		case "header":
+			value = r.Header.Get(p.Name)
+			present = value != ""
+		}
+		if !present {
+			if p.Required {
+				return fmt.Errorf("%s parameter %s is required", p.In, p.Name)
+			}
+			continue
+		}
+		if err := d.validateParameter(p, value); err != nil {
+			return err
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	mediaType := mimeJSON
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, _ = mime.ParseMediaType(ct)
+	}
+	content, ok := op.RequestBody.Content[mediaType]
+	if !ok || content.Schema == nil || !isJSON(mediaType) {
+		return nil
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return fmt.Errorf("failed to read request body: %s", err.Error())
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return fmt.Errorf("request body is required")
+		}
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("invalid json body: %s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid json body: unexpected data after the document")
+	}
+	return d.validate(content.Schema, value, "body")
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == mimeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// validateParameter checks a parameter, converting it to the type of its schema first
+func (d *Document) validateParameter(p *Parameter, raw string) error {
+	name := fmt.Sprintf("%s parameter %s", p.In, p.Name)
+	var value interface{} = raw
+	switch p.Schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return fmt.Errorf("%s must be a number", name)
+		}
+		value = json.Number(raw)
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", name)
+		}
+		value = b
+	}
+	return d.validate(p.Schema, value, name)
+}
+
+// validate checks a decoded JSON value against s, numbers being json.Number
+func (d *Document) validate(s *Schema, value interface{}, path string) error {
+	if s.Ref != "" {
+		target, ok := d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", path, s.Ref)
+		}
+		return d.validate(target, value, path)
+	}
+	for _, sub := range s.AllOf {
+		if err := d.validate(sub, value, path); err != nil {
+			return err
+		}
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		return fmt.Errorf("%s must be one of %s", path, formatEnum(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case nil:
+		if s.Type != "" && s.Type != "null" {
+			return fmt.Errorf("%s must be %s", path, article(s.Type))
+		}
+	case bool:
+		if s.Type != "" && s.Type != "boolean" {
+			return fmt.Errorf("%s must be %s", path, article(s.Type))
+		}
+	case json.Number:
+		return validateNumber(s, v, path)
+	case string:
+		if s.Type != "" && s.Type != "string" {
+			return fmt.Errorf("%s must be %s", path, article(s.Type))
+		}
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fmt.Errorf("%s must be at least %d characters", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fmt.Errorf("%s must be at most %d characters", path, *s.MaxLength)
+		}
+	case []interface{}:
+		if s.Type != "" && s.Type != "array" {
+			return fmt.Errorf("%s must be %s", path, article(s.Type))
+		}
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Errorf("%s must have at least %d items", path, *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fmt.Errorf("%s must have at most %d items", path, *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		if s.Type != "" && s.Type != "object" {
+			return fmt.Errorf("%s must be %s", path, article(s.Type))
+		}
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		for name, item := range v {
+			prop, ok := s.Properties[name]
+			if !ok {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				continue
+			}
+			if err := d.validate(prop, item, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateNumber(s *Schema, n json.Number, path string) error {
+	switch s.Type {
+	case "", "number":
+	case "integer":
+		if _, err := n.Int64(); err != nil {
+			return fmt.Errorf("%s must be an integer", path)
+		}
+	default:
+		return fmt.Errorf("%s must be %s", path, article(s.Type))
+	}
+	f, _ := n.Float64()
+	if s.Minimum != nil && f < *s.Minimum {
+		return fmt.Errorf("%s must be at least %v", path, *s.Minimum)
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		return fmt.Errorf("%s must be at most %v", path, *s.Maximum)
+	}
+	return nil
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, e := range enum {
+		values[i] = fmt.Sprint(e)
+	}
+	return strings.Join(values, ", ")
+}
+
+func article(typ string) string {
+	switch typ {
+	case "integer", "object", "array":
+		return "an " + typ
+	}
+	return "a " + typ
+}
@@ -0,0 +1,5 @@
+// Package usersv1 holds the protocol buffer messages and gRPC service of the users API,
+// generated from users.proto and users_service.proto
+package usersv1
+
+//go:generate protoc -I ../.. --go_out=paths=source_relative,plugins=grpc:../.. users/v1/users.proto users/v1/users_service.proto
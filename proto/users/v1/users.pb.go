@@ -0,0 +1,264 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: users/v1/users.proto
+
+package usersv1
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// User is a user projected on the fields of a view, fields outside it are left empty.
+// The password is only ever read from requests.
+type User struct {
+	Id                   int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	FirstName            string   `protobuf:"bytes,2,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName             string   `protobuf:"bytes,3,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	Email                string   `protobuf:"bytes,4,opt,name=email,proto3" json:"email,omitempty"`
+	DateCreated          string   `protobuf:"bytes,5,opt,name=date_created,json=dateCreated,proto3" json:"date_created,omitempty"`
+	Status               string   `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	DeletedAt            string   `protobuf:"bytes,7,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`
+	ErasedAt             string   `protobuf:"bytes,8,opt,name=erased_at,json=erasedAt,proto3" json:"erased_at,omitempty"`
+	Version              int64    `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	Password             string   `protobuf:"bytes,10,opt,name=password,proto3" json:"password,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *User) Reset()         { *m = User{} }
+func (m *User) String() string { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()    {}
+func (*User) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6f4f876b666dee06, []int{0}
+}
+
+func (m *User) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_User.Unmarshal(m, b)
+}
+func (m *User) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_User.Marshal(b, m, deterministic)
+}
+func (m *User) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_User.Merge(m, src)
+}
+func (m *User) XXX_Size() int {
+	return xxx_messageInfo_User.Size(m)
+}
+func (m *User) XXX_DiscardUnknown() {
+	xxx_messageInfo_User.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_User proto.InternalMessageInfo
+
+func (m *User) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *User) GetFirstName() string {
+	if m != nil {
+		return m.FirstName
+	}
+	return ""
+}
+
+func (m *User) GetLastName() string {
+	if m != nil {
+		return m.LastName
+	}
+	return ""
+}
+
+func (m *User) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *User) GetDateCreated() string {
+	if m != nil {
+		return m.DateCreated
+	}
+	return ""
+}
+
+func (m *User) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *User) GetDeletedAt() string {
+	if m != nil {
+		return m.DeletedAt
+	}
+	return ""
+}
+
+func (m *User) GetErasedAt() string {
+	if m != nil {
+		return m.ErasedAt
+	}
+	return ""
+}
+
+func (m *User) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *User) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+// Users is a list of users
+type Users struct {
+	Users                []*User  `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Users) Reset()         { *m = Users{} }
+func (m *Users) String() string { return proto.CompactTextString(m) }
+func (*Users) ProtoMessage()    {}
+func (*Users) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6f4f876b666dee06, []int{1}
+}
+
+func (m *Users) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Users.Unmarshal(m, b)
+}
+func (m *Users) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Users.Marshal(b, m, deterministic)
+}
+func (m *Users) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Users.Merge(m, src)
+}
+func (m *Users) XXX_Size() int {
+	return xxx_messageInfo_Users.Size(m)
+}
+func (m *Users) XXX_DiscardUnknown() {
+	xxx_messageInfo_Users.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Users proto.InternalMessageInfo
+
+func (m *Users) GetUsers() []*User {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+// RestErr is the error every failed request answers with
+type RestErr struct {
+	Message              string   `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Status               int32    `protobuf:"varint,2,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string   `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RestErr) Reset()         { *m = RestErr{} }
+func (m *RestErr) String() string { return proto.CompactTextString(m) }
+func (*RestErr) ProtoMessage()    {}
+func (*RestErr) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6f4f876b666dee06, []int{2}
+}
+
+func (m *RestErr) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RestErr.Unmarshal(m, b)
+}
+func (m *RestErr) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RestErr.Marshal(b, m, deterministic)
+}
+func (m *RestErr) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestErr.Merge(m, src)
+}
+func (m *RestErr) XXX_Size() int {
+	return xxx_messageInfo_RestErr.Size(m)
+}
+func (m *RestErr) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestErr.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestErr proto.InternalMessageInfo
+
+func (m *RestErr) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *RestErr) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *RestErr) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*User)(nil), "users.v1.User")
+	proto.RegisterType((*Users)(nil), "users.v1.Users")
+	proto.RegisterType((*RestErr)(nil), "users.v1.RestErr")
+}
+
+func init() { proto.RegisterFile("users/v1/users.proto", fileDescriptor_6f4f876b666dee06) }
+
+var fileDescriptor_6f4f876b666dee06 = []byte{
+	// 338 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x91, 0x41, 0x6b, 0xdb, 0x40,
+	0x10, 0x85, 0x91, 0x6c, 0xd9, 0xd2, 0xba, 0xf8, 0xb0, 0x98, 0xb2, 0xb4, 0x14, 0x5c, 0xd3, 0x83,
+	0x2f, 0x96, 0x50, 0x0b, 0xbd, 0xf4, 0xe4, 0x9a, 0x5c, 0x03, 0x11, 0xe4, 0x92, 0x8b, 0x18, 0x5b,
+	0x13, 0x5b, 0x44, 0xf2, 0x9a, 0x99, 0x95, 0xf2, 0x4b, 0xf2, 0x7f, 0xc3, 0xee, 0x4a, 0x21, 0xb7,
+	0x7d, 0xef, 0x63, 0x99, 0x37, 0x6f, 0xc4, 0xaa, 0x63, 0x24, 0xce, 0xfa, 0x3c, 0x73, 0x8f, 0xf4,
+	0x46, 0xda, 0x68, 0x19, 0x7b, 0xd1, 0xe7, 0x9b, 0xb7, 0x50, 0x4c, 0x1f, 0x19, 0x49, 0x2e, 0x45,
+	0x58, 0x57, 0x2a, 0x58, 0x07, 0xdb, 0x49, 0x11, 0xd6, 0x95, 0xfc, 0x21, 0xc4, 0x73, 0x4d, 0x6c,
+	0xca, 0x2b, 0xb4, 0xa8, 0xc2, 0x75, 0xb0, 0x4d, 0x8a, 0xc4, 0x39, 0xf7, 0xd0, 0xa2, 0xfc, 0x2e,
+	0x92, 0x06, 0x46, 0x3a, 0x71, 0x34, 0x6e, 0x60, 0x80, 0x2b, 0x11, 0x61, 0x0b, 0x75, 0xa3, 0xa6,
+	0x0e, 0x78, 0x21, 0x7f, 0x8a, 0x2f, 0x15, 0x18, 0x2c, 0x4f, 0x84, 0x60, 0xb0, 0x52, 0x91, 0x83,
+	0x0b, 0xeb, 0x1d, 0xbc, 0x25, 0xbf, 0x8a, 0x19, 0x1b, 0x30, 0x1d, 0xab, 0x99, 0x83, 0x83, 0xb2,
+	0x61, 0x2a, 0x6c, 0xd0, 0x60, 0x55, 0x82, 0x51, 0x73, 0x1f, 0x66, 0x70, 0xf6, 0xc6, 0x86, 0x41,
+	0x02, 0xf6, 0x34, 0xf6, 0x61, 0xbc, 0xb1, 0x37, 0x52, 0x89, 0x79, 0x8f, 0xc4, 0xb5, 0xbe, 0xaa,
+	0xc4, 0x6d, 0x37, 0x4a, 0xf9, 0x4d, 0xc4, 0x37, 0x60, 0x7e, 0xd5, 0x54, 0x29, 0xe1, 0x7f, 0x8d,
+	0x7a, 0xb3, 0x13, 0x91, 0xad, 0x85, 0xe5, 0x2f, 0x11, 0xb9, 0xb2, 0x54, 0xb0, 0x9e, 0x6c, 0x17,
+	0xbf, 0x97, 0xe9, 0x58, 0x5d, 0x6a, 0x79, 0xe1, 0xe1, 0xe6, 0x41, 0xcc, 0x0b, 0x64, 0x73, 0x47,
+	0x64, 0xe7, 0xb5, 0xc8, 0x0c, 0x67, 0x74, 0x6d, 0x26, 0xc5, 0x28, 0x3f, 0x6d, 0x67, 0xeb, 0x8c,
+	0x3e, 0xb6, 0xb3, 0x75, 0x11, 0x69, 0x1a, 0x7a, 0xf4, 0xe2, 0xff, 0xe1, 0x69, 0x7f, 0xae, 0xcd,
+	0xa5, 0x3b, 0xa6, 0x27, 0xdd, 0x66, 0x0c, 0x1d, 0x41, 0x7f, 0xe6, 0x4b, 0xfe, 0x37, 0x3b, 0x6a,
+	0xfd, 0xc2, 0x46, 0x13, 0x96, 0x6e, 0xf8, 0x0e, 0x6e, 0x75, 0xe6, 0xee, 0x9a, 0x8d, 0xc7, 0xfe,
+	0xe7, 0x1e, 0x7d, 0x7e, 0x9c, 0x39, 0xff, 0xcf, 0xfb, 0x00, 0xbe, 0x8d, 0x83, 0xfa, 0x07, 0x02,
+	0x00, 0x00,
+}
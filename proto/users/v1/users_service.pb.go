@@ -0,0 +1,680 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: users/v1/users_service.proto
+
+package usersv1
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	field_mask "google.golang.org/genproto/protobuf/field_mask"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type GetUserRequest struct {
+	Id                   int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	IncludeDeleted       bool     `protobuf:"varint,2,opt,name=include_deleted,json=includeDeleted,proto3" json:"include_deleted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetUserRequest) Reset()         { *m = GetUserRequest{} }
+func (m *GetUserRequest) String() string { return proto.CompactTextString(m) }
+func (*GetUserRequest) ProtoMessage()    {}
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0476f69c73c5728c, []int{0}
+}
+
+func (m *GetUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetUserRequest.Unmarshal(m, b)
+}
+func (m *GetUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetUserRequest.Marshal(b, m, deterministic)
+}
+func (m *GetUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetUserRequest.Merge(m, src)
+}
+func (m *GetUserRequest) XXX_Size() int {
+	return xxx_messageInfo_GetUserRequest.Size(m)
+}
+func (m *GetUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetUserRequest proto.InternalMessageInfo
+
+func (m *GetUserRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *GetUserRequest) GetIncludeDeleted() bool {
+	if m != nil {
+		return m.IncludeDeleted
+	}
+	return false
+}
+
+type CreateUserRequest struct {
+	User                 *User    `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateUserRequest) Reset()         { *m = CreateUserRequest{} }
+func (m *CreateUserRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateUserRequest) ProtoMessage()    {}
+func (*CreateUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0476f69c73c5728c, []int{1}
+}
+
+func (m *CreateUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateUserRequest.Unmarshal(m, b)
+}
+func (m *CreateUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateUserRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateUserRequest.Merge(m, src)
+}
+func (m *CreateUserRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateUserRequest.Size(m)
+}
+func (m *CreateUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateUserRequest proto.InternalMessageInfo
+
+func (m *CreateUserRequest) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+type UpdateUserRequest struct {
+	// user holds the id of the user to update and its new field values
+	User       *User                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	UpdateMask *field_mask.FieldMask `protobuf:"bytes,2,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	// version the update is conditional on, as If-Match is, 0 for any version unless USERS_REQUIRE_IF_MATCH is set
+	Version              int64    `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateUserRequest) Reset()         { *m = UpdateUserRequest{} }
+func (m *UpdateUserRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateUserRequest) ProtoMessage()    {}
+func (*UpdateUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0476f69c73c5728c, []int{2}
+}
+
+func (m *UpdateUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateUserRequest.Unmarshal(m, b)
+}
+func (m *UpdateUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateUserRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateUserRequest.Merge(m, src)
+}
+func (m *UpdateUserRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateUserRequest.Size(m)
+}
+func (m *UpdateUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateUserRequest proto.InternalMessageInfo
+
+func (m *UpdateUserRequest) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func (m *UpdateUserRequest) GetUpdateMask() *field_mask.FieldMask {
+	if m != nil {
+		return m.UpdateMask
+	}
+	return nil
+}
+
+func (m *UpdateUserRequest) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+type DeleteUserRequest struct {
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// version the deletion is conditional on, as If-Match is, 0 for any version unless USERS_REQUIRE_IF_MATCH is set
+	Version              int64    `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteUserRequest) Reset()         { *m = DeleteUserRequest{} }
+func (m *DeleteUserRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteUserRequest) ProtoMessage()    {}
+func (*DeleteUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0476f69c73c5728c, []int{3}
+}
+
+func (m *DeleteUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteUserRequest.Unmarshal(m, b)
+}
+func (m *DeleteUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteUserRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteUserRequest.Merge(m, src)
+}
+func (m *DeleteUserRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteUserRequest.Size(m)
+}
+func (m *DeleteUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteUserRequest proto.InternalMessageInfo
+
+func (m *DeleteUserRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *DeleteUserRequest) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+type DeleteUserResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteUserResponse) Reset()         { *m = DeleteUserResponse{} }
+func (m *DeleteUserResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteUserResponse) ProtoMessage()    {}
+func (*DeleteUserResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0476f69c73c5728c, []int{4}
+}
+
+func (m *DeleteUserResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteUserResponse.Unmarshal(m, b)
+}
+func (m *DeleteUserResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteUserResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteUserResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteUserResponse.Merge(m, src)
+}
+func (m *DeleteUserResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteUserResponse.Size(m)
+}
+func (m *DeleteUserResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteUserResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteUserResponse proto.InternalMessageInfo
+
+type SearchUsersRequest struct {
+	Status               string   `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	IncludeDeleted       bool     `protobuf:"varint,2,opt,name=include_deleted,json=includeDeleted,proto3" json:"include_deleted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SearchUsersRequest) Reset()         { *m = SearchUsersRequest{} }
+func (m *SearchUsersRequest) String() string { return proto.CompactTextString(m) }
+func (*SearchUsersRequest) ProtoMessage()    {}
+func (*SearchUsersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0476f69c73c5728c, []int{5}
+}
+
+func (m *SearchUsersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SearchUsersRequest.Unmarshal(m, b)
+}
+func (m *SearchUsersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SearchUsersRequest.Marshal(b, m, deterministic)
+}
+func (m *SearchUsersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SearchUsersRequest.Merge(m, src)
+}
+func (m *SearchUsersRequest) XXX_Size() int {
+	return xxx_messageInfo_SearchUsersRequest.Size(m)
+}
+func (m *SearchUsersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SearchUsersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SearchUsersRequest proto.InternalMessageInfo
+
+func (m *SearchUsersRequest) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *SearchUsersRequest) GetIncludeDeleted() bool {
+	if m != nil {
+		return m.IncludeDeleted
+	}
+	return false
+}
+
+type LoginRequest struct {
+	Email                string   `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
+	Password             string   `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LoginRequest) Reset()         { *m = LoginRequest{} }
+func (m *LoginRequest) String() string { return proto.CompactTextString(m) }
+func (*LoginRequest) ProtoMessage()    {}
+func (*LoginRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0476f69c73c5728c, []int{6}
+}
+
+func (m *LoginRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginRequest.Unmarshal(m, b)
+}
+func (m *LoginRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginRequest.Marshal(b, m, deterministic)
+}
+func (m *LoginRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginRequest.Merge(m, src)
+}
+func (m *LoginRequest) XXX_Size() int {
+	return xxx_messageInfo_LoginRequest.Size(m)
+}
+func (m *LoginRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LoginRequest proto.InternalMessageInfo
+
+func (m *LoginRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *LoginRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*GetUserRequest)(nil), "users.v1.GetUserRequest")
+	proto.RegisterType((*CreateUserRequest)(nil), "users.v1.CreateUserRequest")
+	proto.RegisterType((*UpdateUserRequest)(nil), "users.v1.UpdateUserRequest")
+	proto.RegisterType((*DeleteUserRequest)(nil), "users.v1.DeleteUserRequest")
+	proto.RegisterType((*DeleteUserResponse)(nil), "users.v1.DeleteUserResponse")
+	proto.RegisterType((*SearchUsersRequest)(nil), "users.v1.SearchUsersRequest")
+	proto.RegisterType((*LoginRequest)(nil), "users.v1.LoginRequest")
+}
+
+func init() { proto.RegisterFile("users/v1/users_service.proto", fileDescriptor_0476f69c73c5728c) }
+
+var fileDescriptor_0476f69c73c5728c = []byte{
+	// 466 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x53, 0xc1, 0x6e, 0xd3, 0x40,
+	0x10, 0x95, 0x5d, 0xda, 0xa6, 0x93, 0x2a, 0x28, 0xab, 0xa8, 0xb2, 0x4c, 0x0f, 0x91, 0x2f, 0xf4,
+	0x82, 0x4d, 0x52, 0x09, 0x84, 0xaa, 0x4a, 0x40, 0x11, 0x15, 0x12, 0x5c, 0x5c, 0xe5, 0xc2, 0x25,
+	0xda, 0xc4, 0x53, 0x67, 0x15, 0x27, 0x6b, 0x76, 0xd6, 0xe6, 0x2f, 0xf8, 0x58, 0xbe, 0x00, 0x65,
+	0x37, 0xc6, 0x4e, 0x1c, 0x21, 0x7a, 0xdb, 0x7d, 0x33, 0xef, 0x79, 0xf6, 0xbd, 0x31, 0x5c, 0x16,
+	0x84, 0x8a, 0xa2, 0x72, 0x14, 0x99, 0xc3, 0x94, 0x50, 0x95, 0x62, 0x8e, 0x61, 0xae, 0xa4, 0x96,
+	0xac, 0x63, 0xc0, 0xb0, 0x1c, 0xf9, 0xc3, 0x54, 0xca, 0x34, 0xc3, 0xc8, 0xe0, 0xb3, 0xe2, 0x31,
+	0x7a, 0x14, 0x98, 0x25, 0xd3, 0x15, 0xa7, 0xa5, 0xed, 0xf5, 0x07, 0xbb, 0x4a, 0x16, 0x0d, 0xbe,
+	0x40, 0xef, 0x1e, 0xf5, 0x84, 0x50, 0xc5, 0xf8, 0xa3, 0x40, 0xd2, 0xac, 0x07, 0xae, 0x48, 0x3c,
+	0x67, 0xe8, 0x5c, 0x1d, 0xc5, 0xae, 0x48, 0xd8, 0x4b, 0x78, 0x2e, 0xd6, 0xf3, 0xac, 0x48, 0x70,
+	0x9a, 0x60, 0x86, 0x1a, 0x13, 0xcf, 0x1d, 0x3a, 0x57, 0x9d, 0xb8, 0xb7, 0x85, 0x3f, 0x59, 0x34,
+	0x78, 0x0b, 0xfd, 0x3b, 0x85, 0x5c, 0x63, 0x53, 0x2d, 0x80, 0x67, 0x9b, 0xcf, 0x19, 0xbd, 0xee,
+	0xb8, 0x17, 0x56, 0x03, 0x87, 0xa6, 0xc9, 0xd4, 0x82, 0x5f, 0x0e, 0xf4, 0x27, 0x79, 0xf2, 0x74,
+	0x26, 0xbb, 0x81, 0x6e, 0x61, 0x88, 0xe6, 0xa1, 0x66, 0xae, 0xee, 0xd8, 0x0f, 0xad, 0x17, 0x61,
+	0xe5, 0x45, 0xf8, 0x79, 0xe3, 0xc5, 0x37, 0x4e, 0xcb, 0x18, 0x6c, 0xfb, 0xe6, 0xcc, 0x3c, 0x38,
+	0x2d, 0x51, 0x91, 0x90, 0x6b, 0xef, 0xc8, 0xbc, 0xb6, 0xba, 0x06, 0xb7, 0xd0, 0xb7, 0x8f, 0xfa,
+	0x97, 0x2f, 0x0d, 0xba, 0xbb, 0x4b, 0x1f, 0x00, 0x6b, 0xd2, 0x29, 0x97, 0x6b, 0xc2, 0x60, 0x02,
+	0xec, 0x01, 0xb9, 0x9a, 0x2f, 0x36, 0x28, 0x55, 0xaa, 0x17, 0x70, 0x42, 0x9a, 0xeb, 0x82, 0x8c,
+	0xf2, 0x59, 0xbc, 0xbd, 0xfd, 0xbf, 0xeb, 0xef, 0xe1, 0xfc, 0xab, 0x4c, 0xc5, 0xba, 0x12, 0x1c,
+	0xc0, 0x31, 0xae, 0xb8, 0xc8, 0xb6, 0x7a, 0xf6, 0xc2, 0x7c, 0xe8, 0xe4, 0x9c, 0xe8, 0xa7, 0x54,
+	0x56, 0xe7, 0x2c, 0xfe, 0x7b, 0x1f, 0xff, 0x76, 0xe1, 0xdc, 0xcc, 0xf4, 0x60, 0x77, 0x8b, 0x5d,
+	0xc3, 0xe9, 0x76, 0x27, 0x98, 0x57, 0xdb, 0xbe, 0xbb, 0x26, 0xfe, 0x5e, 0x20, 0xec, 0x1d, 0x40,
+	0x9d, 0x3e, 0x7b, 0x51, 0x57, 0x5b, 0x3b, 0x71, 0x88, 0x5a, 0xc7, 0xdf, 0xa4, 0xb6, 0x96, 0xa2,
+	0x45, 0xbd, 0x07, 0xa8, 0xad, 0x6e, 0x52, 0x5b, 0xf9, 0xf9, 0x97, 0x87, 0x8b, 0x36, 0x1d, 0x76,
+	0x0b, 0xdd, 0x46, 0x3a, 0xac, 0xd1, 0xdc, 0x0e, 0x6d, 0x7f, 0x8a, 0xd7, 0x0e, 0x8b, 0xe0, 0xd8,
+	0xa4, 0xc0, 0x2e, 0xea, 0x52, 0x33, 0x96, 0x7d, 0xca, 0xc7, 0xbb, 0xef, 0x1f, 0x52, 0xa1, 0x17,
+	0xc5, 0x2c, 0x9c, 0xcb, 0x55, 0x44, 0xbc, 0x50, 0xbc, 0x4c, 0x69, 0x31, 0x7a, 0x13, 0xcd, 0xa4,
+	0x5c, 0x92, 0x96, 0x0a, 0xa7, 0x86, 0xf1, 0x8a, 0xe7, 0xc2, 0xfe, 0xd5, 0x51, 0xf5, 0x03, 0xdf,
+	0x98, 0x43, 0x39, 0x9a, 0x9d, 0x18, 0xfc, 0xfa, 0xcf, 0x00, 0x15, 0xbd, 0xb3, 0xde, 0x25, 0x04,
+	0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// UsersServiceClient is the client API for UsersService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type UsersServiceClient interface {
+	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error)
+	// UpdateUser changes the fields named by the update mask, or the non empty ones without a
+	// mask. The "*" path replaces every updatable field.
+	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*User, error)
+	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*DeleteUserResponse, error)
+	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (UsersService_SearchUsersClient, error)
+	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*User, error)
+}
+
+type usersServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUsersServiceClient(cc grpc.ClientConnInterface) UsersServiceClient {
+	return &usersServiceClient{cc}
+}
+
+func (c *usersServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/users.v1.UsersService/GetUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *usersServiceClient) CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/users.v1.UsersService/CreateUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *usersServiceClient) UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/users.v1.UsersService/UpdateUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *usersServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*DeleteUserResponse, error) {
+	out := new(DeleteUserResponse)
+	err := c.cc.Invoke(ctx, "/users.v1.UsersService/DeleteUser", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *usersServiceClient) SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...grpc.CallOption) (UsersService_SearchUsersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_UsersService_serviceDesc.Streams[0], "/users.v1.UsersService/SearchUsers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &usersServiceSearchUsersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type UsersService_SearchUsersClient interface {
+	Recv() (*User, error)
+	grpc.ClientStream
+}
+
+type usersServiceSearchUsersClient struct {
+	grpc.ClientStream
+}
+
+func (x *usersServiceSearchUsersClient) Recv() (*User, error) {
+	m := new(User)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *usersServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/users.v1.UsersService/Login", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UsersServiceServer is the server API for UsersService service.
+type UsersServiceServer interface {
+	GetUser(context.Context, *GetUserRequest) (*User, error)
+	CreateUser(context.Context, *CreateUserRequest) (*User, error)
+	// UpdateUser changes the fields named by the update mask, or the non empty ones without a
+	// mask. The "*" path replaces every updatable field.
+	UpdateUser(context.Context, *UpdateUserRequest) (*User, error)
+	DeleteUser(context.Context, *DeleteUserRequest) (*DeleteUserResponse, error)
+	SearchUsers(*SearchUsersRequest, UsersService_SearchUsersServer) error
+	Login(context.Context, *LoginRequest) (*User, error)
+}
+
+// UnimplementedUsersServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedUsersServiceServer struct {
+}
+
+func (*UnimplementedUsersServiceServer) GetUser(ctx context.Context, req *GetUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (*UnimplementedUsersServiceServer) CreateUser(ctx context.Context, req *CreateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUser not implemented")
+}
+func (*UnimplementedUsersServiceServer) UpdateUser(ctx context.Context, req *UpdateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+}
+func (*UnimplementedUsersServiceServer) DeleteUser(ctx context.Context, req *DeleteUserRequest) (*DeleteUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (*UnimplementedUsersServiceServer) SearchUsers(req *SearchUsersRequest, srv UsersService_SearchUsersServer) error {
+	return status.Errorf(codes.Unimplemented, "method SearchUsers not implemented")
+}
+func (*UnimplementedUsersServiceServer) Login(ctx context.Context, req *LoginRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+
+func RegisterUsersServiceServer(s *grpc.Server, srv UsersServiceServer) {
+	s.RegisterService(&_UsersService_serviceDesc, srv)
+}
+
+func _UsersService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServiceServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/users.v1.UsersService/GetUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServiceServer).GetUser(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UsersService_CreateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServiceServer).CreateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/users.v1.UsersService/CreateUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServiceServer).CreateUser(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UsersService_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServiceServer).UpdateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/users.v1.UsersService/UpdateUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServiceServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UsersService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServiceServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/users.v1.UsersService/DeleteUser",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UsersService_SearchUsers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchUsersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UsersServiceServer).SearchUsers(m, &usersServiceSearchUsersServer{stream})
+}
+
+type UsersService_SearchUsersServer interface {
+	Send(*User) error
+	grpc.ServerStream
+}
+
+type usersServiceSearchUsersServer struct {
+	grpc.ServerStream
+}
+
+func (x *usersServiceSearchUsersServer) Send(m *User) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _UsersService_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UsersServiceServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/users.v1.UsersService/Login",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UsersServiceServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _UsersService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "users.v1.UsersService",
+	HandlerType: (*UsersServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetUser",
+			Handler:    _UsersService_GetUser_Handler,
+		},
+		{
+			MethodName: "CreateUser",
+			Handler:    _UsersService_CreateUser_Handler,
+		},
+		{
+			MethodName: "UpdateUser",
+			Handler:    _UsersService_UpdateUser_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _UsersService_DeleteUser_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _UsersService_Login_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SearchUsers",
+			Handler:       _UsersService_SearchUsers_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "users/v1/users_service.proto",
+}
@@ -0,0 +1,42 @@
+package publishers
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryPublisher keeps the last published messages in process, for development and tests
+type MemoryPublisher struct {
+	mux      sync.RWMutex
+	retained int
+	messages []Message
+}
+
+// NewMemoryPublisher returns an empty in-memory publisher keeping up to retained messages
+func NewMemoryPublisher(retained int) *MemoryPublisher {
+	return &MemoryPublisher{retained: retained}
+}
+
+// Publish stores the message, dropping the oldest one once retained messages are kept
+func (p *MemoryPublisher) Publish(ctx context.Context, msg Message) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.retained <= 0 {
+		return nil
+	}
+	if len(p.messages) == p.retained {
+		copy(p.messages, p.messages[1:])
+		p.messages = p.messages[:len(p.messages)-1]
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of the messages published last, oldest first
+func (p *MemoryPublisher) Messages() []Message {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	result := make([]Message, len(p.messages))
+	copy(result, p.messages)
+	return result
+}
@@ -0,0 +1,38 @@
+package publishers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMemoryPublisherRetained(t *testing.T) {
+	tests := []struct {
+		name      string
+		retained  int
+		published int
+		want      []int64
+	}{
+		{name: "below the limit", retained: 3, published: 2, want: []int64{1, 2}},
+		{name: "drops the oldest", retained: 3, published: 5, want: []int64{3, 4, 5}},
+		{name: "keeps nothing", retained: 0, published: 2, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewMemoryPublisher(tt.retained)
+			for id := 1; id <= tt.published; id++ {
+				if err := p.Publish(context.Background(), Message{ID: int64(id)}); err != nil {
+					t.Fatalf("publishing %d: %v", id, err)
+				}
+			}
+			got := []int64{}
+			for _, m := range p.Messages() {
+				got = append(got, m.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messages %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package publishers
+
+import "context"
+
+// MultiPublisher publishes every message to each of its publishers in turn
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+// NewMultiPublisher returns a publisher fanning out to ps
+func NewMultiPublisher(ps ...Publisher) *MultiPublisher {
+	return &MultiPublisher{publishers: ps}
+}
+
+// Publish stops at the first failure, the message is then retried on every publisher
+func (p *MultiPublisher) Publish(ctx context.Context, msg Message) error {
+	for _, pub := range p.publishers {
+		if err := pub.Publish(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package publishers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+)
+
+// Message is a domain event handed to a publisher
+type Message struct {
+	ID         int64  `json:"id"`
+	Type       string `json:"type"`
+	Key        string `json:"key"`
+	Payload    []byte `json:"payload"`
+	OccurredAt string `json:"occurred_at"`
+}
+
+type envelope struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	Key        string          `json:"key"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt string          `json:"occurred_at"`
+}
+
+// Envelope returns the JSON document sent to HTTP consumers, embedding the payload as is
+func (m Message) Envelope() ([]byte, error) {
+	return json.Marshal(envelope{
+		ID:         m.ID,
+		Type:       m.Type,
+		Key:        m.Key,
+		Payload:    json.RawMessage(m.Payload),
+		OccurredAt: m.OccurredAt,
+	})
+}
+
+// Publisher delivers messages to consumers. Delivery is at least once, so consumers
+// should use Message.ID to discard duplicates.
+type Publisher interface {
+	Publish(context.Context, Message) error
+}
+
+// New returns the publisher selected by OUTBOX_PUBLISHER, either memory or webhook. The memory
+// publisher only keeps the last OUTBOX_MEMORY_RETAINED messages.
+func New() (Publisher, error) {
+	switch kind := config.GetString("OUTBOX_PUBLISHER", "memory"); kind {
+	case "memory":
+		return NewMemoryPublisher(config.GetInt("OUTBOX_MEMORY_RETAINED", 1000)), nil
+	case "webhook":
+		url := config.GetString("OUTBOX_WEBHOOK_URL", "")
+		if url == "" {
+			return nil, fmt.Errorf("OUTBOX_WEBHOOK_URL is required for the webhook publisher")
+		}
+		return NewWebhookPublisher(url), nil
+	default:
+		return nil, fmt.Errorf("unknown publisher %q", kind)
+	}
+}
@@ -0,0 +1,180 @@
+package publishers
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNotRetained is returned when resuming after a message the stream no longer retains
+var ErrNotRetained = errors.New("message no longer retained")
+
+// Stream keeps the last messages published, once each and in publishing order, and hands new
+// ones to its subscribers
+type Stream struct {
+	mux    sync.Mutex
+	buffer int
+	// log is a ring of the retained messages, the oldest at start
+	log   []Message
+	start int
+	size  int
+	ids   map[int64]bool
+	subs  map[*Subscription]bool
+}
+
+// NewStream returns a stream retaining up to retained messages, each subscriber buffering up
+// to buffer of them
+func NewStream(retained, buffer int) *Stream {
+	if retained < 1 {
+		retained = 1
+	}
+	return &Stream{
+		buffer: buffer,
+		log:    make([]Message, retained),
+		ids:    make(map[int64]bool, retained),
+		subs:   make(map[*Subscription]bool),
+	}
+}
+
+// Subscription receives the messages published to a stream that it matches
+type Subscription struct {
+	// C is closed once the subscription ends, when closed or when it falls too far behind
+	C <-chan Message
+
+	c      chan Message
+	match  func(Message) bool
+	stream *Stream
+}
+
+// Close ends the subscription
+func (sub *Subscription) Close() {
+	sub.stream.mux.Lock()
+	defer sub.stream.mux.Unlock()
+	sub.stream.unsubscribe(sub)
+}
+
+// Publish retains the message and hands it to the matching subscribers, unless it was already
+// published. A subscriber whose buffer is full is unsubscribed, to resume from the last
+// message it got.
+func (s *Stream) Publish(ctx context.Context, msg Message) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.ids[msg.ID] {
+		return nil
+	}
+	end := (s.start + s.size) % len(s.log)
+	if s.size == len(s.log) {
+		delete(s.ids, s.log[s.start].ID)
+		s.start = (s.start + 1) % len(s.log)
+	} else {
+		s.size++
+	}
+	s.log[end] = msg
+	s.ids[msg.ID] = true
+
+	for sub := range s.subs {
+		if !sub.match(msg) {
+			continue
+		}
+		select {
+		case sub.c <- msg:
+		default:
+			s.unsubscribe(sub)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the retained messages published after lastID that match, and a subscription
+// to the matching messages published from then on. With a lastID of 0 only messages published
+// from then on are received. ErrNotRetained is returned if lastID is no longer retained.
+func (s *Stream) Subscribe(lastID int64, match func(Message) bool) ([]Message, *Subscription, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var backlog []Message
+	if lastID > 0 {
+		if !s.ids[lastID] {
+			return nil, nil, ErrNotRetained
+		}
+		found := false
+		for i := 0; i < s.size; i++ {
+			msg := s.log[(s.start+i)%len(s.log)]
+			if found && match(msg) {
+				backlog = append(backlog, msg)
+			}
+			found = found || msg.ID == lastID
+		}
+	}
+
+	c := make(chan Message, s.buffer)
+	sub := &Subscription{C: c, c: c, match: match, stream: s}
+	s.subs[sub] = true
+	return backlog, sub, nil
+}
+
+// Keyed returns the ids of the retained messages with key, in publishing order
+func (s *Stream) Keyed(key string) []int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var ids []int64
+	for i := 0; i < s.size; i++ {
+		if msg := s.log[(s.start+i)%len(s.log)]; msg.Key == key {
+			ids = append(ids, msg.ID)
+		}
+	}
+	return ids
+}
+
+// Replace swaps the payload of the retained messages for the one of the message of msgs with
+// the same id, such as once they were redacted. Only later backlogs hold the new payloads.
+func (s *Stream) Replace(msgs []Message) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	payloads := make(map[int64][]byte, len(msgs))
+	for _, msg := range msgs {
+		payloads[msg.ID] = msg.Payload
+	}
+	for i := 0; i < s.size; i++ {
+		j := (s.start + i) % len(s.log)
+		if payload, ok := payloads[s.log[j].ID]; ok {
+			s.log[j].Payload = payload
+		}
+	}
+}
+
+// Evict drops the retained messages among ids, subscribing after one of them then fails with
+// ErrNotRetained
+func (s *Stream) Evict(ids []int64) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	evicted := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		evicted[id] = s.ids[id]
+	}
+	kept := 0
+	for i := 0; i < s.size; i++ {
+		msg := s.log[(s.start+i)%len(s.log)]
+		if evicted[msg.ID] {
+			delete(s.ids, msg.ID)
+			continue
+		}
+		s.log[(s.start+kept)%len(s.log)] = msg
+		kept++
+	}
+	for i := kept; i < s.size; i++ {
+		s.log[(s.start+i)%len(s.log)] = Message{}
+	}
+	s.size = kept
+}
+
+func (s *Stream) unsubscribe(sub *Subscription) {
+	if s.subs[sub] {
+		delete(s.subs, sub)
+		close(sub.c)
+	}
+}
@@ -0,0 +1,171 @@
+package publishers
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func publishAll(t *testing.T, s *Stream, keys ...string) {
+	t.Helper()
+	for i, key := range keys {
+		id := int64(i + 1)
+		if err := s.Publish(context.Background(), Message{ID: id, Key: key, Payload: []byte(strconv.FormatInt(id, 10))}); err != nil {
+			t.Fatalf("publishing %d: %v", id, err)
+		}
+	}
+}
+
+func messageIDs(msgs []Message) []int64 {
+	ids := []int64{}
+	for _, m := range msgs {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestStreamSubscribe(t *testing.T) {
+	all := func(Message) bool { return true }
+	keyed := func(key string) func(Message) bool {
+		return func(m Message) bool { return m.Key == key }
+	}
+
+	tests := []struct {
+		name     string
+		retained int
+		keys     []string
+		lastID   int64
+		match    func(Message) bool
+
+		backlog []int64
+		err     error
+	}{
+		{name: "new events only", retained: 5, keys: []string{"1", "2", "3"}, match: all, backlog: []int64{}},
+		{name: "resume after an event", retained: 5, keys: []string{"1", "2", "3"}, lastID: 1, match: all, backlog: []int64{2, 3}},
+		{name: "resume after the last event", retained: 5, keys: []string{"1", "2", "3"}, lastID: 3, match: all, backlog: []int64{}},
+		{name: "resume filtered", retained: 5, keys: []string{"1", "2", "1", "2"}, lastID: 1, match: keyed("2"), backlog: []int64{2, 4}},
+		{name: "resume after an event filtered out", retained: 5, keys: []string{"1", "2", "1"}, lastID: 2, match: keyed("1"), backlog: []int64{3}},
+		{name: "resume after a dropped event", retained: 2, keys: []string{"1", "2", "3"}, lastID: 1, match: all, err: ErrNotRetained},
+		{name: "resume after an unknown event", retained: 5, keys: []string{"1"}, lastID: 9, match: all, err: ErrNotRetained},
+		{name: "resume in a wrapped ring", retained: 3, keys: []string{"1", "2", "3", "4", "5"}, lastID: 3, match: all, backlog: []int64{4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStream(tt.retained, 4)
+			publishAll(t, s, tt.keys...)
+
+			backlog, sub, err := s.Subscribe(tt.lastID, tt.match)
+			if err != tt.err {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			defer sub.Close()
+			if got := messageIDs(backlog); !reflect.DeepEqual(got, tt.backlog) {
+				t.Errorf("backlog %v, want %v", got, tt.backlog)
+			}
+
+			next := int64(len(tt.keys) + 1)
+			s.Publish(context.Background(), Message{ID: next, Key: "1"})
+			s.Publish(context.Background(), Message{ID: next, Key: "1"})
+			if tt.match(Message{Key: "1"}) {
+				if got := <-sub.C; got.ID != next {
+					t.Errorf("received %d, want %d", got.ID, next)
+				}
+			}
+			if len(sub.C) != 0 {
+				t.Errorf("%d more messages received, want none", len(sub.C))
+			}
+		})
+	}
+}
+
+func TestStreamUnsubscribesSlowSubscribers(t *testing.T) {
+	s := NewStream(10, 2)
+	_, sub, err := s.Subscribe(0, func(Message) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishAll(t, s, "1", "2", "3")
+
+	var got []int64
+	for m := range sub.C {
+		got = append(got, m.ID)
+	}
+	if !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Errorf("received %v before the subscription ended, want [1 2]", got)
+	}
+	// the subscriber resumes from the last message it got
+	backlog, sub, err := s.Subscribe(2, func(Message) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if ids := messageIDs(backlog); !reflect.DeepEqual(ids, []int64{3}) {
+		t.Errorf("resumed with %v, want [3]", ids)
+	}
+}
+
+// retainedMessages returns the messages s retains, oldest first
+func retainedMessages(s *Stream) []Message {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	msgs := make([]Message, s.size)
+	for i := range msgs {
+		msgs[i] = s.log[(s.start+i)%len(s.log)]
+	}
+	return msgs
+}
+
+func TestStreamErasure(t *testing.T) {
+	tests := []struct {
+		name     string
+		evict    bool
+		retained []int64
+		payload  map[int64]string
+	}{
+		{name: "replaced", retained: []int64{2, 3, 4}, payload: map[int64]string{2: "2", 3: "redacted", 4: "4"}},
+		{name: "evicted", evict: true, retained: []int64{2, 4}, payload: map[int64]string{2: "2", 4: "4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewStream(3, 4)
+			publishAll(t, s, "7", "8", "7", "8")
+
+			ids := s.Keyed("7")
+			if !reflect.DeepEqual(ids, []int64{3}) {
+				t.Fatalf("keyed %v, want the retained [3]", ids)
+			}
+			if tt.evict {
+				s.Evict(ids)
+			} else {
+				// messages no longer retained are not brought back
+				s.Replace([]Message{{ID: 3, Key: "7", Payload: []byte("redacted")}, {ID: 1, Payload: []byte("dropped")}})
+			}
+
+			retained := retainedMessages(s)
+			if got := messageIDs(retained); !reflect.DeepEqual(got, tt.retained) {
+				t.Errorf("retained %v, want %v", got, tt.retained)
+			}
+			for _, m := range retained {
+				if string(m.Payload) != tt.payload[m.ID] {
+					t.Errorf("message %d has payload %q, want %q", m.ID, m.Payload, tt.payload[m.ID])
+				}
+			}
+			if _, _, err := s.Subscribe(3, func(Message) bool { return true }); (err == ErrNotRetained) != tt.evict {
+				t.Errorf("resuming after the erased message: %v", err)
+			}
+
+			// the ring keeps its order once a message was evicted from its middle
+			s.Publish(context.Background(), Message{ID: 5, Key: "7"})
+			s.Publish(context.Background(), Message{ID: 6, Key: "7"})
+			if got, want := messageIDs(retainedMessages(s)), []int64{4, 5, 6}; !reflect.DeepEqual(got, want) {
+				t.Errorf("retained %v after publishing again, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops idle keys
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the limits in process, each instance counting its own requests
+type MemoryStore struct {
+	mux       sync.Mutex
+	buckets   map[string]*bucket
+	windows   map[string]*window
+	nextSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration
+}
+
+type window struct {
+	start      time.Time
+	prev, curr int64
+	size       time.Duration
+}
+
+// NewMemoryStore returns an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		windows:   make(map[string]*window),
+		nextSweep: time.Now().Add(sweepInterval),
+	}
+}
+
+// Take counts a request for key against policy
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	now := time.Now()
+	key = policy.Name + ":" + key
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if now.After(s.nextSweep) {
+		s.sweep(now)
+	}
+	switch policy.Algorithm {
+	case TokenBucket:
+		return s.takeToken(now, key, policy), nil
+	case SlidingWindow:
+		return s.takeWindow(now, key, policy), nil
+	default:
+		return Result{}, fmt.Errorf("unknown rate limit algorithm %q", policy.Algorithm)
+	}
+}
+
+func (s *MemoryStore) takeToken(now time.Time, key string, p Policy) Result {
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(p.Limit), updated: now, window: p.Window}
+		s.buckets[key] = b
+	}
+	refill := float64(now.Sub(b.updated)) / float64(p.Window) * float64(p.Limit)
+	b.tokens = math.Min(float64(p.Limit), b.tokens+refill)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return bucketResult(p, allowed, b.tokens)
+}
+
+func (s *MemoryStore) takeWindow(now time.Time, key string, p Policy) Result {
+	start := now.Truncate(p.Window)
+	w, ok := s.windows[key]
+	if !ok {
+		w = &window{start: start, size: p.Window}
+		s.windows[key] = w
+	}
+	if w.start != start {
+		if w.start.Add(p.Window) == start {
+			w.prev = w.curr
+		} else {
+			w.prev = 0
+		}
+		w.curr = 0
+		w.start = start
+	}
+
+	elapsed := now.Sub(start)
+	estimate := float64(w.prev)*float64(p.Window-elapsed)/float64(p.Window) + float64(w.curr)
+	allowed := estimate+1 <= float64(p.Limit)
+	if allowed {
+		w.curr++
+	}
+	return windowResult(p, allowed, w.prev, w.curr, elapsed)
+}
+
+// sweep drops the keys whose limits are fully available again
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.window {
+			delete(s.buckets, key)
+		}
+	}
+	for key, w := range s.windows {
+		if now.Sub(w.start) >= 2*w.size {
+			delete(s.windows, key)
+		}
+	}
+	s.nextSweep = now.Add(sweepInterval)
+}
@@ -0,0 +1,115 @@
+// Package ratelimit counts requests against limits per key, in process or on a server shared
+// by every instance
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/resp"
+)
+
+const (
+	// TokenBucket refills Limit tokens evenly over Window, allowing bursts of up to Limit
+	TokenBucket = "token_bucket"
+	// SlidingWindow allows Limit requests in any Window, estimated from the counts of the
+	// current and previous fixed windows
+	SlidingWindow = "sliding_window"
+)
+
+// Policy is a limit of requests per window
+type Policy struct {
+	// Name identifies the policy in keys and headers
+	Name      string
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+}
+
+// String formats the policy as in the RateLimit-Policy header
+func (p Policy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Limit, int64(p.Window/time.Second))
+}
+
+// Result is the outcome of taking a request from a limit
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the limit is fully available again
+	Reset time.Duration
+	// RetryAfter is the time until a request is allowed again, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the state of the limits
+type Store interface {
+	// Take counts a request for key against policy
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// New returns the store selected by RATELIMIT_STORE, memory or redis. The redis store talks
+// to RATELIMIT_REDIS_ADDR, so every replica counts against the same limits.
+func New() (Store, error) {
+	switch kind := config.GetString("RATELIMIT_STORE", "memory"); kind {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		client := resp.NewClient(resp.Options{
+			Addr:     config.GetString("RATELIMIT_REDIS_ADDR", "localhost:6379"),
+			Password: config.GetString("RATELIMIT_REDIS_PASSWORD", ""),
+			DB:       config.GetInt("RATELIMIT_REDIS_DB", 0),
+			Timeout:  config.GetDuration("RATELIMIT_REDIS_TIMEOUT", 100*time.Millisecond),
+		})
+		return NewRedisStore(client, "users-api:ratelimit:"), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", kind)
+	}
+}
+
+// bucketResult is the result of a token bucket holding tokens after the request
+func bucketResult(p Policy, allowed bool, tokens float64) Result {
+	perToken := p.Window / time.Duration(p.Limit)
+	r := Result{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(p.Limit) - tokens) * float64(perToken)),
+	}
+	if !allowed {
+		r.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	return r
+}
+
+// windowResult is the result of a sliding window whose previous and current fixed windows
+// counted prev and curr requests, elapsed into the current one
+func windowResult(p Policy, allowed bool, prev, curr int64, elapsed time.Duration) Result {
+	left := p.Window - elapsed
+	estimate := float64(prev)*float64(left)/float64(p.Window) + float64(curr)
+	r := Result{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: int(math.Max(0, math.Floor(float64(p.Limit)-estimate))),
+		Reset:     left,
+	}
+	if curr > 0 {
+		// the current window still counts as the previous one of the next
+		r.Reset += p.Window
+	}
+	if allowed {
+		return r
+	}
+	if curr < int64(p.Limit) {
+		// the weight of the previous window decays until the estimate is back under the limit
+		excess := estimate - float64(p.Limit-1)
+		r.RetryAfter = time.Duration(math.Min(excess/float64(prev)*float64(p.Window), float64(left)))
+	} else {
+		// wait for the next window, then for the weight of this one to decay
+		r.RetryAfter = left + time.Duration(float64(p.Window)*(1-float64(p.Limit-1)/float64(curr)))
+	}
+	return r
+}
@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/resp"
+)
+
+// stores returns a memory store and a Redis store on a stand-in server, to be closed
+func stores(t *testing.T) (map[string]Store, func()) {
+	t.Helper()
+	srv, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting redis stand-in: %v", err)
+	}
+	return map[string]Store{
+		"memory": NewMemoryStore(),
+		"redis":  NewRedisStore(resp.NewClient(resp.Options{Addr: srv.Addr()}), "test:"),
+	}, srv.Close
+}
+
+func TestStoreTake(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		// wait after the limit is reached, before a request is expected to be allowed again
+		wait time.Duration
+	}{
+		{name: "token bucket", policy: Policy{Name: "bucket", Algorithm: TokenBucket, Limit: 3, Window: 300 * time.Millisecond}, wait: 150 * time.Millisecond},
+		{name: "sliding window", policy: Policy{Name: "window", Algorithm: SlidingWindow, Limit: 3, Window: 300 * time.Millisecond}, wait: 450 * time.Millisecond},
+	}
+
+	all, closeStores := stores(t)
+	defer closeStores()
+	for storeName, store := range all {
+		for _, tt := range tests {
+			t.Run(storeName+" "+tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				for i := 0; i < tt.policy.Limit; i++ {
+					r, err := store.Take(ctx, "client", tt.policy)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !r.Allowed || r.Remaining != tt.policy.Limit-i-1 || r.Limit != tt.policy.Limit || r.RetryAfter != 0 {
+						t.Fatalf("request %d: got %+v, want allowed with %d remaining", i+1, r, tt.policy.Limit-i-1)
+					}
+				}
+
+				r, err := store.Take(ctx, "client", tt.policy)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r.Allowed || r.Remaining != 0 || r.RetryAfter <= 0 || r.RetryAfter > 2*tt.policy.Window || r.Reset <= 0 {
+					t.Fatalf("request over the limit: got %+v, want rejected with a retry after", r)
+				}
+
+				if r, err := store.Take(ctx, "other", tt.policy); err != nil || !r.Allowed {
+					t.Fatalf("another key: got %+v, %v, want allowed", r, err)
+				}
+				other := tt.policy
+				other.Name = "other"
+				if r, err := store.Take(ctx, "client", other); err != nil || !r.Allowed {
+					t.Fatalf("another policy: got %+v, %v, want allowed", r, err)
+				}
+
+				time.Sleep(tt.wait)
+				if r, err := store.Take(ctx, "client", tt.policy); err != nil || !r.Allowed {
+					t.Fatalf("after %v: got %+v, %v, want allowed", tt.wait, r, err)
+				}
+			})
+		}
+	}
+}
+
+func TestStoreUnknownAlgorithm(t *testing.T) {
+	all, closeStores := stores(t)
+	defer closeStores()
+	for name, store := range all {
+		if _, err := store.Take(context.Background(), "client", Policy{Name: "p", Algorithm: "leaky", Limit: 1, Window: time.Second}); err == nil {
+			t.Errorf("%s store took a request for an unknown algorithm", name)
+		}
+	}
+}
+
+func TestWindowResult(t *testing.T) {
+	p := Policy{Limit: 10, Window: 10 * time.Second}
+	tests := []struct {
+		name       string
+		allowed    bool
+		prev, curr int64
+		elapsed    time.Duration
+
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{name: "first request", allowed: true, curr: 1, elapsed: time.Second, remaining: 9},
+		{name: "previous window weighs in", allowed: true, prev: 10, curr: 1, elapsed: 5 * time.Second, remaining: 4},
+		{name: "rejected while the previous window decays", prev: 10, curr: 5, elapsed: 5 * time.Second, retryAfter: time.Second},
+		{name: "rejected until the next window", curr: 10, elapsed: 5 * time.Second, retryAfter: 5*time.Second + time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := windowResult(p, tt.allowed, tt.prev, tt.curr, tt.elapsed)
+			if diff := r.RetryAfter - tt.retryAfter; r.Remaining != tt.remaining || diff < -time.Millisecond || diff > time.Millisecond {
+				t.Errorf("got remaining %d and retry after %v, want %d and %v", r.Remaining, r.RetryAfter, tt.remaining, tt.retryAfter)
+			}
+		})
+	}
+}
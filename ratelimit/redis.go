@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/resp"
+)
+
+// tokenBucketScript refills and takes a token atomically. It returns whether the request is
+// allowed and the tokens left, as a string since Lua numbers are truncated in replies.
+const tokenBucketScript = `
+local limit, window, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or limit
+local updated = tonumber(state[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - updated) * limit / window)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, tostring(tokens)}
+`
+
+// slidingWindowScript counts the request in the current fixed window unless the estimate is
+// over the limit. It returns whether the request is allowed, the counts of the previous and
+// current windows and the time elapsed into the current one.
+const slidingWindowScript = `
+local limit, window, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local start = now - now % window
+local curr_key = KEYS[1] .. ':' .. start
+local prev = tonumber(redis.call('GET', KEYS[1] .. ':' .. (start - window))) or 0
+local curr = tonumber(redis.call('GET', curr_key)) or 0
+local elapsed = now - start
+local allowed = 0
+if prev * (window - elapsed) / window + curr + 1 <= limit then
+	curr = redis.call('INCR', curr_key)
+	redis.call('PEXPIRE', curr_key, 2 * window)
+	allowed = 1
+end
+return {allowed, prev, curr, elapsed}
+`
+
+// RedisStore keeps the limits on a server speaking the Redis protocol, shared by every instance.
+// Requests are timed by the instance clock, which should be synchronised across instances.
+type RedisStore struct {
+	client *resp.Client
+	prefix string
+}
+
+// NewRedisStore returns a store keeping its keys under prefix on the server the client talks to
+func NewRedisStore(client *resp.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Take counts a request for key against policy
+func (s *RedisStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	key = s.prefix + policy.Name + ":" + key
+	args := []string{
+		strconv.Itoa(policy.Limit),
+		strconv.FormatInt(int64(policy.Window/time.Millisecond), 10),
+		strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+	}
+
+	switch policy.Algorithm {
+	case TokenBucket:
+		reply, err := s.eval(ctx, tokenBucketScript, key, args)
+		if err != nil {
+			return Result{}, err
+		}
+		if len(reply) != 2 {
+			return Result{}, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+		}
+		tokens, err := strconv.ParseFloat(fmt.Sprint(reply[1]), 64)
+		if err != nil {
+			return Result{}, err
+		}
+		return bucketResult(policy, reply[0] == int64(1), tokens), nil
+	case SlidingWindow:
+		reply, err := s.eval(ctx, slidingWindowScript, key, args)
+		if err != nil {
+			return Result{}, err
+		}
+		counts := make([]int64, len(reply))
+		for i, v := range reply {
+			n, ok := v.(int64)
+			if !ok {
+				return Result{}, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+			}
+			counts[i] = n
+		}
+		if len(counts) != 4 {
+			return Result{}, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+		}
+		return windowResult(policy, counts[0] == 1, counts[1], counts[2], time.Duration(counts[3])*time.Millisecond), nil
+	default:
+		return Result{}, fmt.Errorf("unknown rate limit algorithm %q", policy.Algorithm)
+	}
+}
+
+// eval runs a cached script, loading it on the first call
+func (s *RedisStore) eval(ctx context.Context, script, key string, args []string) ([]interface{}, error) {
+	sum := sha1.Sum([]byte(script))
+	cmd := append([]string{"EVALSHA", hex.EncodeToString(sum[:]), "1", key}, args...)
+	reply, err := s.client.Do(ctx, cmd...)
+	if e, ok := err.(resp.Error); ok && strings.HasPrefix(string(e), "NOSCRIPT") {
+		cmd[0], cmd[1] = "EVAL", script
+		reply, err = s.client.Do(ctx, cmd...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+	}
+	return items, nil
+}
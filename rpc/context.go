@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+)
+
+// call holds what the HTTP middlewares resolve from headers, read from metadata instead
+type call struct {
+	view   string
+	caller users.Caller
+}
+
+type callKey struct{}
+
+// getCall returns the call the context was set up for
+func getCall(ctx context.Context) call {
+	c, _ := ctx.Value(callKey{}).(call)
+	return c
+}
+
+// newCallContext resolves the request id, view and actor of a call from its metadata, and
+// pins its reads to the primary once it has written as DBSession does
+func newCallContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(key string) string {
+		if values := md.Get(strings.ToLower(key)); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	id := get(middlewares.RequestIDHeader)
+	if id == "" || len(id) > 128 {
+		id = crypto.GetRandomToken(16)
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(middlewares.RequestIDHeader), id)); err != nil {
+		logger.Error("failed to set request id header: ", err)
+	}
+
+	view := get(middlewares.ViewHeader)
+	if view == "" {
+		view = middlewares.DefaultView
+		if get("X-Public") == "true" {
+			view = users.ViewPublic
+		}
+	}
+	actor := get("X-Actor")
+	if actor == "" {
+		actor = "anonymous"
+	}
+
+	c := call{view: view, caller: users.Caller{Actor: actor, RequestID: id}}
+	return context.WithValue(usersdb.WithSession(ctx), callKey{}, c)
+}
+
+// recoverPanic turns a panicking handler into an internal error, as gin's recovery does
+func recoverPanic(method string, err *error) {
+	if r := recover(); r != nil {
+		logger.Error(fmt.Sprintf("panic in %s: ", method), fmt.Errorf("%v", r))
+		*err = status.Error(codes.Internal, "internal server error")
+	}
+}
+
+func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	return handler(newCallContext(ctx), req)
+}
+
+// contextStream replaces the context of a server stream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	return handler(srv, &contextStream{ServerStream: ss, ctx: newCallContext(ss.Context())})
+}
@@ -0,0 +1,41 @@
+package rpc
+
+import (
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// codesByStatus maps the HTTP status of a RestErr to the closest gRPC code
+var codesByStatus = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.Aborted,
+	http.StatusPreconditionFailed:    codes.FailedPrecondition,
+	http.StatusPreconditionRequired:  codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusInternalServerError:   codes.Internal,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// statusError returns err as a gRPC status error, carrying the RestErr as its detail
+func statusError(err *errors.RestErr) error {
+	code, ok := codesByStatus[err.Status]
+	if !ok {
+		code = codes.Unknown
+	}
+	st := status.New(code, err.Message)
+	if detailed, detailErr := st.WithDetails(err.Proto()); detailErr == nil {
+		st = detailed
+	} else {
+		logger.Error("failed to attach error details: ", detailErr)
+	}
+	return st.Err()
+}
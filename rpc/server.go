@@ -0,0 +1,29 @@
+// Package rpc serves the users API over gRPC, next to the HTTP one
+package rpc
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	usersv1 "github.com/sauravgsh16/bookstore_users-api/proto/users/v1"
+)
+
+// NewServer returns a gRPC server with the users service, along with the standard health and
+// reflection services
+func NewServer() *grpc.Server {
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryInterceptor),
+		grpc.StreamInterceptor(streamInterceptor),
+	)
+	usersv1.RegisterUsersServiceServer(s, &usersServer{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("users.v1.UsersService", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+
+	reflection.Register(s)
+	return s
+}
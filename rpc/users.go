@@ -0,0 +1,166 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	usersv1 "github.com/sauravgsh16/bookstore_users-api/proto/users/v1"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+var (
+	// requireVersion rejects updates and deletions not conditional on a version, as the HTTP API
+	// rejects them without an If-Match header
+	requireVersion = config.GetBool("USERS_REQUIRE_IF_MATCH", false)
+)
+
+// updatableFields are the user fields an update mask may name
+var updatableFields = map[string]func(dst, src *users.User){
+	"first_name": func(dst, src *users.User) { dst.FirstName = src.FirstName },
+	"last_name":  func(dst, src *users.User) { dst.LastName = src.LastName },
+	"email":      func(dst, src *users.User) { dst.Email = src.Email },
+}
+
+// checkVersion returns an error if version is required and the request is not conditional on one
+func checkVersion(version int64) *errors.RestErr {
+	if version == 0 && requireVersion {
+		return errors.NewPreconditionRequiredError("version is required")
+	}
+	return nil
+}
+
+// usersServer serves users.v1.UsersService from the user service
+type usersServer struct{}
+
+// project returns u in the view of the call
+func project(ctx context.Context, u *users.User) (*usersv1.User, error) {
+	p, err := users.NewProjection(getCall(ctx).view, nil)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return u.Marshall(p).Proto(), nil
+}
+
+// GetUser returns a user
+func (s *usersServer) GetUser(ctx context.Context, req *usersv1.GetUserRequest) (*usersv1.User, error) {
+	user, err := services.UserServ.GetUser(ctx, int(req.Id), req.IncludeDeleted)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return project(ctx, user)
+}
+
+// CreateUser creates a new user
+func (s *usersServer) CreateUser(ctx context.Context, req *usersv1.CreateUserRequest) (*usersv1.User, error) {
+	if req.User == nil {
+		return nil, statusError(errors.NewBadRequestError("user is required"))
+	}
+	var u users.User
+	u.FromProto(req.User)
+
+	user, err := services.UserServ.CreateUser(ctx, u, getCall(ctx).caller)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return project(ctx, user)
+}
+
+// UpdateUser updates the fields of a user named by the update mask
+func (s *usersServer) UpdateUser(ctx context.Context, req *usersv1.UpdateUserRequest) (*usersv1.User, error) {
+	if req.User == nil {
+		return nil, statusError(errors.NewBadRequestError("user is required"))
+	}
+	if err := checkVersion(req.Version); err != nil {
+		return nil, statusError(err)
+	}
+	var u users.User
+	u.FromProto(req.User)
+	u.Version = int(req.Version)
+
+	isPatch := true
+	if paths := req.GetUpdateMask().GetPaths(); len(paths) == 1 && paths[0] == "*" {
+		isPatch = false
+	} else if len(paths) > 0 {
+		merged, err := applyMask(ctx, u, paths)
+		if err != nil {
+			return nil, statusError(err)
+		}
+		u, isPatch = *merged, false
+	}
+
+	user, err := services.UserServ.UpdateUser(ctx, u, isPatch, getCall(ctx).caller)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return project(ctx, user)
+}
+
+// applyMask returns the stored user with the masked fields taken from u. Without a version of
+// its own the update is made conditional on the version read, so a concurrent change is not
+// silently overwritten.
+func applyMask(ctx context.Context, u users.User, paths []string) (*users.User, *errors.RestErr) {
+	for _, path := range paths {
+		if _, ok := updatableFields[path]; !ok {
+			return nil, errors.NewBadRequestError(fmt.Sprintf("field %q cannot be updated", path))
+		}
+	}
+
+	current, err := services.UserServ.GetUser(ctx, u.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	if u.Version != 0 {
+		current.Version = u.Version
+	}
+	for _, path := range paths {
+		updatableFields[path](current, &u)
+	}
+	return current, nil
+}
+
+// DeleteUser soft deletes a user
+func (s *usersServer) DeleteUser(ctx context.Context, req *usersv1.DeleteUserRequest) (*usersv1.DeleteUserResponse, error) {
+	if err := checkVersion(req.Version); err != nil {
+		return nil, statusError(err)
+	}
+	if err := services.UserServ.DeleteUser(ctx, int(req.Id), int(req.Version), getCall(ctx).caller); err != nil {
+		return nil, statusError(err)
+	}
+	return &usersv1.DeleteUserResponse{}, nil
+}
+
+// SearchUsers streams the users with a status
+func (s *usersServer) SearchUsers(req *usersv1.SearchUsersRequest, stream usersv1.UsersService_SearchUsersServer) error {
+	ctx := stream.Context()
+	p, projErr := users.NewProjection(getCall(ctx).view, nil)
+	if projErr != nil {
+		return statusError(projErr)
+	}
+
+	result, err := services.UserServ.SearchUser(ctx, req.Status, req.IncludeDeleted)
+	if err != nil {
+		return statusError(err)
+	}
+	for _, user := range result {
+		if err := stream.Send(user.Marshall(p).Proto()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Login returns the user the credentials belong to
+func (s *usersServer) Login(ctx context.Context, req *usersv1.LoginRequest) (*usersv1.User, error) {
+	user, err := services.UserServ.LoginUser(ctx, users.LoginRequest{
+		Email:    req.Email,
+		Password: crypto.GetMd5(req.Password),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return project(ctx, user)
+}
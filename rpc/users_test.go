@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/middlewares"
+	usersv1 "github.com/sauravgsh16/bookstore_users-api/proto/users/v1"
+	"github.com/sauravgsh16/bookstore_users-api/services"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// versionedUsers records the version each update and deletion was conditional on
+type versionedUsers struct {
+	services.UserInterface
+	versions []int
+}
+
+func (s *versionedUsers) UpdateUser(ctx context.Context, u users.User, isPatch bool, caller users.Caller) (*users.User, *errors.RestErr) {
+	s.versions = append(s.versions, u.Version)
+	return &u, nil
+}
+
+func (s *versionedUsers) DeleteUser(ctx context.Context, userID int, version int, caller users.Caller) *errors.RestErr {
+	s.versions = append(s.versions, version)
+	return nil
+}
+
+func TestRequireVersion(t *testing.T) {
+	defer func(serv services.UserInterface, required bool) {
+		services.UserServ, requireVersion = serv, required
+	}(services.UserServ, requireVersion)
+
+	ctx := context.WithValue(context.Background(), callKey{}, call{view: middlewares.DefaultView})
+	server := &usersServer{}
+	calls := map[string]func(version int64) error{
+		"update": func(version int64) error {
+			_, err := server.UpdateUser(ctx, &usersv1.UpdateUserRequest{User: &usersv1.User{Id: 1}, Version: version})
+			return err
+		},
+		"delete": func(version int64) error {
+			_, err := server.DeleteUser(ctx, &usersv1.DeleteUserRequest{Id: 1, Version: version})
+			return err
+		},
+	}
+
+	tests := []struct {
+		name     string
+		required bool
+		version  int64
+		wantCode codes.Code
+	}{
+		{name: "required and missing", required: true, version: 0, wantCode: codes.FailedPrecondition},
+		{name: "required and given", required: true, version: 3, wantCode: codes.OK},
+		{name: "optional and missing", required: false, version: 0, wantCode: codes.OK},
+		{name: "optional and given", required: false, version: 3, wantCode: codes.OK},
+	}
+	for _, tt := range tests {
+		for method, send := range calls {
+			t.Run(tt.name+" "+method, func(t *testing.T) {
+				stored := &versionedUsers{}
+				services.UserServ, requireVersion = stored, tt.required
+
+				err := send(tt.version)
+				if code := status.Code(err); code != tt.wantCode {
+					t.Fatalf("got code %v, want %v", code, tt.wantCode)
+				}
+				if tt.wantCode != codes.OK {
+					if len(stored.versions) != 0 {
+						t.Errorf("service called with versions %v, want no call", stored.versions)
+					}
+					return
+				}
+				if len(stored.versions) != 1 || stored.versions[0] != int(tt.version) {
+					t.Errorf("service called with versions %v, want [%d]", stored.versions, tt.version)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"strconv"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/publishers"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"go.uber.org/zap"
+)
+
+var (
+	// EventsServ of type EventsInterface derived from EventsService struct
+	EventsServ EventsInterface = &EventsService{}
+)
+
+// EventFilter selects events by type and by the status they leave the user in, an empty set
+// selecting any
+type EventFilter struct {
+	Types    map[string]bool
+	Statuses map[string]bool
+}
+
+// Match reports whether the event of msg is selected
+func (f EventFilter) Match(msg publishers.Message) bool {
+	if len(f.Types) > 0 && !f.Types[msg.Type] {
+		return false
+	}
+	return len(f.Statuses) == 0 || f.Statuses[users.EventStatus(msg.Type, msg.Payload)]
+}
+
+// EventsService struct, streaming the domain events kept in Stream
+type EventsService struct {
+	Stream *publishers.Stream
+}
+
+// NewEventsService returns an events service reading from stream
+func NewEventsService(stream *publishers.Stream) *EventsService {
+	return &EventsService{Stream: stream}
+}
+
+// EventsInterface describes methods to be implemented
+type EventsInterface interface {
+	Subscribe(int64, EventFilter) ([]publishers.Message, *publishers.Subscription, *errors.RestErr)
+}
+
+// Subscribe returns the events selected by filter published after lastID, and a subscription to
+// the next ones. A lastID of 0 subscribes to the next events only.
+func (s *EventsService) Subscribe(lastID int64, filter EventFilter) ([]publishers.Message, *publishers.Subscription, *errors.RestErr) {
+	backlog, sub, err := s.Stream.Subscribe(lastID, filter.Match)
+	if err == publishers.ErrNotRetained {
+		return nil, nil, errors.NewGoneError(fmt.Sprintf("events after %d are no longer retained, resync and subscribe again without Last-Event-ID", lastID))
+	}
+	if err != nil {
+		logger.Error("failed to subscribe to events: ", err)
+		return nil, nil, errors.NewInternalServerError("failed to subscribe to events")
+	}
+	return backlog, sub, nil
+}
+
+// StartEventStream feeds stream with the domain events published by the relay of any replica,
+// after loading the last retained ones published before
+func StartEventStream(stream *publishers.Stream, retained int) error {
+	if err := users.ListenPublished(func(ids []int64) {
+		if ids == nil {
+			// notifications were missed while reconnecting, catch up from the outbox
+			loadLastPublished(stream, retained)
+			return
+		}
+		msgs, err := users.FindPublished(context.Background(), ids)
+		if err != nil {
+			logger.Info("failed to load published events", zap.Int("count", len(ids)), zap.String("error", err.Message))
+			return
+		}
+		streamMessages(stream, msgs)
+	}); err != nil {
+		return goerrors.New(err.Message)
+	}
+	loadLastPublished(stream, retained)
+	return nil
+}
+
+func loadLastPublished(stream *publishers.Stream, retained int) {
+	msgs, err := users.FindLastPublished(context.Background(), retained)
+	if err != nil {
+		logger.Info("failed to load last published events", zap.String("error", err.Message))
+		return
+	}
+	streamMessages(stream, msgs)
+}
+
+func streamMessages(stream *publishers.Stream, msgs users.OutboxMessages) {
+	for _, m := range msgs {
+		stream.Publish(context.Background(), streamMessage(m))
+		if m.EventType == users.EventUserErased {
+			redactStream(stream, strconv.Itoa(m.AggregateID))
+		}
+	}
+}
+
+// redactStream reloads the retained events of an erased user, whose payloads the erasure
+// redacted in the outbox. Those that cannot be reloaded are evicted.
+func redactStream(stream *publishers.Stream, key string) {
+	ids := stream.Keyed(key)
+	msgs, err := users.FindPublished(context.Background(), ids)
+	if err != nil {
+		logger.Info("failed to reload events of an erased user, evicting them", zap.String("key", key), zap.String("error", err.Message))
+		stream.Evict(ids)
+		return
+	}
+	redacted := make([]publishers.Message, len(msgs))
+	reloaded := make(map[int64]bool, len(msgs))
+	for i, m := range msgs {
+		redacted[i] = streamMessage(m)
+		reloaded[m.ID] = true
+	}
+	stream.Replace(redacted)
+
+	var missing []int64
+	for _, id := range ids {
+		if !reloaded[id] {
+			missing = append(missing, id)
+		}
+	}
+	stream.Evict(missing)
+}
+
+func streamMessage(m *users.OutboxMessage) publishers.Message {
+	return publishers.Message{
+		ID:         m.ID,
+		Type:       m.EventType,
+		Key:        strconv.Itoa(m.AggregateID),
+		Payload:    m.Payload,
+		OccurredAt: m.DateCreated,
+	}
+}
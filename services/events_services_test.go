@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/publishers"
+)
+
+func eventMessage(t *testing.T, id int64, event users.DomainEvent) publishers.Message {
+	t.Helper()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("encoding event: %v", err)
+	}
+	return publishers.Message{ID: id, Type: event.EventType(), Payload: payload}
+}
+
+func stringSet(values ...string) map[string]bool {
+	result := make(map[string]bool, len(values))
+	for _, v := range values {
+		result[v] = true
+	}
+	return result
+}
+
+func TestEventFilterMatch(t *testing.T) {
+	created := eventMessage(t, 1, users.UserCreated{UserID: 1, Status: users.StatusPending})
+	activated := eventMessage(t, 2, users.UserStatusChanged{UserID: 1, Action: users.ActionActivate, From: users.StatusPending, To: users.StatusActive})
+	deleted := eventMessage(t, 3, users.UserDeleted{UserID: 1})
+	updated := eventMessage(t, 4, users.UserUpdated{UserID: 1})
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   []int64
+	}{
+		{name: "any", want: []int64{1, 2, 3, 4}},
+		{name: "by type", filter: EventFilter{Types: stringSet(users.EventUserCreated, users.EventUserUpdated)}, want: []int64{1, 4}},
+		{name: "by status", filter: EventFilter{Statuses: stringSet(users.StatusActive, users.StatusDeleted)}, want: []int64{2, 3}},
+		{name: "by status left in on creation", filter: EventFilter{Statuses: stringSet(users.StatusPending)}, want: []int64{1}},
+		{name: "by type and status", filter: EventFilter{Types: stringSet(users.EventUserStatusChanged), Statuses: stringSet(users.StatusDeleted, users.StatusActive)}, want: []int64{2}},
+		{name: "status of events without one", filter: EventFilter{Statuses: stringSet(users.StatusActive)}, want: []int64{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []int64{}
+			for _, msg := range []publishers.Message{created, activated, deleted, updated} {
+				if tt.filter.Match(msg) {
+					got = append(got, msg.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matched %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventsSubscribe(t *testing.T) {
+	stream := publishers.NewStream(2, 4)
+	s := NewEventsService(stream)
+	for _, msg := range []publishers.Message{
+		eventMessage(t, 1, users.UserCreated{UserID: 1, Status: users.StatusActive}),
+		eventMessage(t, 2, users.UserUpdated{UserID: 1}),
+		eventMessage(t, 3, users.UserDeleted{UserID: 1}),
+	} {
+		stream.Publish(context.Background(), msg)
+	}
+
+	tests := []struct {
+		name   string
+		lastID int64
+		filter EventFilter
+		want   []int64
+		status int
+	}{
+		{name: "resume", lastID: 2, want: []int64{3}},
+		{name: "resume filtered", lastID: 2, filter: EventFilter{Types: stringSet(users.EventUserUpdated)}, want: []int64{}},
+		{name: "resume after a dropped event", lastID: 1, status: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backlog, sub, err := s.Subscribe(tt.lastID, tt.filter)
+			if err != nil {
+				if err.Status != tt.status {
+					t.Fatalf("got %d %s, want status %d", err.Status, err.Message, tt.status)
+				}
+				return
+			}
+			defer sub.Close()
+			got := []int64{}
+			for _, msg := range backlog {
+				got = append(got, msg.ID)
+			}
+			if tt.status != 0 || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("backlog %v, want %v or status %d", got, tt.want, tt.status)
+			}
+		})
+	}
+}
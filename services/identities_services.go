@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/oidc"
+)
+
+var (
+	// IdentityServ of type IdentityInterface derived from IdentityService struct
+	IdentityServ IdentityInterface = &IdentityService{}
+)
+
+// IdentityService struct
+type IdentityService struct{}
+
+// IdentityInterface describes methods to be implemented
+type IdentityInterface interface {
+	BeginLogin(context.Context, string) (string, *errors.RestErr)
+	BeginLink(context.Context, int, string, users.Caller) (string, *errors.RestErr)
+	CompleteLogin(context.Context, string, string, string, users.Caller) (*users.User, *errors.RestErr)
+	GetIdentities(context.Context, int, users.Caller) (users.Identities, *errors.RestErr)
+	UnlinkIdentity(context.Context, int, string, users.Caller) *errors.RestErr
+}
+
+func getProvider(name string) (*oidc.Provider, *errors.RestErr) {
+	p, ok := oidc.GetProvider(name)
+	if !ok {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("identity provider %s not configured", name))
+	}
+	return p, nil
+}
+
+func authCodeURL(ctx context.Context, p *oidc.Provider, userID int) (string, *errors.RestErr) {
+	state, nonce := oidc.NewState(p.Name, userID)
+	u, err := p.AuthCodeURL(ctx, state, nonce)
+	if err != nil {
+		logger.Error("failed to build authorization url: ", err)
+		return "", errors.NewInternalServerError("identity provider unavailable")
+	}
+	return u, nil
+}
+
+// BeginLogin returns the provider url to redirect to for signing in
+func (s *IdentityService) BeginLogin(ctx context.Context, provider string) (string, *errors.RestErr) {
+	p, err := getProvider(provider)
+	if err != nil {
+		return "", err
+	}
+	return authCodeURL(ctx, p, 0)
+}
+
+// BeginLink returns the provider url to redirect to for linking an identity to an existing user.
+// Only the user, signed in, may link identities to itself.
+func (s *IdentityService) BeginLink(ctx context.Context, userID int, provider string, caller users.Caller) (string, *errors.RestErr) {
+	if err := checkOwner(userID, caller); err != nil {
+		return "", err
+	}
+	p, err := getProvider(provider)
+	if err != nil {
+		return "", err
+	}
+	if _, err := UserServ.GetUser(ctx, userID, false); err != nil {
+		return "", err
+	}
+	return authCodeURL(ctx, p, userID)
+}
+
+// CompleteLogin handles the provider callback, either signing the user in or linking the identity
+func (s *IdentityService) CompleteLogin(ctx context.Context, provider, state, code string, caller users.Caller) (user *users.User, err *errors.RestErr) {
+	linking := false
+	defer func() {
+		if !linking {
+			recordLogin(methodOIDC, err)
+		}
+	}()
+
+	p, err := getProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	st, ok := oidc.ConsumeState(state)
+	if !ok || st.Provider != p.Name {
+		return nil, errors.NewUnauthorizedError("invalid or expired state")
+	}
+	// the state was handed to the signed in owner of st.UserID by BeginLink, and the browser
+	// returning from the provider carries no access token
+	linking = st.UserID != 0
+
+	claims, exErr := p.Exchange(ctx, code, st.Nonce)
+	if exErr != nil {
+		logger.Error("oidc code exchange failed: ", exErr)
+		return nil, errors.NewUnauthorizedError("failed to verify identity")
+	}
+
+	identity := &users.Identity{}
+	findErr := identity.FindByProviderSubject(ctx, p.Name, claims.Subject)
+	if findErr != nil && findErr.Error != "not_found" {
+		return nil, findErr
+	}
+	linked := findErr == nil
+
+	if st.UserID != 0 {
+		if linked {
+			if identity.UserID != st.UserID {
+				return nil, errors.NewConflictError(fmt.Sprintf("%s identity already linked to another user", p.Name))
+			}
+			return UserServ.GetUser(ctx, st.UserID, false)
+		}
+		return s.link(ctx, p, claims, st.UserID)
+	}
+
+	if linked {
+		user, err := UserServ.GetUser(ctx, identity.UserID, false)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCanLogin(user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	email := strings.TrimSpace(strings.ToLower(claims.Email))
+	if p.LinkByEmail && claims.EmailVerified && email != "" {
+		existing := &users.User{}
+		err := existing.FindByEmail(ctx, email)
+		if err == nil {
+			if err := checkCanLogin(existing); err != nil {
+				return nil, err
+			}
+			return s.link(ctx, p, claims, existing.ID)
+		}
+		if err.Error != "not_found" {
+			return nil, err
+		}
+	}
+
+	if !p.AutoProvision {
+		return nil, errors.NewUnauthorizedError(fmt.Sprintf("no user linked to this %s account", p.Name))
+	}
+	if !claims.EmailVerified || email == "" {
+		return nil, errors.NewUnauthorizedError("identity provider did not return a verified email")
+	}
+
+	// provisioned users sign in through the provider, so the password is never handed out. The
+	// provider verified the email, so they are activated right away.
+	caller.Actor = oidcActorPrefix + p.Name
+	err = users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+		created, err := UserServ.CreateUser(ctx, users.User{
+			FirstName: claims.GivenName,
+			LastName:  claims.FamilyName,
+			Email:     email,
+			Password:  crypto.GetRandomToken(32),
+		}, caller)
+		if err != nil {
+			return err
+		}
+		if _, err := UserServ.ChangeStatus(ctx, created.ID, users.ActionActivate, "email verified by "+p.Name, caller); err != nil {
+			return err
+		}
+		user, err = s.link(ctx, p, claims, created.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkOwner returns an error unless the caller is signed in as the user
+func checkOwner(userID int, caller users.Caller) *errors.RestErr {
+	if caller.UserID == 0 {
+		return errors.NewUnauthorizedError("sign in to manage your identities")
+	}
+	if caller.UserID != userID {
+		return errors.NewForbiddenError("identities can only be managed by the user they belong to")
+	}
+	return nil
+}
+
+func (s *IdentityService) link(ctx context.Context, p *oidc.Provider, claims *oidc.Claims, userID int) (*users.User, *errors.RestErr) {
+	identity := &users.Identity{
+		UserID:      userID,
+		Provider:    p.Name,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		DateCreated: dates.GetNowDBString(),
+	}
+	if err := identity.Save(ctx); err != nil {
+		return nil, err
+	}
+	return UserServ.GetUser(ctx, userID, false)
+}
+
+// GetIdentities returns the identities linked to a user, to the user only
+func (s *IdentityService) GetIdentities(ctx context.Context, userID int, caller users.Caller) (users.Identities, *errors.RestErr) {
+	if err := checkOwner(userID, caller); err != nil {
+		return nil, err
+	}
+	if _, err := UserServ.GetUser(ctx, userID, false); err != nil {
+		return nil, err
+	}
+	dao := users.Identity{}
+	return dao.FindByUserID(ctx, userID)
+}
+
+// UnlinkIdentity removes the provider identity from a user, at the request of the user only.
+// The last identity of a user without a usable password is kept, as the user could no longer
+// sign in.
+func (s *IdentityService) UnlinkIdentity(ctx context.Context, userID int, provider string, caller users.Caller) *errors.RestErr {
+	if err := checkOwner(userID, caller); err != nil {
+		return err
+	}
+	hasPwd, err := hasPassword(ctx, userID)
+	if err != nil {
+		return err
+	}
+	identity := &users.Identity{
+		UserID:   userID,
+		Provider: strings.ToLower(provider),
+	}
+	return identity.Delete(ctx, hasPwd)
+}
+
+// hasPassword reports whether the user can sign in with a password. Users provisioned on their
+// first sign in through a provider were given a random one that is never handed out.
+func hasPassword(ctx context.Context, userID int) (bool, *errors.RestErr) {
+	user := &users.User{ID: userID}
+	actor, err := user.FindSignupActor(ctx)
+	if err != nil {
+		if err.Error == "not_found" {
+			return true, nil
+		}
+		return false, err
+	}
+	return !strings.HasPrefix(actor, oidcActorPrefix), nil
+}
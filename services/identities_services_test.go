@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/oidc"
+	"github.com/sauravgsh16/bookstore_users-api/utils/oidc/oidctest"
+)
+
+var providerCount int
+
+// newTestProvider registers a provider backed by a stub server, which the caller must close
+func newTestProvider(t *testing.T, autoProvision bool) (*oidc.Provider, *oidctest.Server) {
+	t.Helper()
+	srv := oidctest.NewServer("client", "secret")
+	providerCount++
+	p := &oidc.Provider{
+		Name:          fmt.Sprintf("stub%d", providerCount),
+		Issuer:        srv.URL,
+		ClientID:      srv.ClientID,
+		ClientSecret:  srv.ClientSecret,
+		RedirectURL:   "http://localhost/callback",
+		AutoProvision: autoProvision,
+	}
+	if err := oidc.Register(p); err != nil {
+		srv.Close()
+		t.Fatalf("registering provider: %v", err)
+	}
+	return p, srv
+}
+
+// signInAt runs the authorization step at the provider for the url returned by a service call
+func signInAt(t *testing.T, srv *oidctest.Server, authURL string, user oidctest.User) (code, state string) {
+	t.Helper()
+	code, state, err := srv.Authorize(authURL, user)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	return code, state
+}
+
+func TestBeginLinkRequiresOwner(t *testing.T) {
+	p, srv := newTestProvider(t, false)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		caller     users.Caller
+		wantStatus int
+	}{
+		{"anonymous", users.Caller{Actor: "anonymous"}, http.StatusUnauthorized},
+		{"another user", users.Caller{Actor: "mallory", UserID: 2}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := IdentityServ.BeginLink(context.Background(), 1, p.Name, tt.caller)
+			if err == nil || err.Status != tt.wantStatus {
+				t.Fatalf("BeginLink error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestManageIdentitiesRequiresOwner(t *testing.T) {
+	tests := []struct {
+		name       string
+		caller     users.Caller
+		wantStatus int
+	}{
+		{"anonymous", users.Caller{Actor: "anonymous"}, http.StatusUnauthorized},
+		{"another user", users.Caller{Actor: "mallory", UserID: 2}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := IdentityServ.GetIdentities(context.Background(), 1, tt.caller); err == nil || err.Status != tt.wantStatus {
+				t.Errorf("GetIdentities error = %v, want status %d", err, tt.wantStatus)
+			}
+			if err := IdentityServ.UnlinkIdentity(context.Background(), 1, "google", tt.caller); err == nil || err.Status != tt.wantStatus {
+				t.Errorf("UnlinkIdentity error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCompleteLoginRejects(t *testing.T) {
+	p, srv := newTestProvider(t, true)
+	defer srv.Close()
+	other, otherSrv := newTestProvider(t, true)
+	defer otherSrv.Close()
+	alice := oidctest.User{Subject: "alice", Email: "alice@test.invalid", EmailVerified: true}
+
+	tests := []struct {
+		name string
+		// start returns the code and state the provider redirected back with
+		start      func() (code, state string)
+		wantStatus int
+	}{
+		{
+			name:       "unknown state",
+			start:      func() (string, string) { return srv.NewCode(srv.Sign(srv.Claims(alice, ""))), "unknown" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "state of another provider",
+			start: func() (string, string) {
+				state, _ := oidc.NewState(other.Name, 0)
+				return srv.NewCode(srv.Sign(srv.Claims(alice, ""))), state
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "nonce of another request",
+			start: func() (string, string) {
+				state, _ := oidc.NewState(p.Name, 0)
+				return srv.NewCode(srv.Sign(srv.Claims(alice, "other"))), state
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "replayed state",
+			start: func() (string, string) {
+				state, _ := oidc.NewState(p.Name, 0)
+				oidc.ConsumeState(state)
+				return srv.NewCode(srv.Sign(srv.Claims(alice, ""))), state
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, state := tt.start()
+			user, err := IdentityServ.CompleteLogin(context.Background(), p.Name, state, code, users.Caller{Actor: "anonymous"})
+			if err == nil || err.Status != tt.wantStatus {
+				t.Fatalf("CompleteLogin = %v, %v, want status %d", user, err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestProviderSignIn(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	anonymous := users.Caller{Actor: "anonymous"}
+
+	t.Run("auto provisioning then login", func(t *testing.T) {
+		p, srv := newTestProvider(t, true)
+		defer srv.Close()
+		bob := oidctest.User{Subject: "bob", Email: uniqueEmail("bob"), EmailVerified: true, GivenName: "Bob"}
+
+		authURL, err := IdentityServ.BeginLogin(ctx, p.Name)
+		if err != nil {
+			t.Fatalf("BeginLogin: %v", err.Message)
+		}
+		code, state := signInAt(t, srv, authURL, bob)
+		provisioned, err := IdentityServ.CompleteLogin(ctx, p.Name, state, code, anonymous)
+		if err != nil {
+			t.Fatalf("CompleteLogin provisioning: %v", err.Message)
+		}
+		if provisioned.Email != bob.Email || provisioned.FirstName != bob.GivenName {
+			t.Errorf("provisioned user = %+v, want the claims of %+v", provisioned, bob)
+		}
+		if provisioned.Status != users.StatusActive {
+			t.Errorf("provisioned user is %s, want active as the provider verified the email", provisioned.Status)
+		}
+
+		authURL, _ = IdentityServ.BeginLogin(ctx, p.Name)
+		code, state = signInAt(t, srv, authURL, bob)
+		user, err := IdentityServ.CompleteLogin(ctx, p.Name, state, code, anonymous)
+		if err != nil {
+			t.Fatalf("CompleteLogin login: %v", err.Message)
+		}
+		if user.ID != provisioned.ID {
+			t.Errorf("login returned user %d, want the provisioned user %d", user.ID, provisioned.ID)
+		}
+
+		if err := IdentityServ.UnlinkIdentity(ctx, user.ID, p.Name, users.Caller{Actor: "bob", UserID: user.ID}); err == nil || err.Status != http.StatusConflict {
+			t.Errorf("unlinking the only identity of a provisioned user = %v, want a conflict", err)
+		}
+	})
+
+	t.Run("unknown identity without auto provisioning", func(t *testing.T) {
+		p, srv := newTestProvider(t, false)
+		defer srv.Close()
+
+		authURL, _ := IdentityServ.BeginLogin(ctx, p.Name)
+		code, state := signInAt(t, srv, authURL, oidctest.User{Subject: "carol", Email: uniqueEmail("carol"), EmailVerified: true})
+		if _, err := IdentityServ.CompleteLogin(ctx, p.Name, state, code, anonymous); err == nil || err.Status != http.StatusUnauthorized {
+			t.Errorf("CompleteLogin = %v, want unauthorized", err)
+		}
+	})
+
+	t.Run("linking by the signed in owner", func(t *testing.T) {
+		p, srv := newTestProvider(t, false)
+		defer srv.Close()
+		owner, err := UserServ.CreateUser(ctx, users.User{FirstName: "Dave", Email: uniqueEmail("dave"), Password: "secret"}, anonymous)
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err.Message)
+		}
+		if _, err := UserServ.ChangeStatus(ctx, owner.ID, users.ActionActivate, "", anonymous); err != nil {
+			t.Fatalf("activating: %v", err.Message)
+		}
+		caller := users.Caller{Actor: "dave", UserID: owner.ID}
+
+		authURL, err := IdentityServ.BeginLink(ctx, owner.ID, p.Name, caller)
+		if err != nil {
+			t.Fatalf("BeginLink: %v", err.Message)
+		}
+		dave := oidctest.User{Subject: "dave", Email: "dave@elsewhere.invalid", EmailVerified: true}
+		code, state := signInAt(t, srv, authURL, dave)
+		// the browser returns from the provider without the access token of the owner
+		if _, err := IdentityServ.CompleteLogin(ctx, p.Name, state, code, anonymous); err != nil {
+			t.Fatalf("CompleteLogin link: %v", err.Message)
+		}
+
+		authURL, _ = IdentityServ.BeginLogin(ctx, p.Name)
+		code, state = signInAt(t, srv, authURL, dave)
+		user, err := IdentityServ.CompleteLogin(ctx, p.Name, state, code, anonymous)
+		if err != nil {
+			t.Fatalf("CompleteLogin with the linked identity: %v", err.Message)
+		}
+		if user.ID != owner.ID {
+			t.Errorf("linked identity signed in user %d, want %d", user.ID, owner.ID)
+		}
+
+		if err := IdentityServ.UnlinkIdentity(ctx, owner.ID, p.Name, caller); err != nil {
+			t.Errorf("unlinking the identity of a user with a password: %v", err.Message)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+)
+
+// dbErr is why the users database could not be opened, tests needing it being skipped then
+var dbErr error
+
+func TestMain(m *testing.M) {
+	dbErr = usersdb.Open()
+	os.Exit(m.Run())
+}
+
+// requireDB skips tb unless the users database, migrated to the latest schema, is reachable
+func requireDB(tb testing.TB) {
+	tb.Helper()
+	if dbErr != nil {
+		tb.Skipf("users database unavailable: %v", dbErr)
+	}
+}
+
+// uniqueEmail returns an email no other test run uses
+func uniqueEmail(name string) string {
+	return fmt.Sprintf("%s.%d@test.invalid", name, time.Now().UnixNano())
+}
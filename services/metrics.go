@@ -0,0 +1,41 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/metrics"
+)
+
+const (
+	methodPassword = "password"
+	methodOIDC     = "oidc"
+
+	// oidcActorPrefix starts the actor of changes made on behalf of an identity provider
+	oidcActorPrefix = "oidc:"
+)
+
+var (
+	signups = metrics.NewCounter("users_signups_total", "Users who signed up, by method", "method")
+	logins  = metrics.NewCounter("users_logins_total", "Logins, by method and result", "method", "result")
+)
+
+// signupMethod tells how the user created by caller signed up
+func signupMethod(caller users.Caller) string {
+	if strings.HasPrefix(caller.Actor, oidcActorPrefix) {
+		return methodOIDC
+	}
+	return methodPassword
+}
+
+// recordLogin counts a login by method which err failed, ignoring those failing on server errors
+// as they say nothing of the credentials
+func recordLogin(method string, err *errors.RestErr) {
+	switch {
+	case err == nil:
+		logins.Inc(method, "succeeded")
+	case err.Status < 500:
+		logins.Inc(method, "failed")
+	}
+}
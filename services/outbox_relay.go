@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/publishers"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"go.uber.org/zap"
+)
+
+var (
+	outboxMaxAttempts    = config.GetInt("OUTBOX_MAX_ATTEMPTS", 10)
+	outboxRetryBase      = config.GetDuration("OUTBOX_RETRY_BASE", 5*time.Second)
+	outboxRetryMax       = config.GetDuration("OUTBOX_RETRY_MAX", time.Hour)
+	outboxPublishTimeout = config.GetDuration("OUTBOX_PUBLISH_TIMEOUT", 30*time.Second)
+	// outboxLease is how long claimed messages are kept from other relays, a batch is given
+	// half of it to be published
+	outboxLease = config.GetDuration("OUTBOX_LEASE", 5*time.Minute)
+)
+
+// StartOutboxRelay periodically publishes pending domain events from the outbox
+func StartOutboxRelay(p publishers.Publisher, interval time.Duration, batchSize int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// keep draining while full batches are being claimed
+			for relayOutbox(p, batchSize) == batchSize {
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// relayOutbox claims a batch of messages, publishes it without holding a transaction and
+// records the outcome. It returns the number of messages claimed.
+func relayOutbox(p publishers.Publisher, batchSize int) int {
+	now := dates.GetNow()
+	lease := now.Add(outboxLease)
+
+	msgs, err := users.ClaimOutbox(context.Background(), dates.GetDBString(now), dates.GetDBString(lease), batchSize)
+	if err != nil {
+		logger.Info("failed to claim outbox messages", zap.String("error", err.Message))
+		return 0
+	}
+	if len(msgs) == 0 {
+		return 0
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(outboxLease/2))
+	published, failed, released := publishInOrder(ctx, p, msgs)
+	cancel()
+
+	if err := users.RecordRelay(context.Background(), published, failed, released); err != nil {
+		// the messages are claimed again once their lease expires
+		logger.Info("failed to record relayed outbox messages", zap.String("error", err.Message))
+	}
+	return len(msgs)
+}
+
+// publishInOrder publishes msgs in order. Once a message for a user fails, later messages for
+// that user are released to keep ordering, as are the messages left when ctx is done. Failed
+// messages are scheduled for a retry, or dead lettered once they run out of attempts.
+func publishInOrder(ctx context.Context, p publishers.Publisher, msgs users.OutboxMessages) ([]int64, users.OutboxMessages, []int64) {
+	var (
+		published, released []int64
+		failed              users.OutboxMessages
+	)
+	blocked := make(map[int]bool)
+
+	for _, m := range msgs {
+		if blocked[m.AggregateID] || ctx.Err() != nil {
+			released = append(released, m.ID)
+			continue
+		}
+
+		publishCtx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+		err := p.Publish(publishCtx, publishers.Message{
+			ID:         m.ID,
+			Type:       m.EventType,
+			Key:        strconv.Itoa(m.AggregateID),
+			Payload:    m.Payload,
+			OccurredAt: m.DateCreated,
+		})
+		cancel()
+
+		if err != nil {
+			blocked[m.AggregateID] = true
+			if ctx.Err() != nil {
+				released = append(released, m.ID)
+				continue
+			}
+			logger.Error("failed to publish domain event: ", err, zap.Int64("id", m.ID), zap.Int("attempts", m.Attempts+1))
+			scheduleRetry(m, err)
+			failed = append(failed, m)
+			continue
+		}
+		published = append(published, m.ID)
+	}
+	return published, failed, released
+}
+
+// scheduleRetry counts the failed attempt of m and sets when it is tried next, or moves it to
+// the dead letter once it reached outboxMaxAttempts
+func scheduleRetry(m *users.OutboxMessage, err error) {
+	m.Attempts++
+	m.LastError = err.Error()
+	if m.Attempts >= outboxMaxAttempts {
+		m.NextAttemptAt = ""
+		m.DateDead = dates.GetNowDBString()
+		logger.Info("outbox message moved to dead letter", zap.Int64("id", m.ID), zap.Int("attempts", m.Attempts))
+		return
+	}
+	m.NextAttemptAt = dates.GetDBString(dates.GetNow().Add(retryBackoff(m.Attempts, outboxRetryBase, outboxRetryMax)))
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/publishers"
+)
+
+// failingPublisher fails the messages whose id is in fail and records the ids of the others
+type failingPublisher struct {
+	fail      map[int64]bool
+	published []int64
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, msg publishers.Message) error {
+	if p.fail[msg.ID] {
+		return fmt.Errorf("consumer unavailable")
+	}
+	p.published = append(p.published, msg.ID)
+	return nil
+}
+
+func outboxMessages(attempts int, aggregates ...int) users.OutboxMessages {
+	msgs := make(users.OutboxMessages, len(aggregates))
+	for i, aggregate := range aggregates {
+		msgs[i] = &users.OutboxMessage{ID: int64(i + 1), AggregateID: aggregate, EventType: "user.updated", Attempts: attempts}
+	}
+	return msgs
+}
+
+func TestPublishInOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		msgs      users.OutboxMessages
+		fail      map[int64]bool
+		cancelled bool
+
+		published []int64
+		failed    []int64
+		released  []int64
+		dead      bool
+	}{
+		{
+			name:      "all published",
+			msgs:      outboxMessages(0, 1, 2, 1),
+			published: []int64{1, 2, 3},
+		},
+		{
+			name:      "failure holds back the later messages of the user",
+			msgs:      outboxMessages(0, 1, 2, 1),
+			fail:      map[int64]bool{1: true},
+			published: []int64{2},
+			failed:    []int64{1},
+			released:  []int64{3},
+		},
+		{
+			name:      "failure does not hold back other users",
+			msgs:      outboxMessages(0, 1, 2, 1),
+			fail:      map[int64]bool{2: true},
+			published: []int64{1, 3},
+			failed:    []int64{2},
+		},
+		{
+			name:   "last attempt moves to dead letter",
+			msgs:   outboxMessages(outboxMaxAttempts-1, 1),
+			fail:   map[int64]bool{1: true},
+			failed: []int64{1},
+			dead:   true,
+		},
+		{
+			name:      "batch deadline releases the messages left",
+			msgs:      outboxMessages(0, 1, 2),
+			cancelled: true,
+			released:  []int64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+			attempts := make(map[int64]int, len(tt.msgs))
+			for _, m := range tt.msgs {
+				attempts[m.ID] = m.Attempts
+			}
+
+			p := &failingPublisher{fail: tt.fail}
+			published, failed, released := publishInOrder(ctx, p, tt.msgs)
+
+			if !reflect.DeepEqual(published, tt.published) || !reflect.DeepEqual(p.published, tt.published) {
+				t.Errorf("published %v, publisher got %v, want %v", published, p.published, tt.published)
+			}
+			if !reflect.DeepEqual(released, tt.released) {
+				t.Errorf("released %v, want %v", released, tt.released)
+			}
+			var failedIDs []int64
+			for _, m := range failed {
+				failedIDs = append(failedIDs, m.ID)
+				if m.Attempts != attempts[m.ID]+1 || m.LastError == "" {
+					t.Errorf("message %d has attempts %d and error %q, want %d and the error", m.ID, m.Attempts, m.LastError, attempts[m.ID]+1)
+				}
+				if dead := m.DateDead != ""; dead != tt.dead || dead == (m.NextAttemptAt != "") {
+					t.Errorf("message %d dead at %q with next attempt at %q, want dead %v", m.ID, m.DateDead, m.NextAttemptAt, tt.dead)
+				}
+			}
+			if !reflect.DeepEqual(failedIDs, tt.failed) {
+				t.Errorf("failed %v, want %v", failedIDs, tt.failed)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	base, max := time.Second, time.Minute
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 3, want: 4 * time.Second},
+		{attempts: 6, want: 32 * time.Second},
+		{attempts: 7, want: time.Minute},
+		{attempts: 64, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.attempts), func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if got := retryBackoff(tt.attempts, base, max); got < tt.want || got > tt.want+tt.want/10 {
+					t.Fatalf("retryBackoff(%d) = %v, want %v with up to 10%% jitter", tt.attempts, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sauravgsh16/bookstore_users-api/blobs"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/images"
+)
+
+var (
+	// ProfileServ of type ProfileInterface derived from ProfileService struct
+	ProfileServ ProfileInterface = &ProfileService{}
+
+	// MaxAvatarBytes is the largest avatar upload accepted
+	MaxAvatarBytes = int64(config.GetInt("USERS_AVATAR_MAX_BYTES", 2<<20))
+	maxAvatarSide  = config.GetInt("USERS_AVATAR_MAX_SIDE", 4096)
+	thumbnailSide  = config.GetInt("USERS_AVATAR_THUMBNAIL_SIDE", 128)
+)
+
+// ProfileService struct, avatars are kept in Blobs
+type ProfileService struct {
+	Blobs blobs.Store
+}
+
+// NewProfileService returns a profile service keeping avatars in store
+func NewProfileService(store blobs.Store) *ProfileService {
+	return &ProfileService{Blobs: store}
+}
+
+// ProfileInterface describes methods to be implemented
+type ProfileInterface interface {
+	GetProfile(context.Context, int) (*users.Profile, *errors.RestErr)
+	UpdateProfile(context.Context, users.Profile, bool) (*users.Profile, *errors.RestErr)
+	GetAddresses(context.Context, int) (users.Addresses, *errors.RestErr)
+	GetAddress(context.Context, int, int) (*users.Address, *errors.RestErr)
+	CreateAddress(context.Context, users.Address) (*users.Address, *errors.RestErr)
+	UpdateAddress(context.Context, users.Address) (*users.Address, *errors.RestErr)
+	DeleteAddress(context.Context, int, int) *errors.RestErr
+	SetAvatar(context.Context, int, []byte) (*users.Profile, *errors.RestErr)
+	GetAvatar(context.Context, int, bool) (io.ReadCloser, string, *errors.RestErr)
+	DeleteAvatar(context.Context, int) *errors.RestErr
+	ReadAvatar(context.Context, int) ([]byte, *errors.RestErr)
+	RemoveAvatarBlobs(context.Context, int) *errors.RestErr
+}
+
+func avatarKey(userID int) string {
+	return fmt.Sprintf("avatars/%d/original", userID)
+}
+
+func thumbnailKey(userID int) string {
+	return fmt.Sprintf("avatars/%d/thumbnail", userID)
+}
+
+// thumbnailType keeps jpeg thumbnails for jpeg avatars, others are png
+func thumbnailType(avatarType string) string {
+	if avatarType == "image/jpeg" {
+		return avatarType
+	}
+	return "image/png"
+}
+
+// GetProfile returns the profile of a user, empty if it was never filled in
+func (s *ProfileService) GetProfile(ctx context.Context, userID int) (*users.Profile, *errors.RestErr) {
+	if _, err := UserServ.GetUser(ctx, userID, false); err != nil {
+		return nil, err
+	}
+	return s.loadProfile(ctx, userID)
+}
+
+func (s *ProfileService) loadProfile(ctx context.Context, userID int) (*users.Profile, *errors.RestErr) {
+	p := &users.Profile{UserID: userID}
+	if err := p.Get(ctx); err != nil && err.Error != "not_found" {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpdateProfile replaces the profile fields, or only the non empty ones if isPatch is set
+func (s *ProfileService) UpdateProfile(ctx context.Context, p users.Profile, isPatch bool) (*users.Profile, *errors.RestErr) {
+	current, err := s.GetProfile(ctx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if isPatch {
+		if p.Phone != "" {
+			current.Phone = p.Phone
+		}
+		if p.DateOfBirth != "" {
+			current.DateOfBirth = p.DateOfBirth
+		}
+		if p.Locale != "" {
+			current.Locale = p.Locale
+		}
+		if p.Timezone != "" {
+			current.Timezone = p.Timezone
+		}
+	} else {
+		current.Phone = p.Phone
+		current.DateOfBirth = p.DateOfBirth
+		current.Locale = p.Locale
+		current.Timezone = p.Timezone
+	}
+
+	if err := current.Validate(); err != nil {
+		return nil, err
+	}
+	current.DateUpdated = dates.GetNowDBString()
+	if err := current.Save(ctx); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// GetAddresses returns the addresses of a user
+func (s *ProfileService) GetAddresses(ctx context.Context, userID int) (users.Addresses, *errors.RestErr) {
+	if _, err := UserServ.GetUser(ctx, userID, false); err != nil {
+		return nil, err
+	}
+	dao := users.Address{}
+	return dao.FindByUserID(ctx, userID)
+}
+
+// GetAddress returns an address of a user
+func (s *ProfileService) GetAddress(ctx context.Context, userID, addressID int) (*users.Address, *errors.RestErr) {
+	if _, err := UserServ.GetUser(ctx, userID, false); err != nil {
+		return nil, err
+	}
+	addr := &users.Address{ID: addressID, UserID: userID}
+	if err := addr.Get(ctx); err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
+
+// CreateAddress adds an address to a user
+func (s *ProfileService) CreateAddress(ctx context.Context, a users.Address) (*users.Address, *errors.RestErr) {
+	if _, err := UserServ.GetUser(ctx, a.UserID, false); err != nil {
+		return nil, err
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	a.ID = 0
+	a.DateCreated = dates.GetNowDBString()
+	if err := a.Save(ctx); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// UpdateAddress replaces an address of a user
+func (s *ProfileService) UpdateAddress(ctx context.Context, a users.Address) (*users.Address, *errors.RestErr) {
+	if _, err := UserServ.GetUser(ctx, a.UserID, false); err != nil {
+		return nil, err
+	}
+	if err := a.Validate(); err != nil {
+		return nil, err
+	}
+
+	a.DateUpdated = dates.GetNowDBString()
+	if err := a.Update(ctx); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// DeleteAddress removes an address from a user
+func (s *ProfileService) DeleteAddress(ctx context.Context, userID, addressID int) *errors.RestErr {
+	if _, err := UserServ.GetUser(ctx, userID, false); err != nil {
+		return err
+	}
+	addr := &users.Address{ID: addressID, UserID: userID}
+	return addr.Delete(ctx)
+}
+
+// SetAvatar validates the uploaded image and stores it, stripped of its metadata, along with
+// a thumbnail. A previous avatar is replaced.
+func (s *ProfileService) SetAvatar(ctx context.Context, userID int, data []byte) (*users.Profile, *errors.RestErr) {
+	if _, err := UserServ.GetUser(ctx, userID, false); err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxAvatarBytes {
+		return nil, &errors.RestErr{
+			Message: fmt.Sprintf("avatar must not exceed %d bytes", MaxAvatarBytes),
+			Status:  http.StatusRequestEntityTooLarge,
+			Error:   "request_entity_too_large",
+		}
+	}
+
+	img, original, contentType, err := images.Normalize(data, maxAvatarSide)
+	if err == images.ErrUnsupported {
+		return nil, errors.NewUnsupportedMediaTypeError(err.Error())
+	}
+	if err != nil {
+		return nil, errors.NewBadRequestError(err.Error())
+	}
+	thumbnail, err := images.Encode(images.Thumbnail(img, thumbnailSide), thumbnailType(contentType))
+	if err != nil {
+		logger.Error("failed to encode avatar thumbnail: ", err)
+		return nil, errors.NewInternalServerError("failed to process avatar")
+	}
+
+	if err := s.Blobs.Put(ctx, avatarKey(userID), bytes.NewReader(original)); err != nil {
+		logger.Error("failed to store avatar: ", err)
+		return nil, errors.NewInternalServerError("failed to store avatar")
+	}
+	if err := s.Blobs.Put(ctx, thumbnailKey(userID), bytes.NewReader(thumbnail)); err != nil {
+		logger.Error("failed to store avatar thumbnail: ", err)
+		return nil, errors.NewInternalServerError("failed to store avatar")
+	}
+
+	p := &users.Profile{UserID: userID, AvatarType: contentType, DateUpdated: dates.GetNowDBString()}
+	if err := p.SaveAvatar(ctx); err != nil {
+		return nil, err
+	}
+	return s.loadProfile(ctx, userID)
+}
+
+// GetAvatar returns the avatar of a user, or its thumbnail, and its content type.
+// The caller closes the reader.
+func (s *ProfileService) GetAvatar(ctx context.Context, userID int, thumbnail bool) (io.ReadCloser, string, *errors.RestErr) {
+	p, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !p.HasAvatar() {
+		return nil, "", errors.NewNotFoundError(fmt.Sprintf("user %d has no avatar", userID))
+	}
+
+	key, contentType := avatarKey(userID), p.AvatarType
+	if thumbnail {
+		key, contentType = thumbnailKey(userID), thumbnailType(p.AvatarType)
+	}
+	r, getErr := s.Blobs.Get(ctx, key)
+	if getErr == blobs.ErrNotFound {
+		return nil, "", errors.NewNotFoundError(fmt.Sprintf("user %d has no avatar", userID))
+	}
+	if getErr != nil {
+		logger.Error("failed to read avatar: ", getErr)
+		return nil, "", errors.NewInternalServerError("failed to read avatar")
+	}
+	return r, contentType, nil
+}
+
+// DeleteAvatar removes the avatar of a user
+func (s *ProfileService) DeleteAvatar(ctx context.Context, userID int) *errors.RestErr {
+	p, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !p.HasAvatar() {
+		return errors.NewNotFoundError(fmt.Sprintf("user %d has no avatar", userID))
+	}
+
+	p.AvatarType = ""
+	p.DateUpdated = dates.GetNowDBString()
+	if err := p.SaveAvatar(ctx); err != nil {
+		return err
+	}
+	return s.RemoveAvatarBlobs(ctx, userID)
+}
+
+// RemoveAvatarBlobs deletes the stored avatar images of a user, whatever its state
+func (s *ProfileService) RemoveAvatarBlobs(ctx context.Context, userID int) *errors.RestErr {
+	for _, key := range []string{avatarKey(userID), thumbnailKey(userID)} {
+		if err := s.Blobs.Delete(ctx, key); err != nil {
+			logger.Error("failed to delete avatar: ", err)
+			return errors.NewInternalServerError("failed to delete avatar")
+		}
+	}
+	return nil
+}
+
+// ReadAvatar returns the stored avatar of a user whatever its state, nil if there is none
+func (s *ProfileService) ReadAvatar(ctx context.Context, userID int) ([]byte, *errors.RestErr) {
+	r, err := s.Blobs.Get(ctx, avatarKey(userID))
+	if err == blobs.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("failed to read avatar: ", err)
+		return nil, errors.NewInternalServerError("failed to read avatar")
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		logger.Error("failed to read avatar: ", err)
+		return nil, errors.NewInternalServerError("failed to read avatar")
+	}
+	return data, nil
+}
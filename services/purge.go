@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"go.uber.org/zap"
+)
+
+// StartPurgeJob periodically hard deletes users that have been soft deleted for longer than retention
+func StartPurgeJob(retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purgeDeletedUsers(retention)
+			<-ticker.C
+		}
+	}()
+}
+
+func purgeDeletedUsers(retention time.Duration) {
+	before := dates.GetDBString(dates.GetNow().Add(-retention))
+	ctx := context.Background()
+	ids, err := users.PurgeDeleted(ctx, before)
+	if err != nil {
+		logger.Info("failed to purge deleted users", zap.String("error", err.Message))
+		return
+	}
+	for _, id := range ids {
+		if err := ProfileServ.RemoveAvatarBlobs(ctx, id); err != nil {
+			logger.Info("failed to remove avatar of purged user", zap.Int("user_id", id), zap.String("error", err.Message))
+		}
+	}
+	if len(ids) > 0 {
+		logger.Info("purged deleted users", zap.Int("count", len(ids)))
+	}
+}
@@ -18,7 +18,7 @@ type Resolver struct{}
 
 // UserResolverFunc defines resolver for get user
 func (r *Resolver) UserResolverFunc(p graphql.ResolveParams) (interface{}, error) {
-	user, err := UserServ.GetUser(p.Args["id"].(int))
+	user, err := UserServ.GetUser(p.Context, p.Args["id"].(int), false)
 	if err != nil {
 		return nil, fmt.Errorf(err.Error)
 	}
@@ -27,7 +27,7 @@ func (r *Resolver) UserResolverFunc(p graphql.ResolveParams) (interface{}, error
 
 // UsersResolverFunc defines resolver tp get all users with status
 func (r *Resolver) UsersResolverFunc(p graphql.ResolveParams) (interface{}, error) {
-	users, err := UserServ.SearchUser(p.Args["status"].(string))
+	users, err := UserServ.SearchUser(p.Context, p.Args["status"].(string), false)
 	if err != nil {
 		return nil, fmt.Errorf(err.Error)
 	}
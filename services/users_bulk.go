@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// ImportUsers creates the users of rows, reporting the outcome of each. Rows are validated as
+// POST /users does and emails must be unique within the import and against existing users.
+// A dry run stops after those checks.
+func (s *UserService) ImportUsers(ctx context.Context, rows []users.BulkRow, mode string, dryRun bool, caller users.Caller) (*users.BulkReport, *errors.RestErr) {
+	if !users.IsValidBulkMode(mode) {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("invalid import mode %q", mode))
+	}
+
+	report := &users.BulkReport{
+		DryRun:  dryRun,
+		Mode:    mode,
+		Total:   len(rows),
+		Results: make([]*users.BulkResult, len(rows)),
+	}
+	var (
+		pending []*users.User
+		results []*users.BulkResult
+		seen    = make(map[string]bool, len(rows))
+	)
+	for i := range rows {
+		row := &rows[i]
+		res := &users.BulkResult{Row: row.Row, Email: row.User.Email}
+		report.Results[i] = res
+
+		switch {
+		case row.Error != "":
+			res.Status, res.Error = users.BulkInvalid, row.Error
+		case !row.User.Validate():
+			res.Status, res.Error = users.BulkInvalid, "invalid user data"
+		case seen[row.User.Email]:
+			res.Status, res.Error = users.BulkDuplicate, "email repeated in import"
+		default:
+			seen[row.User.Email] = true
+			res.Email = row.User.Email
+			u := row.User
+			pending = append(pending, &u)
+			results = append(results, res)
+		}
+	}
+
+	if len(pending) > 0 {
+		var err *errors.RestErr
+		switch {
+		case dryRun:
+			err = s.checkImport(ctx, pending, results)
+		case mode == users.BulkModeCopy:
+			err = s.copyImport(ctx, pending, results, caller)
+		default:
+			s.rowImport(ctx, pending, results, caller)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, res := range report.Results {
+		switch res.Status {
+		case users.BulkCreated:
+			report.Created++
+		case users.BulkWouldCreate:
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+func (s *UserService) checkImport(ctx context.Context, pending []*users.User, results []*users.BulkResult) *errors.RestErr {
+	emails := make([]string, len(pending))
+	for i, u := range pending {
+		emails[i] = u.Email
+	}
+	existing, err := users.FindExistingEmails(ctx, emails)
+	if err != nil {
+		return err
+	}
+	for i, u := range pending {
+		if existing[u.Email] {
+			results[i].Status, results[i].Error = users.BulkDuplicate, "email already taken"
+		} else {
+			results[i].Status = users.BulkWouldCreate
+		}
+	}
+	return nil
+}
+
+func (s *UserService) copyImport(ctx context.Context, pending []*users.User, results []*users.BulkResult, caller users.Caller) *errors.RestErr {
+	now := dates.GetNowDBString()
+	for _, u := range pending {
+		u.DateCreated = now
+		u.Status = users.StatusPending
+		u.Password = crypto.GetMd5(u.Password)
+	}
+	if err := users.CopyUsers(ctx, pending, caller); err != nil {
+		return err
+	}
+	for i, u := range pending {
+		if u.ID == 0 {
+			results[i].Status, results[i].Error = users.BulkDuplicate, "email already taken"
+			continue
+		}
+		results[i].Status, results[i].ID = users.BulkCreated, u.ID
+	}
+	return nil
+}
+
+func (s *UserService) rowImport(ctx context.Context, pending []*users.User, results []*users.BulkResult, caller users.Caller) {
+	for i, u := range pending {
+		// imported users did not sign up themselves
+		created, err := s.createUser(ctx, *u, caller)
+		if err != nil {
+			results[i].Status, results[i].Error = users.BulkFailed, err.Message
+			continue
+		}
+		results[i].Status, results[i].ID = users.BulkCreated, created.ID
+	}
+}
+
+// ExportUsers streams every user to fn, see users.ExportUsers
+func (s *UserService) ExportUsers(ctx context.Context, includeDeleted bool, fn func(*users.User) *errors.RestErr) *errors.RestErr {
+	return users.ExportUsers(ctx, includeDeleted, fn)
+}
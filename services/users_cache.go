@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/cache"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// missingUser is cached for ids that do not exist, gob never encodes a user to it
+var missingUser = []byte{0}
+
+// userLoadTimeout bounds a load shared by the requests missing the same user, which outlives
+// any one of them
+var userLoadTimeout = config.GetDuration("USERS_CACHE_LOAD_TIMEOUT", 2*time.Second)
+
+// CachedUserService decorates a UserInterface, serving GetUser from a cache that writes
+// going through it invalidate once committed. Soft deleted users are never cached, and reads
+// within a transaction bypass the cache so that they see the writes of the transaction.
+type CachedUserService struct {
+	UserInterface
+
+	cache       cache.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	loads       cache.Group
+}
+
+type userLoad struct {
+	user *users.User
+	err  *errors.RestErr
+}
+
+// NewCachedUserService returns next cached for ttl, missing ids being remembered for negativeTTL
+func NewCachedUserService(next UserInterface, c cache.Cache, ttl, negativeTTL time.Duration) *CachedUserService {
+	return &CachedUserService{
+		UserInterface: next,
+		cache:         c,
+		ttl:           ttl,
+		negativeTTL:   negativeTTL,
+	}
+}
+
+func userCacheKey(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// GetUser returns the cached user, loading it once however many requests miss at the same time
+func (s *CachedUserService) GetUser(ctx context.Context, userID int, includeDeleted bool) (*users.User, *errors.RestErr) {
+	if includeDeleted || users.InTx(ctx) {
+		return s.UserInterface.GetUser(ctx, userID, includeDeleted)
+	}
+
+	key := userCacheKey(userID)
+	if data, ok, err := s.cache.Get(ctx, key); err != nil {
+		logger.Info("failed to read user cache", zap.String("key", key), zap.String("error", err.Error()))
+	} else if ok {
+		if bytes.Equal(data, missingUser) {
+			return nil, errors.NewNotFoundError(fmt.Sprintf("user %d not found", userID))
+		}
+		if u, err := decodeUser(data); err == nil {
+			return u, nil
+		}
+	}
+
+	var res userLoad
+	select {
+	case v := <-s.loads.DoChan(key, func() interface{} { return s.load(key, userID) }):
+		res = v.(userLoad)
+	case <-ctx.Done():
+		return nil, errors.NewServiceUnavailableError("database timeout, try again later")
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	// callers may modify the user, so each gets its own copy
+	u := *res.user
+	return &u, nil
+}
+
+// load reads the user into the cache. It runs on its own context, as the requests waiting for
+// it may give up, and reads from the primary so that a lagging replica cannot refill the cache
+// with a version older than the one a write just invalidated.
+func (s *CachedUserService) load(key string, userID int) userLoad {
+	ctx, cancel := context.WithTimeout(users.ReadFromPrimary(context.Background()), userLoadTimeout)
+	defer cancel()
+
+	u, err := s.UserInterface.GetUser(ctx, userID, false)
+	s.store(ctx, key, u, err)
+	return userLoad{user: u, err: err}
+}
+
+func (s *CachedUserService) store(ctx context.Context, key string, u *users.User, restErr *errors.RestErr) {
+	var (
+		data []byte
+		ttl  = s.ttl
+	)
+	switch {
+	case restErr == nil:
+		var err error
+		if data, err = encodeUser(u); err != nil {
+			logger.Error("failed to encode user for cache: ", err)
+			return
+		}
+	case restErr.Error == "not_found":
+		data, ttl = missingUser, s.negativeTTL
+	default:
+		return
+	}
+	if err := s.cache.Set(ctx, key, data, ttl); err != nil {
+		logger.Info("failed to write user cache", zap.String("key", key), zap.String("error", err.Error()))
+	}
+}
+
+// invalidate drops the cached user once the transaction of ctx commits, so that no load can
+// refill the cache with the row as it was before. It runs even if the request that changed
+// the user was cancelled meanwhile.
+func (s *CachedUserService) invalidate(ctx context.Context, userID int) {
+	users.AfterCommit(ctx, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := s.cache.Delete(ctx, userCacheKey(userID)); err != nil {
+			logger.Error("failed to invalidate user cache: ", err)
+		}
+	})
+}
+
+// CreateUser creates the user and forgets it was missing
+func (s *CachedUserService) CreateUser(ctx context.Context, u users.User, caller users.Caller) (*users.User, *errors.RestErr) {
+	user, err := s.UserInterface.CreateUser(ctx, u, caller)
+	if err == nil {
+		s.invalidate(ctx, user.ID)
+	}
+	return user, err
+}
+
+// UpdateUser updates the user and invalidates its cached copy
+func (s *CachedUserService) UpdateUser(ctx context.Context, u users.User, isPatch bool, caller users.Caller) (*users.User, *errors.RestErr) {
+	defer s.invalidate(ctx, u.ID)
+	return s.UserInterface.UpdateUser(ctx, u, isPatch, caller)
+}
+
+// PatchUser patches the user and invalidates its cached copy
+func (s *CachedUserService) PatchUser(ctx context.Context, userID int, version int, patch users.Patch, caller users.Caller) (*users.User, *errors.RestErr) {
+	defer s.invalidate(ctx, userID)
+	return s.UserInterface.PatchUser(ctx, userID, version, patch, caller)
+}
+
+// DeleteUser deletes the user and invalidates its cached copy
+func (s *CachedUserService) DeleteUser(ctx context.Context, userID int, version int, caller users.Caller) *errors.RestErr {
+	defer s.invalidate(ctx, userID)
+	return s.UserInterface.DeleteUser(ctx, userID, version, caller)
+}
+
+// ChangeStatus changes the user status and invalidates its cached copy
+func (s *CachedUserService) ChangeStatus(ctx context.Context, userID int, action, reason string, caller users.Caller) (*users.User, *errors.RestErr) {
+	defer s.invalidate(ctx, userID)
+	return s.UserInterface.ChangeStatus(ctx, userID, action, reason, caller)
+}
+
+// EraseUser erases the user and invalidates its cached copy
+func (s *CachedUserService) EraseUser(ctx context.Context, userID int, version int, reason string, caller users.Caller) (*users.User, *errors.RestErr) {
+	defer s.invalidate(ctx, userID)
+	return s.UserInterface.EraseUser(ctx, userID, version, reason, caller)
+}
+
+func encodeUser(u *users.User) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(u); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeUser(data []byte) (*users.User, error) {
+	u := new(users.User)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ImportUsers imports the users and forgets the created ids were missing
+func (s *CachedUserService) ImportUsers(ctx context.Context, rows []users.BulkRow, mode string, dryRun bool, caller users.Caller) (*users.BulkReport, *errors.RestErr) {
+	report, err := s.UserInterface.ImportUsers(ctx, rows, mode, dryRun, caller)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range report.Results {
+		if res.ID != 0 {
+			s.invalidate(ctx, res.ID)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/sauravgsh16/bookstore_users-api/datasource/postgres/usersdb"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/cache"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/resp"
+)
+
+// storedUsers serves GetUser from a map, counting the loads and blocking them while hold is set
+type storedUsers struct {
+	UserInterface
+
+	mu    sync.Mutex
+	users map[int]users.User
+	loads int
+	hold  chan struct{}
+	// loadErr is the error of the context of the last load, once it returned
+	loadErr error
+}
+
+func (s *storedUsers) GetUser(ctx context.Context, userID int, includeDeleted bool) (*users.User, *errors.RestErr) {
+	s.mu.Lock()
+	s.loads++
+	hold := s.hold
+	s.mu.Unlock()
+	if hold != nil {
+		<-hold
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadErr = ctx.Err()
+	u, ok := s.users[userID]
+	if !ok {
+		return nil, errors.NewNotFoundError(fmt.Sprintf("user %d not found", userID))
+	}
+	return &u, nil
+}
+
+func (s *storedUsers) UpdateUser(ctx context.Context, u users.User, isPatch bool, caller users.Caller) (*users.User, *errors.RestErr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.ID] = u
+	return &u, nil
+}
+
+func (s *storedUsers) CreateUser(ctx context.Context, u users.User, caller users.Caller) (*users.User, *errors.RestErr) {
+	return s.UpdateUser(ctx, u, false, caller)
+}
+
+func (s *storedUsers) loadCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loads
+}
+
+// newCachedUsers returns a cached service over stored users, cached on a Redis stand-in
+func newCachedUsers(t *testing.T) (*CachedUserService, *storedUsers, *miniredis.Miniredis) {
+	t.Helper()
+	srv, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting redis stand-in: %v", err)
+	}
+	stored := &storedUsers{users: map[int]users.User{1: {ID: 1, FirstName: "Alice", Status: users.StatusActive}}}
+	client := resp.NewClient(resp.Options{Addr: srv.Addr()})
+	return NewCachedUserService(stored, cache.NewRedis(client, "test:"), time.Minute, 10*time.Second), stored, srv
+}
+
+func TestCachedUserService(t *testing.T) {
+	ctx := context.Background()
+	caller := users.Caller{Actor: "test"}
+
+	tests := []struct {
+		name string
+		// steps runs against the service, returning the user id read last
+		steps     func(*CachedUserService, *miniredis.Miniredis) int
+		wantLoads int
+		wantErr   int
+		wantName  string
+	}{
+		{
+			name:      "miss then hit",
+			steps:     func(s *CachedUserService, _ *miniredis.Miniredis) int { s.GetUser(ctx, 1, false); return 1 },
+			wantLoads: 1,
+			wantName:  "Alice",
+		},
+		{
+			name: "update invalidates",
+			steps: func(s *CachedUserService, _ *miniredis.Miniredis) int {
+				s.GetUser(ctx, 1, false)
+				s.UpdateUser(ctx, users.User{ID: 1, FirstName: "Alicia"}, false, caller)
+				return 1
+			},
+			wantLoads: 2,
+			wantName:  "Alicia",
+		},
+		{
+			name: "expired entry reloads",
+			steps: func(s *CachedUserService, srv *miniredis.Miniredis) int {
+				s.GetUser(ctx, 1, false)
+				srv.FastForward(2 * time.Minute)
+				return 1
+			},
+			wantLoads: 2,
+			wantName:  "Alice",
+		},
+		{
+			name: "deleted users bypass the cache",
+			steps: func(s *CachedUserService, _ *miniredis.Miniredis) int {
+				s.GetUser(ctx, 1, true)
+				s.GetUser(ctx, 1, true)
+				return 1
+			},
+			wantLoads: 3,
+			wantName:  "Alice",
+		},
+		{
+			name:      "missing user is remembered",
+			steps:     func(s *CachedUserService, _ *miniredis.Miniredis) int { s.GetUser(ctx, 2, false); return 2 },
+			wantLoads: 1,
+			wantErr:   http.StatusNotFound,
+		},
+		{
+			name: "missing user expires sooner",
+			steps: func(s *CachedUserService, srv *miniredis.Miniredis) int {
+				s.GetUser(ctx, 2, false)
+				srv.FastForward(11 * time.Second)
+				return 2
+			},
+			wantLoads: 2,
+			wantErr:   http.StatusNotFound,
+		},
+		{
+			name: "creation forgets the user was missing",
+			steps: func(s *CachedUserService, _ *miniredis.Miniredis) int {
+				s.GetUser(ctx, 2, false)
+				s.CreateUser(ctx, users.User{ID: 2, FirstName: "Bob"}, caller)
+				return 2
+			},
+			wantLoads: 2,
+			wantName:  "Bob",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, stored, srv := newCachedUsers(t)
+			defer srv.Close()
+
+			userID := tt.steps(s, srv)
+			u, err := s.GetUser(ctx, userID, false)
+			if got := stored.loadCount(); got != tt.wantLoads {
+				t.Errorf("loads = %d, want %d", got, tt.wantLoads)
+			}
+			if tt.wantErr != 0 {
+				if err == nil || err.Status != tt.wantErr {
+					t.Fatalf("GetUser = %+v, %v, want status %d", u, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetUser: %v", err.Message)
+			}
+			if u.FirstName != tt.wantName {
+				t.Errorf("GetUser first name = %q, want %q", u.FirstName, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestCachedUserServiceReturnsCopies(t *testing.T) {
+	s, _, srv := newCachedUsers(t)
+	defer srv.Close()
+
+	u, _ := s.GetUser(context.Background(), 1, false)
+	u.FirstName = "changed"
+	if again, _ := s.GetUser(context.Background(), 1, false); again.FirstName != "Alice" {
+		t.Errorf("cached user changed by a caller to %q", again.FirstName)
+	}
+}
+
+func TestCachedUserServiceCollapsesLoads(t *testing.T) {
+	s, stored, srv := newCachedUsers(t)
+	defer srv.Close()
+	stored.hold = make(chan struct{})
+
+	// the first caller gives up while the load is in flight, the others keep waiting
+	first, cancel := context.WithCancel(context.Background())
+	const callers = 10
+	errs := make(chan *errors.RestErr, callers)
+	for i := 0; i < callers; i++ {
+		ctx := context.Background()
+		if i == 0 {
+			ctx = first
+		}
+		go func(ctx context.Context) {
+			_, err := s.GetUser(ctx, 1, false)
+			errs <- err
+		}(ctx)
+	}
+	for stored.loadCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-errs; err == nil || err.Status != http.StatusServiceUnavailable {
+		t.Fatalf("cancelled caller got %v, want service unavailable", err)
+	}
+	close(stored.hold)
+
+	for i := 1; i < callers; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("waiting caller got %v", err.Message)
+		}
+	}
+	if got := stored.loadCount(); got != 1 {
+		t.Errorf("loads = %d, want the concurrent misses collapsed into 1", got)
+	}
+	if stored.loadErr != nil {
+		t.Errorf("shared load ran on a context ended with %v", stored.loadErr)
+	}
+	if !srv.Exists("test:" + userCacheKey(1)) {
+		t.Error("shared load did not fill the cache")
+	}
+}
+
+func TestCachedUserServiceInTx(t *testing.T) {
+	s, stored, srv := newCachedUsers(t)
+	defer srv.Close()
+	caller := users.Caller{Actor: "test"}
+
+	s.GetUser(context.Background(), 1, false)
+	ctx := usersdb.NewContext(context.Background(), nil)
+	tx, _ := usersdb.FromContext(ctx)
+
+	// reads of the transaction bypass the cache, neither served nor filled by it
+	s.CreateUser(ctx, users.User{ID: 2, FirstName: "Bob"}, caller)
+	if u, err := s.GetUser(ctx, 2, false); err != nil || u.FirstName != "Bob" {
+		t.Fatalf("GetUser in the transaction = %+v, %v, want the user it created", u, err)
+	}
+	s.UpdateUser(ctx, users.User{ID: 1, FirstName: "Alicia"}, false, caller)
+	if u, _ := s.GetUser(ctx, 1, false); u.FirstName != "Alicia" {
+		t.Errorf("GetUser in the transaction = %q, want the update of the transaction", u.FirstName)
+	}
+	if got := stored.loadCount(); got != 3 {
+		t.Errorf("loads = %d, want 3", got)
+	}
+	if srv.Exists("test:" + userCacheKey(2)) {
+		t.Error("read in the transaction filled the cache")
+	}
+
+	// the cached user is dropped only once the transaction commits
+	if !srv.Exists("test:" + userCacheKey(1)) {
+		t.Fatal("user invalidated before the transaction committed")
+	}
+	tx.Committed()
+	if srv.Exists("test:" + userCacheKey(1)) {
+		t.Error("user still cached after the transaction committed")
+	}
+	if u, _ := s.GetUser(context.Background(), 1, false); u.FirstName != "Alicia" {
+		t.Errorf("GetUser after commit = %q, want Alicia", u.FirstName)
+	}
+}
+
+func TestCachedUserServiceCreateInRunInTx(t *testing.T) {
+	requireDB(t)
+	srv, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting redis stand-in: %v", err)
+	}
+	defer srv.Close()
+	s := NewCachedUserService(&UserService{}, cache.NewRedis(resp.NewClient(resp.Options{Addr: srv.Addr()}), "test:"), time.Minute, 10*time.Second)
+	ctx := context.Background()
+	caller := users.Caller{Actor: "test"}
+
+	var created *users.User
+	txErr := users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+		var err *errors.RestErr
+		if created, err = s.CreateUser(ctx, users.User{FirstName: "Gina", Email: uniqueEmail("gina"), Password: "secret"}, caller); err != nil {
+			return err
+		}
+		if _, err := s.GetUser(ctx, created.ID, false); err != nil {
+			return err
+		}
+		_, err = s.ChangeStatus(ctx, created.ID, users.ActionActivate, "", caller)
+		return err
+	})
+	if txErr != nil {
+		t.Fatalf("RunInTx: %v", txErr.Message)
+	}
+	u, getErr := s.GetUser(ctx, created.ID, false)
+	if getErr != nil {
+		t.Fatalf("GetUser after commit: %v", getErr.Message)
+	}
+	if u.Status != users.StatusActive {
+		t.Errorf("user is %s after commit, want active", u.Status)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/domain/webhooks"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// ExportUserData returns everything held on a user, soft deleted or erased users included
+func (s *UserService) ExportUserData(ctx context.Context, userID int) (*users.DataExport, *errors.RestErr) {
+	user, err := s.GetUser(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &users.DataExport{
+		UserID:      user.ID,
+		GeneratedAt: dates.GetNowString(),
+		Account:     user.Marshall(users.ViewProjection(users.ViewAdmin)),
+		Profile:     &users.Profile{UserID: userID},
+	}
+	if err := export.Profile.Get(ctx); err != nil && err.Error != "not_found" {
+		return nil, err
+	}
+	if export.Profile.HasAvatar() {
+		if export.Avatar, err = ProfileServ.ReadAvatar(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	address := users.Address{}
+	if export.Addresses, err = address.FindByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	identity := users.Identity{}
+	if export.Identities, err = identity.FindByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.StatusHistory, err = user.FindStatusChanges(ctx); err != nil {
+		return nil, err
+	}
+	if export.AuditLog, err = user.FindAllAuditEntries(ctx); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// EraseUser irreversibly replaces the personal data of a user with tombstones, keeping its ID.
+// Its identities, profile, addresses and avatar are removed, its past audit entries and events
+// redacted. A non zero version must match the stored version.
+func (s *UserService) EraseUser(ctx context.Context, userID int, version int, reason string, caller users.Caller) (*users.User, *errors.RestErr) {
+	var user *users.User
+	err := users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+		var err *errors.RestErr
+		if user, err = s.GetUser(ctx, userID, true); err != nil {
+			return err
+		}
+		if err := checkVersion(user, version); err != nil {
+			return err
+		}
+		if user.IsErased() {
+			return errors.NewConflictError("user has already been erased")
+		}
+
+		before := *user
+		now := dates.GetNowDBString()
+		user.Anonymize(now)
+
+		sc := &users.StatusChange{
+			Action:      users.ActionErase,
+			From:        before.Status,
+			To:          user.Status,
+			Reason:      strings.TrimSpace(reason),
+			Actor:       caller.Actor,
+			DateCreated: now,
+		}
+		entry := users.NewAuditEntry(users.ActionErase, caller, &before, user)
+		entry.RedactPersonalData()
+		entry.DateCreated = now
+
+		if err := user.Erase(ctx, sc, entry); err != nil {
+			return err
+		}
+		return webhooks.RedactUser(ctx, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// blobs cannot join the transaction, a failure leaves them orphaned but unreachable
+	if err := ProfileServ.RemoveAvatarBlobs(ctx, userID); err != nil {
+		logger.Info("failed to remove avatar of erased user", zap.Int("user_id", userID), zap.String("error", err.Message))
+	}
+	return user, nil
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/sauravgsh16/bookstore_users-api/domain/users"
 	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
@@ -19,46 +21,82 @@ type UserService struct{}
 
 // UserInterface describes methods to be implemented
 type UserInterface interface {
-	GetUser(int) (*users.User, *errors.RestErr)
-	CreateUser(users.User) (*users.User, *errors.RestErr)
-	UpdateUser(users.User, bool) (*users.User, *errors.RestErr)
-	DeleteUser(int) *errors.RestErr
-	SearchUser(string) (users.Users, *errors.RestErr)
-	LoginUser(users.LoginRequest) (*users.User, *errors.RestErr)
+	GetUser(context.Context, int, bool) (*users.User, *errors.RestErr)
+	CreateUser(context.Context, users.User, users.Caller) (*users.User, *errors.RestErr)
+	UpdateUser(context.Context, users.User, bool, users.Caller) (*users.User, *errors.RestErr)
+	PatchUser(context.Context, int, int, users.Patch, users.Caller) (*users.User, *errors.RestErr)
+	DeleteUser(context.Context, int, int, users.Caller) *errors.RestErr
+	SearchUser(context.Context, string, bool) (users.Users, *errors.RestErr)
+	LoginUser(context.Context, users.LoginRequest) (*users.User, *errors.RestErr)
+	ChangeStatus(context.Context, int, string, string, users.Caller) (*users.User, *errors.RestErr)
+	GetStatusHistory(context.Context, int) (users.StatusChanges, *errors.RestErr)
+	GetAuditLog(context.Context, int, int, int) (*users.AuditPage, *errors.RestErr)
+	ImportUsers(context.Context, []users.BulkRow, string, bool, users.Caller) (*users.BulkReport, *errors.RestErr)
+	ExportUsers(context.Context, bool, func(*users.User) *errors.RestErr) *errors.RestErr
+	ExportUserData(context.Context, int) (*users.DataExport, *errors.RestErr)
+	EraseUser(context.Context, int, int, string, users.Caller) (*users.User, *errors.RestErr)
 }
 
-// CreateUser creates a new user in the database
-func (s *UserService) CreateUser(u users.User) (*users.User, *errors.RestErr) {
+// CreateUser creates a new pending user in the database, counting it as a sign up once it is
+// committed. The user cannot sign in until activated.
+func (s *UserService) CreateUser(ctx context.Context, u users.User, caller users.Caller) (*users.User, *errors.RestErr) {
+	user, err := s.createUser(ctx, u, caller)
+	if err != nil {
+		return nil, err
+	}
+	method := signupMethod(caller)
+	users.AfterCommit(ctx, func() { signups.Inc(method) })
+	return user, nil
+}
+
+func (s *UserService) createUser(ctx context.Context, u users.User, caller users.Caller) (*users.User, *errors.RestErr) {
 	if valid := u.Validate(); !valid {
 		return nil, errors.NewBadRequestError("invalid user data")
 	}
 
 	u.DateCreated = dates.GetNowDBString()
-	u.Status = users.StatusActive
+	u.Status = users.StatusPending
 	u.Password = crypto.GetMd5(u.Password)
 
-	if err := u.Save(); err != nil {
+	if err := u.Save(ctx, users.NewAuditEntry(users.AuditActionCreate, caller, nil, &u)); err != nil {
 		return nil, err
 	}
 
 	return &u, nil
 }
 
-// GetUser returns user if present
-func (s *UserService) GetUser(userID int) (*users.User, *errors.RestErr) {
+// GetUser returns user if present, soft deleted users only if includeDeleted is set
+func (s *UserService) GetUser(ctx context.Context, userID int, includeDeleted bool) (*users.User, *errors.RestErr) {
 	user := &users.User{}
-	if err := user.Get(userID); err != nil {
+	if err := user.Get(ctx, userID, includeDeleted); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-// UpdateUser updates a user
-func (s *UserService) UpdateUser(u users.User, isPatch bool) (*users.User, *errors.RestErr) {
-	current, err := s.GetUser(u.ID)
+// UpdateUser updates a user. A non zero u.Version must match the stored version.
+func (s *UserService) UpdateUser(ctx context.Context, u users.User, isPatch bool, caller users.Caller) (*users.User, *errors.RestErr) {
+	var updated *users.User
+	err := users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+		var err *errors.RestErr
+		updated, err = s.updateUser(ctx, u, isPatch, caller)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *UserService) updateUser(ctx context.Context, u users.User, isPatch bool, caller users.Caller) (*users.User, *errors.RestErr) {
+	current, err := s.GetUser(ctx, u.ID, false)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(current, u.Version); err != nil {
+		return nil, err
+	}
+	before := *current
 
 	if isPatch {
 		if u.FirstName != "" {
@@ -78,39 +116,164 @@ func (s *UserService) UpdateUser(u users.User, isPatch bool) (*users.User, *erro
 		current.Email = u.Email
 	}
 
-	if err := current.Update(); err != nil {
+	if err := current.Update(ctx, users.NewAuditEntry(users.AuditActionUpdate, caller, &before, current)); err != nil {
 		return nil, err
 	}
 	return current, nil
 }
 
-// DeleteUser api
-func (s *UserService) DeleteUser(uid int) *errors.RestErr {
-	user, err := s.GetUser(uid)
+// PatchUser applies a merge patch or JSON Patch to the stored user. A non zero version must
+// match the stored version.
+func (s *UserService) PatchUser(ctx context.Context, userID int, version int, patch users.Patch, caller users.Caller) (*users.User, *errors.RestErr) {
+	var patched *users.User
+	err := users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+		current, err := s.GetUser(ctx, userID, false)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(current, version); err != nil {
+			return err
+		}
+		if patched, err = patch.Apply(current); err != nil {
+			return err
+		}
+		return patched.Update(ctx, users.NewAuditEntry(users.AuditActionUpdate, caller, current, patched))
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return patched, nil
+}
 
-	if err := user.Delete(); err != nil {
-		return err
+// DeleteUser soft deletes a user, it is hard deleted by the purge job once retention expires.
+// A non zero version must match the stored version.
+func (s *UserService) DeleteUser(ctx context.Context, uid int, version int, caller users.Caller) *errors.RestErr {
+	return users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+		user, err := s.GetUser(ctx, uid, false)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(user, version); err != nil {
+			return err
+		}
+		return s.applyTransition(ctx, user, users.ActionDelete, "", caller)
+	})
+}
+
+func checkVersion(u *users.User, version int) *errors.RestErr {
+	if version != 0 && u.Version != version {
+		return errors.NewPreconditionFailedError("user has been modified")
 	}
 	return nil
 }
 
 // SearchUser returns users matching passed argument
-func (s *UserService) SearchUser(status string) (users.Users, *errors.RestErr) {
+func (s *UserService) SearchUser(ctx context.Context, status string, includeDeleted bool) (users.Users, *errors.RestErr) {
+	if !users.IsValidStatus(status) {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("invalid status %q", status))
+	}
 	dao := users.User{}
-	return dao.FindByStatus(status)
+	return dao.FindByStatus(ctx, status, includeDeleted)
 }
 
 // LoginUser logs in a user
-func (s *UserService) LoginUser(req users.LoginRequest) (*users.User, *errors.RestErr) {
+func (s *UserService) LoginUser(ctx context.Context, req users.LoginRequest) (*users.User, *errors.RestErr) {
+	user, err := s.loginUser(ctx, req)
+	recordLogin(methodPassword, err)
+	return user, err
+}
+
+func (s *UserService) loginUser(ctx context.Context, req users.LoginRequest) (*users.User, *errors.RestErr) {
 	user := &users.User{}
 
-	fmt.Printf("%#v\n", req)
+	if err := user.FindByEmailPassword(ctx, req.Email, req.Password); err != nil {
+		return nil, err
+	}
+	if err := checkCanLogin(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func checkCanLogin(u *users.User) *errors.RestErr {
+	if !u.CanLogin() {
+		return errors.NewForbiddenError(fmt.Sprintf("user account is %s", u.Status))
+	}
+	return nil
+}
+
+// ChangeStatus applies a lifecycle action to a user
+func (s *UserService) ChangeStatus(ctx context.Context, userID int, action, reason string, caller users.Caller) (*users.User, *errors.RestErr) {
+	if _, ok := users.GetTransition(action); !ok {
+		return nil, errors.NewBadRequestError(fmt.Sprintf("unknown status action %q", action))
+	}
 
-	if err := user.FindByEmailPassword(req.Email, req.Password); err != nil {
+	var user *users.User
+	err := users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+		var err *errors.RestErr
+		if user, err = s.GetUser(ctx, userID, action == users.ActionRestore); err != nil {
+			return err
+		}
+		return s.applyTransition(ctx, user, action, reason, caller)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
+
+func (s *UserService) applyTransition(ctx context.Context, user *users.User, action, reason string, caller users.Caller) *errors.RestErr {
+	t, ok := users.GetTransition(action)
+	if !ok {
+		return errors.NewBadRequestError(fmt.Sprintf("unknown status action %q", action))
+	}
+	if user.IsErased() {
+		return errors.NewConflictError(fmt.Sprintf("cannot %s a user that has been erased", action))
+	}
+	if !t.Allows(user.Status) {
+		return errors.NewConflictError(fmt.Sprintf("cannot %s a user that is %s", action, user.Status))
+	}
+
+	to := t.To
+	if action == users.ActionRestore {
+		prev, err := user.FindStatusBeforeDeletion(ctx)
+		if err != nil && err.Error != "not_found" {
+			return err
+		}
+		if prev != "" {
+			to = prev
+		}
+	}
+
+	sc := &users.StatusChange{
+		Action:      action,
+		From:        user.Status,
+		To:          to,
+		Reason:      strings.TrimSpace(reason),
+		Actor:       caller.Actor,
+		DateCreated: dates.GetNowDBString(),
+	}
+
+	after := *user
+	after.Status = to
+	after.DeletedAt = ""
+	if to == users.StatusDeleted {
+		after.DeletedAt = sc.DateCreated
+	}
+	return user.ChangeStatus(ctx, sc, users.NewAuditEntry(action, caller, user, &after))
+}
+
+// GetStatusHistory returns the status changes of a user
+func (s *UserService) GetStatusHistory(ctx context.Context, userID int) (users.StatusChanges, *errors.RestErr) {
+	user, err := s.GetUser(ctx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	return user.FindStatusChanges(ctx)
+}
+
+// GetAuditLog returns a page of the audit log of a user, newest first
+func (s *UserService) GetAuditLog(ctx context.Context, userID, page, perPage int) (*users.AuditPage, *errors.RestErr) {
+	dao := &users.User{ID: userID}
+	return dao.FindAuditEntries(ctx, page, perPage)
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sauravgsh16/bookstore_users-api/blobs"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"github.com/sauravgsh16/bookstore_users-api/utils/metrics"
+)
+
+func TestUserLifecycle(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	caller := users.Caller{Actor: "admin"}
+	s := &UserService{}
+
+	email := uniqueEmail("erin")
+	created, err := s.CreateUser(ctx, users.User{FirstName: "Erin", Email: email, Password: "secret"}, caller)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err.Message)
+	}
+	if created.Status != users.StatusPending {
+		t.Fatalf("created user is %s, want pending", created.Status)
+	}
+	login := users.LoginRequest{Email: email, Password: crypto.GetMd5("secret")}
+
+	// steps run in order against the created user
+	tests := []struct {
+		action     string
+		wantStatus string
+		wantErr    int
+		// wantLogin is the status of signing in afterwards, 0 when allowed
+		wantLogin int
+	}{
+		{wantStatus: users.StatusPending, wantLogin: http.StatusForbidden},
+		{action: users.ActionSuspend, wantErr: http.StatusConflict, wantStatus: users.StatusPending, wantLogin: http.StatusForbidden},
+		{action: users.ActionActivate, wantStatus: users.StatusActive},
+		{action: users.ActionActivate, wantErr: http.StatusConflict, wantStatus: users.StatusActive},
+		{action: users.ActionSuspend, wantStatus: users.StatusSuspended, wantLogin: http.StatusForbidden},
+		{action: users.ActionReactivate, wantStatus: users.StatusActive},
+	}
+	for _, tt := range tests {
+		if tt.action != "" {
+			_, err := s.ChangeStatus(ctx, created.ID, tt.action, "test", caller)
+			if tt.wantErr == 0 && err != nil {
+				t.Fatalf("%s: %v", tt.action, err.Message)
+			}
+			if tt.wantErr != 0 && (err == nil || err.Status != tt.wantErr) {
+				t.Fatalf("%s = %v, want status %d", tt.action, err, tt.wantErr)
+			}
+		}
+		u, err := s.GetUser(ctx, created.ID, false)
+		if err != nil {
+			t.Fatalf("GetUser: %v", err.Message)
+		}
+		if u.Status != tt.wantStatus {
+			t.Errorf("after %q user is %s, want %s", tt.action, u.Status, tt.wantStatus)
+		}
+		_, err = s.LoginUser(ctx, login)
+		switch {
+		case tt.wantLogin == 0 && err != nil:
+			t.Errorf("after %q login failed: %v", tt.action, err.Message)
+		case tt.wantLogin != 0 && (err == nil || err.Status != tt.wantLogin):
+			t.Errorf("after %q login = %v, want status %d", tt.action, err, tt.wantLogin)
+		}
+	}
+}
+
+func TestEraseUser(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	caller := users.Caller{Actor: "admin"}
+	s := &UserService{}
+
+	dir, tmpErr := ioutil.TempDir("", "avatars")
+	if tmpErr != nil {
+		t.Fatalf("TempDir: %v", tmpErr)
+	}
+	defer os.RemoveAll(dir)
+	store, storeErr := blobs.NewFileStore(dir)
+	if storeErr != nil {
+		t.Fatalf("NewFileStore: %v", storeErr)
+	}
+	defer func(serv ProfileInterface) { ProfileServ = serv }(ProfileServ)
+	ProfileServ = NewProfileService(store)
+
+	email := uniqueEmail("frank")
+	created, err := s.CreateUser(ctx, users.User{FirstName: "Frank", Email: email, Password: "secret"}, caller)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err.Message)
+	}
+	if _, err := s.ChangeStatus(ctx, created.ID, users.ActionActivate, "", caller); err != nil {
+		t.Fatalf("activating: %v", err.Message)
+	}
+
+	if _, err := s.EraseUser(ctx, created.ID, created.Version, "request", caller); err == nil || err.Status != http.StatusPreconditionFailed {
+		t.Fatalf("erasing a stale version = %v, want precondition failed", err)
+	}
+	erased, err := s.EraseUser(ctx, created.ID, 0, "request", caller)
+	if err != nil {
+		t.Fatalf("EraseUser: %v", err.Message)
+	}
+	if !erased.IsErased() || erased.Status != users.StatusDeleted {
+		t.Errorf("erased user = %+v, want it erased and deleted", erased)
+	}
+	if _, err := s.EraseUser(ctx, created.ID, 0, "again", caller); err == nil || err.Status != http.StatusConflict {
+		t.Errorf("erasing again = %v, want a conflict", err)
+	}
+	if _, err := s.LoginUser(ctx, users.LoginRequest{Email: email, Password: crypto.GetMd5("secret")}); err == nil {
+		t.Error("erased user signed in")
+	}
+
+	export, err := s.ExportUserData(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("ExportUserData: %v", err.Message)
+	}
+	if data, _ := json.Marshal(export); strings.Contains(string(data), email) || strings.Contains(string(data), "Frank") {
+		t.Errorf("export of the erased user holds personal data: %s", data)
+	}
+	history := export.StatusHistory
+	if len(history) == 0 || history[len(history)-1].Action != users.ActionErase || history[len(history)-1].Reason != "request" {
+		t.Errorf("status history = %+v, want it to end with the erasure", history)
+	}
+}
+
+// signupCount returns the sign ups counted for method
+func signupCount(t *testing.T, method string) float64 {
+	var b strings.Builder
+	if _, err := metrics.DefaultRegistry.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	prefix := fmt.Sprintf("users_signups_total{method=%q} ", method)
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.HasPrefix(line, prefix) {
+			n, err := strconv.ParseFloat(strings.TrimPrefix(line, prefix), 64)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", line, err)
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func TestCreateUserCountsCommittedSignups(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	caller := users.Caller{Actor: "admin"}
+	s := &UserService{}
+	rollback := errors.NewBadRequestError("rolled back")
+
+	tests := []struct {
+		name string
+		run  func() *errors.RestErr
+		want float64
+	}{
+		{
+			name: "outside of a transaction",
+			run: func() *errors.RestErr {
+				_, err := s.CreateUser(ctx, users.User{FirstName: "Fay", Email: uniqueEmail("fay"), Password: "secret"}, caller)
+				return err
+			},
+			want: 1,
+		},
+		{
+			name: "rolled back transaction",
+			run: func() *errors.RestErr {
+				err := users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+					if _, err := s.CreateUser(ctx, users.User{FirstName: "Gil", Email: uniqueEmail("gil"), Password: "secret"}, caller); err != nil {
+						return err
+					}
+					return rollback
+				})
+				if err != rollback {
+					return err
+				}
+				return nil
+			},
+		},
+		{
+			name: "rolled back savepoint of a committed transaction",
+			run: func() *errors.RestErr {
+				return users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+					users.RunInTx(ctx, func(ctx context.Context) *errors.RestErr {
+						s.CreateUser(ctx, users.User{FirstName: "Hal", Email: uniqueEmail("hal"), Password: "secret"}, caller)
+						return rollback
+					})
+					_, err := s.CreateUser(ctx, users.User{FirstName: "Ida", Email: uniqueEmail("ida"), Password: "secret"}, caller)
+					return err
+				})
+			},
+			want: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := signupCount(t, methodPassword)
+			if err := tt.run(); err != nil {
+				t.Fatalf("run: %v", err.Message)
+			}
+			if got := signupCount(t, methodPassword) - before; got != tt.want {
+				t.Errorf("sign ups counted = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserVersionChecks(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	caller := users.Caller{Actor: "admin"}
+	s := &UserService{}
+
+	created, err := s.CreateUser(ctx, users.User{FirstName: "Jo", Email: uniqueEmail("jo"), Password: "secret"}, caller)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err.Message)
+	}
+	stale := created.Version
+
+	updated, err := s.UpdateUser(ctx, users.User{ID: created.ID, FirstName: "Joe", Version: stale}, true, caller)
+	if err != nil {
+		t.Fatalf("UpdateUser with the current version: %v", err.Message)
+	}
+	if updated.Version <= stale {
+		t.Fatalf("version after update = %d, want more than %d", updated.Version, stale)
+	}
+
+	tests := []struct {
+		name string
+		run  func() *errors.RestErr
+	}{
+		{"update", func() *errors.RestErr {
+			_, err := s.UpdateUser(ctx, users.User{ID: created.ID, FirstName: "Joan", Version: stale}, true, caller)
+			return err
+		}},
+		{"patch", func() *errors.RestErr {
+			patch := users.Patch{MediaType: users.PatchMerge, Document: []byte(`{"first_name":"Joan"}`)}
+			_, err := s.PatchUser(ctx, created.ID, stale, patch, caller)
+			return err
+		}},
+		{"delete", func() *errors.RestErr { return s.DeleteUser(ctx, created.ID, stale, caller) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" of a stale version", func(t *testing.T) {
+			if err := tt.run(); err == nil || err.Status != http.StatusPreconditionFailed {
+				t.Fatalf("got %v, want status %d", err, http.StatusPreconditionFailed)
+			}
+		})
+	}
+
+	if u, err := s.GetUser(ctx, created.ID, false); err != nil || u.FirstName != "Joe" || u.Version != updated.Version {
+		t.Errorf("GetUser = %+v, %v, want the user as updated", u, err)
+	}
+	if err := s.DeleteUser(ctx, created.ID, updated.Version, caller); err != nil {
+		t.Errorf("DeleteUser with the current version: %v", err.Message)
+	}
+}
+
+func TestDeleteRestore(t *testing.T) {
+	requireDB(t)
+	ctx := context.Background()
+	caller := users.Caller{Actor: "admin"}
+	s := &UserService{}
+
+	tests := []struct {
+		status string
+		// actions bring a pending user to status
+		actions []string
+	}{
+		{status: users.StatusPending},
+		{status: users.StatusActive, actions: []string{users.ActionActivate}},
+		{status: users.StatusSuspended, actions: []string{users.ActionActivate, users.ActionSuspend}},
+		{status: users.StatusDeactivated, actions: []string{users.ActionDeactivate}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			created, err := s.CreateUser(ctx, users.User{FirstName: "Kim", Email: uniqueEmail("kim"), Password: "secret"}, caller)
+			if err != nil {
+				t.Fatalf("CreateUser: %v", err.Message)
+			}
+			for _, action := range tt.actions {
+				if _, err := s.ChangeStatus(ctx, created.ID, action, "", caller); err != nil {
+					t.Fatalf("%s: %v", action, err.Message)
+				}
+			}
+
+			if err := s.DeleteUser(ctx, created.ID, 0, caller); err != nil {
+				t.Fatalf("DeleteUser: %v", err.Message)
+			}
+			if _, err := s.GetUser(ctx, created.ID, false); err == nil || err.Status != http.StatusNotFound {
+				t.Errorf("GetUser of a deleted user = %v, want not found", err)
+			}
+			deleted, err := s.GetUser(ctx, created.ID, true)
+			if err != nil {
+				t.Fatalf("GetUser including deleted: %v", err.Message)
+			}
+			if deleted.Status != users.StatusDeleted || deleted.DeletedAt == "" {
+				t.Errorf("deleted user is %s deleted at %q, want deleted with a time", deleted.Status, deleted.DeletedAt)
+			}
+			if err := s.DeleteUser(ctx, created.ID, 0, caller); err == nil || err.Status != http.StatusNotFound {
+				t.Errorf("deleting again = %v, want not found", err)
+			}
+
+			restored, err := s.ChangeStatus(ctx, created.ID, users.ActionRestore, "", caller)
+			if err != nil {
+				t.Fatalf("restore: %v", err.Message)
+			}
+			if restored.Status != tt.status || restored.DeletedAt != "" {
+				t.Errorf("restored user is %s deleted at %q, want %s and not deleted", restored.Status, restored.DeletedAt, tt.status)
+			}
+			if u, err := s.GetUser(ctx, created.ID, false); err != nil || u.Status != tt.status {
+				t.Errorf("GetUser after restore = %+v, %v, want a %s user", u, err, tt.status)
+			}
+			if _, err := s.ChangeStatus(ctx, created.ID, users.ActionRestore, "", caller); err == nil || err.Status != http.StatusConflict {
+				t.Errorf("restoring a user that is not deleted = %v, want a conflict", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/domain/webhooks"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/publishers"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+	"go.uber.org/zap"
+)
+
+var (
+	// WebhookServ of type WebhookInterface derived from WebhookService struct
+	WebhookServ WebhookInterface = &WebhookService{}
+
+	webhookMaxAttempts = config.GetInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	webhookRetryBase   = config.GetDuration("WEBHOOK_RETRY_BASE", 30*time.Second)
+	webhookRetryMax    = config.GetDuration("WEBHOOK_RETRY_MAX", 6*time.Hour)
+	webhookTimeout     = config.GetDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+
+	webhookClient = &http.Client{Timeout: webhookTimeout}
+)
+
+// WebhookService struct
+type WebhookService struct{}
+
+// WebhookInterface describes methods to be implemented
+type WebhookInterface interface {
+	CreateSubscription(context.Context, webhooks.Subscription) (*webhooks.Subscription, *errors.RestErr)
+	GetSubscription(context.Context, int) (*webhooks.Subscription, *errors.RestErr)
+	GetSubscriptions(context.Context) (webhooks.Subscriptions, *errors.RestErr)
+	DeleteSubscription(context.Context, int) *errors.RestErr
+	GetDeliveries(context.Context, int, int, int) (webhooks.Deliveries, *errors.RestErr)
+	Redeliver(context.Context, int, int64) *errors.RestErr
+}
+
+// CreateSubscription registers a partner endpoint. The secret is generated if not supplied
+// and only returned by this call.
+func (s *WebhookService) CreateSubscription(ctx context.Context, sub webhooks.Subscription) (*webhooks.Subscription, *errors.RestErr) {
+	if valid := sub.Validate(users.EventTypes); !valid {
+		return nil, errors.NewBadRequestError("invalid subscription: url must be http(s) and event types known")
+	}
+	if sub.Secret == "" {
+		sub.Secret = crypto.GetRandomToken(32)
+	}
+	sub.DateCreated = dates.GetNowDBString()
+
+	if err := sub.Save(ctx); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// GetSubscription returns a subscription
+func (s *WebhookService) GetSubscription(ctx context.Context, id int) (*webhooks.Subscription, *errors.RestErr) {
+	sub := &webhooks.Subscription{}
+	if err := sub.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// GetSubscriptions returns all subscriptions
+func (s *WebhookService) GetSubscriptions(ctx context.Context) (webhooks.Subscriptions, *errors.RestErr) {
+	dao := &webhooks.Subscription{}
+	return dao.FindAll(ctx)
+}
+
+// DeleteSubscription removes a subscription
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id int) *errors.RestErr {
+	sub := &webhooks.Subscription{ID: id}
+	return sub.Delete(ctx)
+}
+
+// GetDeliveries returns a page of the subscription delivery log
+func (s *WebhookService) GetDeliveries(ctx context.Context, id, page, perPage int) (webhooks.Deliveries, *errors.RestErr) {
+	sub, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return sub.FindDeliveries(ctx, page, perPage)
+}
+
+// Redeliver schedules a delivery to be sent again immediately
+func (s *WebhookService) Redeliver(ctx context.Context, id int, deliveryID int64) *errors.RestErr {
+	sub := &webhooks.Subscription{ID: id}
+	return sub.Redeliver(ctx, deliveryID, dates.GetNowDBString())
+}
+
+// WebhookFanout is a publisher turning domain events into deliveries for matching subscriptions
+type WebhookFanout struct{}
+
+// Publish enqueues the message for every subscription listening to its type
+func (f WebhookFanout) Publish(ctx context.Context, msg publishers.Message) error {
+	body, err := msg.Envelope()
+	if err != nil {
+		return err
+	}
+	if err := webhooks.EnqueueEvent(ctx, msg.ID, msg.Type, body, dates.GetNowDBString()); err != nil {
+		return goerrors.New(err.Message)
+	}
+	return nil
+}
+
+// StartWebhookDispatcher periodically sends due webhook deliveries
+func StartWebhookDispatcher(interval time.Duration, batchSize int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			for dispatchWebhooks(batchSize) == batchSize {
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// dispatchWebhooks sends up to batchSize due deliveries and returns how many were sent. Each is
+// claimed right before it is sent, so its lease only has to outlive its own request.
+func dispatchWebhooks(batchSize int) int {
+	sent := 0
+	for sent < batchSize {
+		now := dates.GetNow()
+		// the lease outlives the request timeout so a delivery is never sent twice concurrently
+		lease := now.Add(2 * webhookTimeout)
+
+		deliveries, err := webhooks.ClaimDue(context.Background(), dates.GetDBString(now), dates.GetDBString(lease), 1)
+		if err != nil {
+			logger.Info("failed to claim webhook deliveries", zap.String("error", err.Message))
+			return sent
+		}
+		if len(deliveries) == 0 {
+			return sent
+		}
+
+		d := deliveries[0]
+		sendWebhook(d)
+		if err := d.RecordAttempt(context.Background()); err != nil {
+			logger.Info("failed to record webhook attempt", zap.Int64("delivery", d.ID), zap.String("error", err.Message))
+		}
+		sent++
+	}
+	return sent
+}
+
+// sendWebhook posts the delivery and updates its status, scheduling a retry on failure
+func sendWebhook(d *webhooks.Delivery) {
+	d.Attempts++
+	d.LastStatusCode = 0
+	d.LastError = ""
+
+	status, err := postSigned(d)
+	d.LastStatusCode = status
+	if err == nil {
+		d.Status = webhooks.DeliverySucceeded
+		d.NextAttemptAt = ""
+		d.DateDelivered = dates.GetNowDBString()
+		return
+	}
+
+	d.LastError = err.Error()
+	if d.Attempts >= webhookMaxAttempts {
+		d.Status = webhooks.DeliveryDead
+		d.NextAttemptAt = ""
+		logger.Info("webhook delivery moved to dead letter", zap.Int64("delivery", d.ID), zap.Int("attempts", d.Attempts))
+		return
+	}
+	d.NextAttemptAt = dates.GetDBString(dates.GetNow().Add(retryBackoff(d.Attempts, webhookRetryBase, webhookRetryMax)))
+}
+
+// retryBackoff doubles the delay from base with every attempt, capped at max and with up to 10% jitter
+func retryBackoff(attempts int, base, max time.Duration) time.Duration {
+	delay := max
+	if attempts < 32 {
+		if d := base * time.Duration(1<<uint(attempts-1)); d > 0 && d < max {
+			delay = d
+		}
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
+
+func postSigned(d *webhooks.Delivery) (int, error) {
+	body := d.Payload
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Event-ID", strconv.FormatInt(d.EventID, 10))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "t="+timestamp+",v1="+signWebhook(d.Secret, timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription secret
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/domain/webhooks"
+	"github.com/sauravgsh16/bookstore_users-api/utils/dates"
+)
+
+func TestSendWebhook(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+		down     bool
+
+		wantStatus string
+		wantCode   int
+		wantRetry  bool
+	}{
+		{name: "acknowledged", status: http.StatusNoContent, wantStatus: webhooks.DeliverySucceeded, wantCode: http.StatusNoContent},
+		{name: "rejected is retried", status: http.StatusInternalServerError, wantStatus: webhooks.DeliveryPending, wantCode: http.StatusInternalServerError, wantRetry: true},
+		{name: "unreachable is retried", down: true, wantStatus: webhooks.DeliveryPending, wantRetry: true},
+		{name: "non 2xx is not a success", status: http.StatusNotModified, wantStatus: webhooks.DeliveryPending, wantCode: http.StatusNotModified, wantRetry: true},
+		{name: "last attempt moves to dead letter", status: http.StatusBadGateway, attempts: webhookMaxAttempts - 1, wantStatus: webhooks.DeliveryDead, wantCode: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signature string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				timestamp := r.Header.Get("X-Webhook-Timestamp")
+				if want := "t=" + timestamp + ",v1=" + signWebhook("secret", timestamp, body); r.Header.Get("X-Webhook-Signature") != want {
+					signature = r.Header.Get("X-Webhook-Signature")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			if tt.down {
+				srv.Close()
+			}
+
+			d := &webhooks.Delivery{ID: 1, EventID: 7, EventType: "user.created", Payload: []byte(`{"id":7}`),
+				Status: webhooks.DeliveryPending, Attempts: tt.attempts, URL: srv.URL, Secret: "secret"}
+			before := dates.GetNow().Truncate(time.Second)
+			sendWebhook(d)
+
+			if signature != "" {
+				t.Errorf("endpoint got signature %q, not the one of the body", signature)
+			}
+			if d.Status != tt.wantStatus || d.Attempts != tt.attempts+1 || d.LastStatusCode != tt.wantCode {
+				t.Errorf("got status %s, attempts %d and code %d, want %s, %d and %d",
+					d.Status, d.Attempts, d.LastStatusCode, tt.wantStatus, tt.attempts+1, tt.wantCode)
+			}
+			if (d.DateDelivered != "") != (tt.wantStatus == webhooks.DeliverySucceeded) {
+				t.Errorf("delivered at %q with status %s", d.DateDelivered, d.Status)
+			}
+			if (d.LastError != "") == (tt.wantStatus == webhooks.DeliverySucceeded) {
+				t.Errorf("last error %q with status %s", d.LastError, d.Status)
+			}
+			if (d.NextAttemptAt != "") != tt.wantRetry {
+				t.Fatalf("next attempt at %q, want a retry %v", d.NextAttemptAt, tt.wantRetry)
+			}
+			if tt.wantRetry {
+				next, err := time.Parse("2006-01-2 15:04:05", d.NextAttemptAt)
+				if err != nil {
+					t.Fatalf("parsing next attempt: %v", err)
+				}
+				delay := next.Sub(before)
+				if min, max := webhookRetryBase, webhookRetryBase+webhookRetryBase/10+2*time.Second; delay < min || delay > max {
+					t.Errorf("retried in %v, want between %v and %v", delay, min, max)
+				}
+			}
+		})
+	}
+}
+
+func TestPostSignedHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer srv.Close()
+
+	d := &webhooks.Delivery{ID: 3, EventID: 9, EventType: "user.deleted", Payload: []byte(`{}`), URL: srv.URL, Secret: "s"}
+	if _, err := postSigned(d); err != nil {
+		t.Fatalf("posting: %v", err)
+	}
+	want := map[string]string{
+		"Content-Type":       "application/json",
+		"X-Webhook-Id":       "3",
+		"X-Webhook-Event":    "user.deleted",
+		"X-Webhook-Event-Id": "9",
+	}
+	for header, value := range want {
+		if got.Get(header) != value {
+			t.Errorf("%s = %q, want %q", header, got.Get(header), value)
+		}
+	}
+	if !strings.HasPrefix(got.Get("X-Webhook-Signature"), "t="+got.Get("X-Webhook-Timestamp")+",v1=") {
+		t.Errorf("signature %q does not carry the timestamp", got.Get("X-Webhook-Signature"))
+	}
+}
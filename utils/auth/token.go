@@ -0,0 +1,67 @@
+// Package auth issues and verifies the access tokens of signed in users. A token holds the user
+// ID and its expiry, signed with HMAC-SHA256 under AUTH_TOKEN_SECRET so that every replica
+// accepts the tokens issued by the others.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+)
+
+var (
+	secret = []byte(config.GetString("AUTH_TOKEN_SECRET", ""))
+	ttl    = config.GetDuration("AUTH_TOKEN_TTL", time.Hour)
+)
+
+func init() {
+	if len(secret) == 0 {
+		secret = []byte(crypto.GetRandomToken(32))
+		logger.Info("AUTH_TOKEN_SECRET not set, access tokens are only accepted by this process until it restarts")
+	}
+}
+
+// NewToken returns an access token for the user, valid for AUTH_TOKEN_TTL
+func NewToken(userID int) string {
+	return newToken(userID, time.Now().Add(ttl))
+}
+
+func newToken(userID int, expires time.Time) string {
+	claims := strconv.Itoa(userID) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return claims + "." + sign(claims)
+}
+
+// ParseToken returns the user of token, if it is signed by this service and has not expired
+func ParseToken(token string) (int, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	claims := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(claims))) {
+		return 0, false
+	}
+
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil || userID <= 0 {
+		return 0, false
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expires {
+		return 0, false
+	}
+	return userID, true
+}
+
+func sign(claims string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(claims))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseToken(t *testing.T) {
+	valid := NewToken(42)
+	tests := []struct {
+		name       string
+		token      string
+		wantUserID int
+		wantOK     bool
+	}{
+		{"valid", valid, 42, true},
+		{"expired", newToken(42, time.Now().Add(-time.Second)), 0, false},
+		{"other user", strings.Replace(valid, "42.", "43.", 1), 0, false},
+		{"extended expiry", strings.Replace(valid, ".", ".9", 1), 0, false},
+		{"forged signature", valid[:strings.LastIndexByte(valid, '.')+1] + "AAAA", 0, false},
+		{"not a user", newToken(0, time.Now().Add(time.Hour)), 0, false},
+		{"malformed", "42", 0, false},
+		{"empty", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, ok := ParseToken(tt.token)
+			if userID != tt.wantUserID || ok != tt.wantOK {
+				t.Errorf("ParseToken(%q) = %d, %v, want %d, %v", tt.token, userID, ok, tt.wantUserID, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores byte values under string keys for a limited time
+type Cache interface {
+	// Get returns the value stored under key, ok is false if it is missing or expired
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Tiered reads through a local cache in front of a shared remote one, so an instance keeps
+// serving hot keys from memory while writes invalidate every instance's view through the remote
+type Tiered struct {
+	Local  Cache
+	Remote Cache
+	// LocalTTL caps how long a value fetched from the remote is kept locally, bounding how
+	// stale an instance can be after another one invalidated the key
+	LocalTTL time.Duration
+}
+
+// Get the value from the local cache, falling back to the remote one
+func (t *Tiered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if v, ok, _ := t.Local.Get(ctx, key); ok {
+		return v, true, nil
+	}
+	v, ok, err := t.Remote.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	t.Local.Set(ctx, key, v, t.LocalTTL)
+	return v, true, nil
+}
+
+// Set the value in both caches
+func (t *Tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	local := ttl
+	if t.LocalTTL > 0 && t.LocalTTL < local {
+		local = t.LocalTTL
+	}
+	t.Local.Set(ctx, key, value, local)
+	return t.Remote.Set(ctx, key, value, ttl)
+}
+
+// Delete the keys from both caches
+func (t *Tiered) Delete(ctx context.Context, keys ...string) error {
+	t.Local.Delete(ctx, keys...)
+	return t.Remote.Delete(ctx, keys...)
+}
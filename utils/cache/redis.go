@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/resp"
+)
+
+// Redis is a remote cache on a server speaking the Redis protocol
+type Redis struct {
+	client *resp.Client
+	prefix string
+}
+
+// NewRedis returns a cache storing its keys under prefix on the server the client talks to
+func NewRedis(client *resp.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+// Get the value stored under key
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.client.Do(ctx, "GET", c.prefix+key)
+	if err == resp.ErrNil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s, _ := reply.(string)
+	return []byte(s), true, nil
+}
+
+// Set the value under key for ttl
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ms := ttl.Nanoseconds() / int64(time.Millisecond)
+	if ms <= 0 {
+		ms = 1
+	}
+	_, err := c.client.Do(ctx, "SET", c.prefix+key, string(value), "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+// Delete the keys
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	args := make([]string, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, k := range keys {
+		args = append(args, c.prefix+k)
+	}
+	_, err := c.client.Do(ctx, args...)
+	return err
+}
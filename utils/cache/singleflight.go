@@ -0,0 +1,52 @@
+package cache
+
+import "sync"
+
+// Group collapses concurrent calls for the same key into one, so a missing key only loads
+// once however many requests ask for it at the same time
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	wg  sync.WaitGroup
+	val interface{}
+}
+
+// Do runs fn for key unless a call for it is already in flight, in which case it waits for
+// that call and returns its result
+func (g *Group) Do(key string, fn func() interface{}) interface{} {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val
+	}
+	c := new(call)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		c.wg.Done()
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+	}()
+	c.val = fn()
+	return c.val
+}
+
+// DoChan is Do returning a channel the result is sent on, so that a caller may stop waiting
+// for it without cancelling the call
+func (g *Group) DoChan(key string, fn func() interface{}) <-chan interface{} {
+	ch := make(chan interface{}, 1)
+	go func() {
+		ch <- g.Do(key, fn)
+	}()
+	return ch
+}
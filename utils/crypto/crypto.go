@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"crypto/md5"
+	"crypto/rand"
 	"encoding/hex"
 )
 
@@ -16,3 +17,12 @@ func GetMd5(s string) string {
 	}
 	return hex.EncodeToString(hash.Sum(nil))
 }
+
+// GetRandomToken returns a hex encoded random string of n bytes
+func GetRandomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err.Error())
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,15 +1,15 @@
 package dates
 
-import (
-	"fmt"
-	"time"
-)
+import "time"
 
 const (
 	apiDateLayout   = "2006-01-20T15:04:05Z"
 	apiDateDBLayout = "2006-01-2 15:04:05"
 )
 
+// storedLayouts are the formats dates come in from the database, as written or as scanned
+var storedLayouts = []string{time.RFC3339Nano, apiDateDBLayout, "2006-01-02 15:04:05.999999999"}
+
 // GetNow returns current time in UTC
 func GetNow() time.Time {
 	return time.Now().UTC()
@@ -22,6 +22,21 @@ func GetNowString() string {
 
 // GetNowDBString returns current time db DATETIME format
 func GetNowDBString() string {
-	fmt.Printf("DATE: %s\n", GetNow().Format(apiDateDBLayout))
 	return GetNow().Format(apiDateDBLayout)
 }
+
+// GetDBString returns t in db DATETIME format
+func GetDBString(t time.Time) string {
+	return t.UTC().Format(apiDateDBLayout)
+}
+
+// ToISO8601 reformats a stored date as ISO 8601 in UTC, such as 2020-01-02T15:04:05Z. Dates in
+// other formats are returned unchanged.
+func ToISO8601(s string) string {
+	for _, layout := range storedLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return s
+}
@@ -37,3 +37,112 @@ func NewInternalServerError(msg string) *RestErr {
 		Error:   "internal_server_error",
 	}
 }
+
+// NewUnauthorizedError returns a unauthorized error
+func NewUnauthorizedError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusUnauthorized,
+		Error:   "unauthorized",
+	}
+}
+
+// NewConflictError returns a conflict error
+func NewConflictError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusConflict,
+		Error:   "conflict",
+	}
+}
+
+// NewForbiddenError returns a forbidden error
+func NewForbiddenError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusForbidden,
+		Error:   "forbidden",
+	}
+}
+
+// NewPreconditionFailedError returns a precondition failed error
+func NewPreconditionFailedError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusPreconditionFailed,
+		Error:   "precondition_failed",
+	}
+}
+
+// NewPreconditionRequiredError returns a precondition required error
+func NewPreconditionRequiredError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusPreconditionRequired,
+		Error:   "precondition_required",
+	}
+}
+
+// NewServiceUnavailableError returns a service unavailable error
+func NewServiceUnavailableError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusServiceUnavailable,
+		Error:   "service_unavailable",
+	}
+}
+
+// NewSerializationError returns a conflict error for transactions aborted by a concurrent one,
+// the operation can safely be retried
+func NewSerializationError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusConflict,
+		Error:   "serialization_failure",
+	}
+}
+
+// NewNotAcceptableError returns a not acceptable error
+func NewNotAcceptableError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusNotAcceptable,
+		Error:   "not_acceptable",
+	}
+}
+
+// NewUnsupportedMediaTypeError returns an unsupported media type error
+func NewUnsupportedMediaTypeError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusUnsupportedMediaType,
+		Error:   "unsupported_media_type",
+	}
+}
+
+// NewUnprocessableEntityError returns an unprocessable entity error
+func NewUnprocessableEntityError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusUnprocessableEntity,
+		Error:   "unprocessable_entity",
+	}
+}
+
+// NewTooManyRequestsError returns a too many requests error
+func NewTooManyRequestsError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusTooManyRequests,
+		Error:   "too_many_requests",
+	}
+}
+
+// NewGoneError returns a gone error
+func NewGoneError(msg string) *RestErr {
+	return &RestErr{
+		Message: msg,
+		Status:  http.StatusGone,
+		Error:   "gone",
+	}
+}
@@ -0,0 +1,21 @@
+package errors
+
+import (
+	"github.com/golang/protobuf/proto"
+
+	usersv1 "github.com/sauravgsh16/bookstore_users-api/proto/users/v1"
+)
+
+// MarshalProto encodes the error as a users.v1.RestErr message
+func (e *RestErr) MarshalProto() ([]byte, error) {
+	return proto.Marshal(e.Proto())
+}
+
+// Proto returns the error as a users.v1.RestErr message
+func (e *RestErr) Proto() *usersv1.RestErr {
+	return &usersv1.RestErr{
+		Message: e.Message,
+		Status:  int32(e.Status),
+		Error:   e.Error,
+	}
+}
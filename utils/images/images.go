@@ -0,0 +1,69 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+var (
+	// ErrUnsupported is returned for data that is not a jpeg, png or gif image
+	ErrUnsupported = errors.New("images: unsupported format, expected jpeg, png or gif")
+
+	contentTypes = map[string]string{
+		"jpeg": "image/jpeg",
+		"png":  "image/png",
+		"gif":  "image/gif",
+	}
+)
+
+// Normalize decodes a jpeg, png or gif image no wider or taller than maxSide pixels and
+// encodes it again in the same format, dropping any metadata such as EXIF locations.
+// The dimensions are checked before the pixels are decoded.
+func Normalize(data []byte, maxSide int) (image.Image, []byte, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, "", ErrUnsupported
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return nil, nil, "", ErrUnsupported
+	}
+	if cfg.Width > maxSide || cfg.Height > maxSide {
+		return nil, nil, "", fmt.Errorf("image is %dx%d, at most %dx%d is accepted", cfg.Width, cfg.Height, maxSide, maxSide)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, "", ErrUnsupported
+	}
+	encoded, err := Encode(img, contentType)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return img, encoded, contentType, nil
+}
+
+// Encode img in the format of contentType
+func Encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
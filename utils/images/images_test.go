@@ -0,0 +1,164 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns a w by h image filled with c
+func testImage(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeTest(t *testing.T, img image.Image, format string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("encoding %s: %v", format, err)
+	}
+	return buf.Bytes()
+}
+
+func TestNormalize(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+
+	tests := []struct {
+		name            string
+		data            func(t *testing.T) []byte
+		maxSide         int
+		wantContentType string
+		wantErr         bool
+	}{
+		{name: "jpeg", data: func(t *testing.T) []byte { return encodeTest(t, testImage(8, 4, red), "jpeg") }, maxSide: 8, wantContentType: "image/jpeg"},
+		{name: "png", data: func(t *testing.T) []byte { return encodeTest(t, testImage(8, 4, red), "png") }, maxSide: 8, wantContentType: "image/png"},
+		{name: "gif", data: func(t *testing.T) []byte { return encodeTest(t, testImage(8, 4, red), "gif") }, maxSide: 8, wantContentType: "image/gif"},
+		{name: "too wide", data: func(t *testing.T) []byte { return encodeTest(t, testImage(9, 4, red), "png") }, maxSide: 8, wantErr: true},
+		{name: "too tall", data: func(t *testing.T) []byte { return encodeTest(t, testImage(4, 9, red), "png") }, maxSide: 8, wantErr: true},
+		{name: "not an image", data: func(t *testing.T) []byte { return []byte("<svg></svg>") }, maxSide: 8, wantErr: true},
+		{name: "empty", data: func(t *testing.T) []byte { return nil }, maxSide: 8, wantErr: true},
+		{
+			name: "truncated",
+			data: func(t *testing.T) []byte {
+				data := encodeTest(t, testImage(8, 4, red), "png")
+				return data[:len(data)/2]
+			},
+			maxSide: 8,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, encoded, contentType, err := Normalize(tt.data(t), tt.maxSide)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Normalize = %s, want an error", contentType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			if contentType != tt.wantContentType {
+				t.Errorf("content type = %s, want %s", contentType, tt.wantContentType)
+			}
+			if b := img.Bounds(); b.Dx() != 8 || b.Dy() != 4 {
+				t.Errorf("image is %dx%d, want 8x4", b.Dx(), b.Dy())
+			}
+			// the encoded image decodes in the same format
+			if _, format, err := image.Decode(bytes.NewReader(encoded)); err != nil || "image/"+format != contentType {
+				t.Errorf("encoded image decodes as %q, %v, want %s", format, err, contentType)
+			}
+		})
+	}
+}
+
+func TestNormalizeDropsMetadata(t *testing.T) {
+	data := encodeTest(t, testImage(4, 4, color.White), "jpeg")
+	// an APP1 segment, as EXIF is stored, right after the start of image marker
+	exif := append([]byte{0xff, 0xe1, 0x00, 0x0e}, []byte("Exif\x00\x00GPS!!")...)
+	withExif := append(append(append([]byte{}, data[:2]...), exif...), data[2:]...)
+
+	_, encoded, _, err := Normalize(withExif, 8)
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if bytes.Contains(encoded, []byte("GPS!!")) {
+		t.Error("normalized image kept the EXIF segment")
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{name: "landscape", w: 400, h: 200, size: 100, wantW: 100, wantH: 50},
+		{name: "portrait", w: 200, h: 400, size: 100, wantW: 50, wantH: 100},
+		{name: "square", w: 300, h: 300, size: 100, wantW: 100, wantH: 100},
+		{name: "smaller than size", w: 40, h: 20, size: 100, wantW: 40, wantH: 20},
+		{name: "thin", w: 1000, h: 2, size: 100, wantW: 100, wantH: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Thumbnail(testImage(tt.w, tt.h, color.White), tt.size)
+			if b := got.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("thumbnail is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestThumbnailAverages(t *testing.T) {
+	// the left half black and the right half white scale down to a black and a white pixel
+	img := testImage(4, 2, color.Black)
+	for y := 0; y < 2; y++ {
+		for x := 2; x < 4; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	// the bounds of an image may not start at the origin
+	larger := testImage(6, 3, color.Black)
+	for y := 1; y < 3; y++ {
+		for x := 4; x < 6; x++ {
+			larger.Set(x, y, color.White)
+		}
+	}
+	offset := larger.SubImage(image.Rect(2, 1, 6, 3))
+
+	for _, src := range []image.Image{img, offset} {
+		got := Thumbnail(src, 2)
+		if r, _, _, _ := got.At(0, 0).RGBA(); r != 0 {
+			t.Errorf("left pixel red = %d, want 0", r)
+		}
+		if r, _, _, _ := got.At(1, 0).RGBA(); r != 0xffff {
+			t.Errorf("right pixel red = %d, want %d", r, 0xffff)
+		}
+	}
+
+	// a pixel covering a black and a white one is grey
+	got := Thumbnail(img, 1)
+	if r, _, _, a := got.At(0, 0).RGBA(); r < 0x7000 || r > 0x8fff || a != 0xffff {
+		t.Errorf("averaged pixel red = %#x alpha = %#x, want grey and opaque", r, a)
+	}
+}
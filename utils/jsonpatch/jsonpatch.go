@@ -0,0 +1,308 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902) documents
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalid is returned for malformed patch documents
+	ErrInvalid = errors.New("invalid patch")
+	// ErrTestFailed is returned when a test operation does not hold
+	ErrTestFailed = errors.New("test failed")
+	// ErrPath is returned for operations on locations the document does not have
+	ErrPath = errors.New("invalid path")
+)
+
+// MergePatch applies a merge patch to doc: members of patch objects replace those of doc,
+// recursively, null members removing them
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, err.Error())
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergePatch(t[key], value)
+	}
+	return t
+}
+
+// Operation is a JSON Patch operation
+type Operation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// Apply applies a JSON Patch to doc. Operations are applied in order and the patch fails as a
+// whole if any of them does.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalid, err.Error())
+	}
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		if target, err = op.apply(target); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: %s has no path", ErrInvalid, op.Op)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %s has no value", ErrInvalid, op.Op)
+		}
+		if err := json.Unmarshal(*op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalid, err.Error())
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: %s has no from", ErrInvalid, op.Op)
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" && isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: cannot move %s into itself", ErrInvalid, *op.From)
+		}
+		if value, err = get(doc, from); err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if doc, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "test":
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("%w: %s", ErrTestFailed, *op.Path)
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalid, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// index parses an array index, "-" standing for the end of the array when allowed
+func index(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrPath, token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || (i == length && !allowEnd) {
+		return 0, fmt.Errorf("%w: array index %q out of range", ErrPath, token)
+	}
+	return i, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q does not exist", ErrPath, token)
+			}
+			doc = value
+		case []interface{}:
+			i, err := index(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: %q does not exist", ErrPath, token)
+		}
+	}
+	return doc, nil
+}
+
+// add sets the value at path, inserting it into arrays, and returns the document
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		i, err := index(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return set(doc, path[:len(path)-1], node)
+	}
+	return nil, fmt.Errorf("%w: cannot add to %q", ErrPath, last)
+}
+
+// remove deletes the value at path and returns the document
+func remove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok {
+			return nil, fmt.Errorf("%w: %q does not exist", ErrPath, last)
+		}
+		delete(node, last)
+		return doc, nil
+	case []interface{}:
+		i, err := index(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:i:i], node[i+1:]...)
+		return set(doc, path[:len(path)-1], node)
+	}
+	return nil, fmt.Errorf("%w: %q does not exist", ErrPath, last)
+}
+
+// set replaces the existing value at path, arrays being reallocated as they grow or shrink
+func set(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		i, err := index(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = value
+	}
+	return doc, nil
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			c[key] = deepCopy(item)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = deepCopy(item)
+		}
+		return c
+	}
+	return value
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// WriteTo writes every metric of the registry in the text exposition format, sorted by name
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mux.RLock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	families := make(map[string]family, len(r.families))
+	for name, f := range r.families {
+		families[name] = f
+	}
+	r.mux.RUnlock()
+	sort.Strings(names)
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, name := range names {
+		f := families[name]
+		help, typ := f.describe()
+		cw.WriteString("# HELP " + name + " " + helpEscaper.Replace(help) + "\n")
+		cw.WriteString("# TYPE " + name + " " + typ + "\n")
+		for _, s := range f.collect() {
+			cw.WriteString(name + s.suffix)
+			if len(s.labels) > 0 {
+				cw.WriteString("{")
+				for i, l := range s.labels {
+					if i > 0 {
+						cw.WriteString(",")
+					}
+					cw.WriteString(l + `="` + labelEscaper.Replace(s.values[i]) + `"`)
+				}
+				cw.WriteString("}")
+			}
+			cw.WriteString(" " + formatFloat(s.value) + "\n")
+		}
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter keeps the first error, so the exposition is written without checking each line
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) WriteString(s string) {
+	if c.err != nil {
+		return
+	}
+	n, err := c.w.WriteString(s)
+	c.n += int64(n)
+	c.err = err
+}
@@ -0,0 +1,306 @@
+// Package metrics keeps counters, gauges and histograms and writes them in the Prometheus text
+// exposition format. Every metric caps its number of label combinations: once reached, new
+// combinations are counted under a single series whose labels are all Overflow, so that a label
+// fed with unbounded values cannot exhaust memory or the scraper.
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+	"go.uber.org/zap"
+)
+
+// Overflow is the label value of the series counting combinations beyond the cap of a metric
+const Overflow = "other"
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+var (
+	// DefBuckets are the upper bounds, in seconds, of latency histograms
+	DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// DefaultRegistry holds the metrics of the application, each capped to METRICS_MAX_SERIES
+	// label combinations
+	DefaultRegistry = NewRegistry(config.GetInt("METRICS_MAX_SERIES", 500))
+
+	metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Registry holds metrics by name
+type Registry struct {
+	mux       sync.RWMutex
+	maxSeries int
+	families  map[string]family
+}
+
+// family is a registered metric
+type family interface {
+	describe() (help, typ string)
+	collect() []sample
+}
+
+// sample is a line of the exposition
+type sample struct {
+	suffix string
+	labels []string
+	values []string
+	value  float64
+}
+
+// NewRegistry returns an empty registry capping metrics to maxSeries label combinations
+func NewRegistry(maxSeries int) *Registry {
+	if maxSeries < 1 {
+		maxSeries = 1
+	}
+	return &Registry{maxSeries: maxSeries, families: make(map[string]family)}
+}
+
+func (r *Registry) register(name string, labels []string, f family) {
+	if !metricName.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", name))
+	}
+	for _, l := range labels {
+		if !labelName.MatchString(l) || l == "le" {
+			panic(fmt.Sprintf("metrics: invalid label name %q for %s", l, name))
+		}
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if _, ok := r.families[name]; ok {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.families[name] = f
+}
+
+// Sample is a value of a function metric, with the values of its labels
+type Sample struct {
+	Values []string
+	Value  float64
+}
+
+// funcFamily reads its samples from a function on every scrape
+type funcFamily struct {
+	help, typ string
+	labels    []string
+	fn        func() []Sample
+}
+
+func (f *funcFamily) describe() (string, string) { return f.help, f.typ }
+
+func (f *funcFamily) collect() []sample {
+	var samples []sample
+	for _, s := range f.fn() {
+		samples = append(samples, sample{labels: f.labels, values: s.Values, value: s.Value})
+	}
+	return samples
+}
+
+// NewGaugeFunc registers a gauge read from fn on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(name, labels, &funcFamily{help: help, typ: typeGauge, labels: labels, fn: fn})
+}
+
+// NewCounterFunc registers a counter read from fn on every scrape, for totals kept elsewhere
+func (r *Registry) NewCounterFunc(name, help string, labels []string, fn func() []Sample) {
+	r.register(name, labels, &funcFamily{help: help, typ: typeCounter, labels: labels, fn: fn})
+}
+
+// vec holds the series of a metric by label values
+type vec struct {
+	name, help, typ string
+	labels          []string
+	buckets         []float64
+	maxSeries       int
+
+	mux    sync.Mutex
+	series map[string]*series
+	capped bool
+}
+
+// series is the state of a label combination, counts only being used by histograms
+type series struct {
+	values []string
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+func (r *Registry) newVec(name, help, typ string, buckets []float64, labels []string) *vec {
+	v := &vec{
+		name:      name,
+		help:      help,
+		typ:       typ,
+		labels:    labels,
+		buckets:   buckets,
+		maxSeries: r.maxSeries,
+		series:    make(map[string]*series),
+	}
+	r.register(name, labels, v)
+	return v
+}
+
+// get returns the series of values, the overflow one once the cap is reached. The caller holds mux.
+func (v *vec) get(values []string) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	if s, ok := v.series[key]; ok {
+		return s
+	}
+	if len(v.series) >= v.maxSeries {
+		if !v.capped {
+			v.capped = true
+			logger.Info("metric reached its series limit, counting new label values as "+Overflow,
+				zap.String("metric", v.name), zap.Int("limit", v.maxSeries))
+		}
+		values = make([]string, len(v.labels))
+		for i := range values {
+			values[i] = Overflow
+		}
+		key = strings.Join(values, "\xff")
+		if s, ok := v.series[key]; ok {
+			return s
+		}
+	}
+	s := &series{values: append([]string(nil), values...)}
+	if v.typ == typeHistogram {
+		s.counts = make([]uint64, len(v.buckets))
+	}
+	v.series[key] = s
+	return s
+}
+
+func (v *vec) describe() (string, string) { return v.help, v.typ }
+
+func (v *vec) collect() []sample {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var samples []sample
+	for _, k := range keys {
+		s := v.series[k]
+		if v.typ != typeHistogram {
+			samples = append(samples, sample{labels: v.labels, values: s.values, value: s.value})
+			continue
+		}
+		labels := append(append([]string(nil), v.labels...), "le")
+		var cumulative uint64
+		for i, upper := range v.buckets {
+			cumulative += s.counts[i]
+			samples = append(samples, sample{suffix: "_bucket", labels: labels,
+				values: append(append([]string(nil), s.values...), formatFloat(upper)), value: float64(cumulative)})
+		}
+		samples = append(samples,
+			sample{suffix: "_bucket", labels: labels, values: append(append([]string(nil), s.values...), "+Inf"), value: float64(s.count)},
+			sample{suffix: "_sum", labels: v.labels, values: s.values, value: s.value},
+			sample{suffix: "_count", labels: v.labels, values: s.values, value: float64(s.count)},
+		)
+	}
+	return samples
+}
+
+// Counter is a total that only goes up
+type Counter struct{ v *vec }
+
+// NewCounter registers a counter labelled by labels
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{v: r.newVec(name, help, typeCounter, nil, labels)}
+}
+
+// Inc adds one to the series of values
+func (c *Counter) Inc(values ...string) { c.Add(1, values...) }
+
+// Add adds delta, which must not be negative, to the series of values
+func (c *Counter) Add(delta float64, values ...string) {
+	c.v.mux.Lock()
+	c.v.get(values).value += delta
+	c.v.mux.Unlock()
+}
+
+// Gauge is a value that goes up and down
+type Gauge struct{ v *vec }
+
+// NewGauge registers a gauge labelled by labels
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{v: r.newVec(name, help, typeGauge, nil, labels)}
+}
+
+// Add adds delta to the series of values
+func (g *Gauge) Add(delta float64, values ...string) {
+	g.v.mux.Lock()
+	g.v.get(values).value += delta
+	g.v.mux.Unlock()
+}
+
+// Set sets the series of values
+func (g *Gauge) Set(value float64, values ...string) {
+	g.v.mux.Lock()
+	g.v.get(values).value = value
+	g.v.mux.Unlock()
+}
+
+// Histogram counts observations in buckets
+type Histogram struct{ v *vec }
+
+// NewHistogram registers a histogram with the upper bounds buckets, labelled by labels
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Histogram{v: r.newVec(name, help, typeHistogram, buckets, labels)}
+}
+
+// Observe records value in the series of values
+func (h *Histogram) Observe(value float64, values ...string) {
+	h.v.mux.Lock()
+	s := h.v.get(values)
+	if i := sort.SearchFloat64s(h.v.buckets, value); i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.count++
+	s.value += value
+	h.v.mux.Unlock()
+}
+
+// NewCounter registers a counter in DefaultRegistry
+func NewCounter(name, help string, labels ...string) *Counter {
+	return DefaultRegistry.NewCounter(name, help, labels...)
+}
+
+// NewGauge registers a gauge in DefaultRegistry
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help, labels...)
+}
+
+// NewHistogram registers a histogram in DefaultRegistry
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return DefaultRegistry.NewHistogram(name, help, buckets, labels...)
+}
+
+// NewGaugeFunc registers a gauge function in DefaultRegistry
+func NewGaugeFunc(name, help string, labels []string, fn func() []Sample) {
+	DefaultRegistry.NewGaugeFunc(name, help, labels, fn)
+}
+
+// NewCounterFunc registers a counter function in DefaultRegistry
+func NewCounterFunc(name, help string, labels []string, fn func() []Sample) {
+	DefaultRegistry.NewCounterFunc(name, help, labels, fn)
+}
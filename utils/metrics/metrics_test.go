@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// exposition returns the lines r writes, without the HELP and TYPE comments
+func exposition(t *testing.T, r *Registry) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestSeriesCap(t *testing.T) {
+	r := NewRegistry(3)
+	c := r.NewCounter("requests_total", "Requests", "route", "status")
+	for _, route := range []string{"/a", "/b", "/c", "/d", "/e"} {
+		c.Inc(route, "200")
+	}
+	c.Inc("/a", "200")
+	c.Inc("/f", "500")
+
+	// three combinations keep their own series, later ones share the overflow series
+	want := []string{
+		`requests_total{route="/a",status="200"} 2`,
+		`requests_total{route="/b",status="200"} 1`,
+		`requests_total{route="/c",status="200"} 1`,
+		`requests_total{route="other",status="other"} 3`,
+	}
+	if got := exposition(t, r); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSeriesCapHistogram(t *testing.T) {
+	r := NewRegistry(1)
+	h := r.NewHistogram("duration_seconds", "Durations", []float64{1}, "operation")
+	for _, op := range []string{"a", "b", "c"} {
+		h.Observe(0.5, op)
+	}
+
+	want := []string{
+		`duration_seconds_bucket{operation="a",le="1"} 1`,
+		`duration_seconds_bucket{operation="a",le="+Inf"} 1`,
+		`duration_seconds_sum{operation="a"} 0.5`,
+		`duration_seconds_count{operation="a"} 1`,
+		`duration_seconds_bucket{operation="other",le="1"} 2`,
+		`duration_seconds_bucket{operation="other",le="+Inf"} 2`,
+		`duration_seconds_sum{operation="other"} 1`,
+		`duration_seconds_count{operation="other"} 2`,
+	}
+	if got := exposition(t, r); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry(10)
+	r.NewGauge("b_gauge", "Second\nline").Set(1.5)
+	r.NewCounter("a_total", `Escaped \ help`, "value").Inc("quote \" and \\ and \n")
+	r.NewGaugeFunc("c_func", "Read on scrape", []string{"pool"}, func() []Sample {
+		return []Sample{{Values: []string{"primary"}, Value: 3}}
+	})
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	want := `# HELP a_total Escaped \\ help
+# TYPE a_total counter
+a_total{value="quote \" and \\ and \n"} 1
+# HELP b_gauge Second\nline
+# TYPE b_gauge gauge
+b_gauge 1.5
+# HELP c_func Read on scrape
+# TYPE c_func gauge
+c_func{pool="primary"} 3
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRegisterRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(r *Registry)
+	}{
+		{name: "invalid metric name", register: func(r *Registry) { r.NewCounter("requests-total", "") }},
+		{name: "invalid label name", register: func(r *Registry) { r.NewCounter("requests_total", "", "http-route") }},
+		{name: "reserved label name", register: func(r *Registry) { r.NewHistogram("duration_seconds", "", DefBuckets, "le") }},
+		{name: "registered twice", register: func(r *Registry) { r.NewGauge("g", ""); r.NewCounter("g", "") }},
+		{name: "wrong number of label values", register: func(r *Registry) { r.NewCounter("requests_total", "", "route").Inc() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("did not panic")
+				}
+			}()
+			tt.register(NewRegistry(10))
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Package oidctest runs an OpenID provider for tests. It serves the discovery document, the key
+// set and a token endpoint exchanging the codes handed out by Authorize for RS256 ID tokens.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const keyID = "test"
+
+// User is who signs in at the provider
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// Server is a running provider, its issuer being its URL
+type Server struct {
+	*httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]string
+}
+
+// NewServer starts a provider issuing ID tokens for clientID. Callers must Close it.
+func NewServer(clientID, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{ClientID: clientID, ClientSecret: clientSecret, key: key, codes: make(map[string]string)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Authorize signs user in at the authorization url returned by the relying party, returning the
+// code and state it would be redirected back with
+func (s *Server) Authorize(authURL string, user User) (code, state string, err error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+	if q.Get("client_id") != s.ClientID {
+		return "", "", fmt.Errorf("unknown client %q", q.Get("client_id"))
+	}
+	return s.NewCode(s.Sign(s.Claims(user, q.Get("nonce")))), q.Get("state"), nil
+}
+
+// Claims returns the claims of a valid ID token for user
+func (s *Server) Claims(user User, nonce string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":            s.URL,
+		"aud":            s.ClientID,
+		"sub":            user.Subject,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"given_name":     user.GivenName,
+		"family_name":    user.FamilyName,
+	}
+}
+
+// Sign returns an RS256 ID token holding claims
+func (s *Server) Sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		panic(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// NewCode returns a single use code the token endpoint exchanges for idToken
+func (s *Server) NewCode(idToken string) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	code := base64.RawURLEncoding.EncodeToString(b)
+	s.mu.Lock()
+	s.codes[code] = idToken
+	s.mu.Unlock()
+	return code
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": keyID,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("client_id") != s.ClientID || r.PostForm.Get("client_secret") != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	idToken, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,201 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/config"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+var (
+	providers = make(map[string]*Provider)
+
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Provider is an OIDC relying-party configuration for a single identity provider
+type Provider struct {
+	Name          string
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	AutoProvision bool
+	LinkByEmail   bool
+
+	mux       sync.RWMutex
+	discovery *discovery
+	keys      map[string]*rsa.PublicKey
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	ErrorDesc   string `json:"error_description"`
+}
+
+// Providers are read from the environment. OIDC_PROVIDERS holds a comma separated
+// list of names, each configured with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URL, OIDC_<NAME>_SCOPES,
+// OIDC_<NAME>_AUTO_PROVISION and OIDC_<NAME>_LINK_BY_EMAIL.
+func init() {
+	for _, name := range config.GetList("OIDC_PROVIDERS") {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		p := &Provider{
+			Name:          strings.ToLower(name),
+			Issuer:        strings.TrimSuffix(config.GetString(prefix+"ISSUER", ""), "/"),
+			ClientID:      config.GetString(prefix+"CLIENT_ID", ""),
+			ClientSecret:  config.GetString(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:   config.GetString(prefix+"REDIRECT_URL", ""),
+			Scopes:        config.GetList(prefix + "SCOPES"),
+			AutoProvision: config.GetBool(prefix+"AUTO_PROVISION", false),
+			LinkByEmail:   config.GetBool(prefix+"LINK_BY_EMAIL", true),
+		}
+		if err := Register(p); err != nil {
+			logger.Error("failed to register oidc provider: ", err)
+		}
+	}
+}
+
+// Register adds a provider to the registry
+func Register(p *Provider) error {
+	if p.Name == "" || p.Issuer == "" || p.ClientID == "" {
+		return fmt.Errorf("oidc provider %q requires a name, issuer and client id", p.Name)
+	}
+	if len(p.Scopes) == 0 {
+		p.Scopes = []string{"openid", "email", "profile"}
+	}
+	providers[p.Name] = p
+	return nil
+}
+
+// GetProvider returns the registered provider with the given name
+func GetProvider(name string) (*Provider, bool) {
+	p, ok := providers[strings.ToLower(name)]
+	return p, ok
+}
+
+// getDiscovery fetches and caches the provider metadata document
+func (p *Provider) getDiscovery(ctx context.Context) (*discovery, error) {
+	p.mux.RLock()
+	d := p.discovery
+	p.mux.RUnlock()
+	if d != nil {
+		return d, nil
+	}
+
+	d = &discovery{}
+	if err := getJSON(ctx, p.Issuer+discoveryPath, d); err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %s", err.Error())
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("issuer mismatch: expected %s got %s", p.Issuer, d.Issuer)
+	}
+
+	p.mux.Lock()
+	p.discovery = d
+	p.mux.Unlock()
+	return d, nil
+}
+
+// AuthCodeURL returns the provider url the user agent must be redirected to
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.ClientID)
+	v.Set("redirect_uri", p.RedirectURL)
+	v.Set("scope", strings.Join(p.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange swaps an authorization code for an ID token and returns its verified claims
+func (p *Provider) Exchange(ctx context.Context, code, nonce string) (*Claims, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+
+	req, err := http.NewRequest(http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("invalid token response: %s", err.Error())
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return nil, fmt.Errorf("token request rejected: %s %s", tr.Error, tr.ErrorDesc)
+	}
+	if tr.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	return p.Verify(ctx, tr.IDToken, nonce)
+}
+
+func getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, u)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
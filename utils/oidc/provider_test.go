@@ -0,0 +1,139 @@
+package oidc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/oidc/oidctest"
+)
+
+// newTestProvider returns a provider backed by a stub server, which the caller must close
+func newTestProvider() (*Provider, *oidctest.Server) {
+	srv := oidctest.NewServer("client", "secret")
+	return &Provider{Name: "stub", Issuer: srv.URL, ClientID: "client", ClientSecret: "secret", RedirectURL: "http://localhost/callback"}, srv
+}
+
+func TestExchange(t *testing.T) {
+	p, srv := newTestProvider()
+	defer srv.Close()
+	user := oidctest.User{Subject: "alice", Email: "alice@test.invalid", EmailVerified: true}
+	other := oidctest.NewServer("client", "secret")
+	defer other.Close()
+
+	tests := []struct {
+		name    string
+		claims  func(map[string]interface{})
+		sign    func(map[string]interface{}) string
+		code    string
+		wantErr string
+	}{
+		{name: "valid"},
+		{name: "nonce of another request", claims: func(c map[string]interface{}) { c["nonce"] = "other" }, wantErr: "nonce mismatch"},
+		{name: "no nonce", claims: func(c map[string]interface{}) { delete(c, "nonce") }, wantErr: "nonce mismatch"},
+		{name: "other audience", claims: func(c map[string]interface{}) { c["aud"] = []string{"someone-else"} }, wantErr: "audience mismatch"},
+		{name: "other issuer", claims: func(c map[string]interface{}) { c["iss"] = "https://issuer.invalid" }, wantErr: "issuer mismatch"},
+		{name: "expired", claims: func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, wantErr: "expired"},
+		{name: "no subject", claims: func(c map[string]interface{}) { delete(c, "sub") }, wantErr: "no subject"},
+		{name: "signed by another key", sign: other.Sign, wantErr: "invalid id token signature"},
+		{name: "unknown code", code: "unknown", wantErr: "invalid_grant"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := srv.Claims(user, "nonce")
+			if tt.claims != nil {
+				tt.claims(claims)
+			}
+			sign := srv.Sign
+			if tt.sign != nil {
+				sign = tt.sign
+			}
+			code := srv.NewCode(sign(claims))
+			if tt.code != "" {
+				code = tt.code
+			}
+
+			got, err := p.Exchange(context.Background(), code, "nonce")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Exchange error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if got.Subject != user.Subject || got.Email != user.Email || !got.EmailVerified {
+				t.Errorf("Exchange claims = %+v, want those of %+v", got, user)
+			}
+		})
+	}
+}
+
+func TestExchangeCodeIsSingleUse(t *testing.T) {
+	p, srv := newTestProvider()
+	defer srv.Close()
+	code := srv.NewCode(srv.Sign(srv.Claims(oidctest.User{Subject: "alice"}, "nonce")))
+
+	if _, err := p.Exchange(context.Background(), code, "nonce"); err != nil {
+		t.Fatalf("first Exchange: %v", err)
+	}
+	if _, err := p.Exchange(context.Background(), code, "nonce"); err == nil {
+		t.Fatal("second Exchange of the same code succeeded")
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	p, srv := newTestProvider()
+	defer srv.Close()
+	state, nonce := NewState(p.Name, 0)
+
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, srv.URL+"/authorize?") {
+		t.Errorf("AuthCodeURL = %s, want the authorization endpoint of %s", authURL, srv.URL)
+	}
+	code, gotState, err := srv.Authorize(authURL, oidctest.User{Subject: "alice"})
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if gotState != state {
+		t.Errorf("state = %q, want %q", gotState, state)
+	}
+	if _, err := p.Exchange(context.Background(), code, nonce); err != nil {
+		t.Errorf("Exchange of the code of the url: %v", err)
+	}
+}
+
+func TestConsumeState(t *testing.T) {
+	state, nonce := NewState("stub", 42)
+	expired, _ := NewState("stub", 0)
+	statesMu.Lock()
+	states[expired].expires = time.Now().Add(-time.Second)
+	statesMu.Unlock()
+
+	tests := []struct {
+		name   string
+		state  string
+		wantOK bool
+	}{
+		{"pending", state, true},
+		{"already consumed", state, false},
+		{"unknown", "unknown", false},
+		{"expired", expired, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := ConsumeState(tt.state)
+			if ok != tt.wantOK {
+				t.Fatalf("ConsumeState ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && (st.Provider != "stub" || st.Nonce != nonce || st.UserID != 42) {
+				t.Errorf("ConsumeState = %+v, want provider stub, nonce %s and user 42", st, nonce)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package oidc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/crypto"
+)
+
+// how long an authorization request may stay pending at the provider
+const stateTTL = 10 * time.Minute
+
+// AuthState is the pending authorization request identified by the state parameter
+type AuthState struct {
+	Provider string
+	Nonce    string
+	// UserID is set when an existing user is linking a new identity
+	UserID  int
+	expires time.Time
+}
+
+var (
+	states   = make(map[string]*AuthState)
+	statesMu sync.Mutex
+)
+
+// NewState records a pending authorization request and returns its state and nonce
+func NewState(provider string, userID int) (string, string) {
+	state := crypto.GetRandomToken(16)
+	nonce := crypto.GetRandomToken(16)
+
+	statesMu.Lock()
+	defer statesMu.Unlock()
+
+	now := time.Now()
+	for k, s := range states {
+		if now.After(s.expires) {
+			delete(states, k)
+		}
+	}
+	states[state] = &AuthState{
+		Provider: provider,
+		Nonce:    nonce,
+		UserID:   userID,
+		expires:  now.Add(stateTTL),
+	}
+	return state, nonce
+}
+
+// ConsumeState returns and removes the pending request for state
+func ConsumeState(state string) (*AuthState, bool) {
+	statesMu.Lock()
+	defer statesMu.Unlock()
+
+	s, ok := states[state]
+	if !ok {
+		return nil, false
+	}
+	delete(states, state)
+	if time.Now().After(s.expires) {
+		return nil, false
+	}
+	return s, true
+}
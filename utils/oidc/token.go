@@ -0,0 +1,195 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// clock skew tolerated when validating exp and iat
+const leeway = time.Minute
+
+// Claims holds the ID token claims used to identify a user
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	Expiry        int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+}
+
+// audience is either a single string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*a = audience{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*a = audience(l)
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// Verify checks the ID token signature and standard claims
+func (p *Provider) Verify(ctx context.Context, token, nonce string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed id token")
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("invalid id token header: %s", err.Error())
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token signature encoding")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch h.Alg {
+	case "RS256":
+		key, err := p.getKey(ctx, h.Kid)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+			return nil, fmt.Errorf("invalid id token signature")
+		}
+	case "HS256":
+		mac := hmac.New(sha256.New, []byte(p.ClientSecret))
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return nil, fmt.Errorf("invalid id token signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported id token algorithm %q", h.Alg)
+	}
+
+	var c Claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("invalid id token claims: %s", err.Error())
+	}
+
+	now := time.Now()
+	switch {
+	case strings.TrimSuffix(c.Issuer, "/") != p.Issuer:
+		return nil, fmt.Errorf("id token issuer mismatch")
+	case !c.Audience.contains(p.ClientID):
+		return nil, fmt.Errorf("id token audience mismatch")
+	case now.Add(-leeway).After(time.Unix(c.Expiry, 0)):
+		return nil, fmt.Errorf("id token expired")
+	case c.IssuedAt != 0 && now.Add(leeway).Before(time.Unix(c.IssuedAt, 0)):
+		return nil, fmt.Errorf("id token issued in the future")
+	case c.Nonce != nonce:
+		return nil, fmt.Errorf("id token nonce mismatch")
+	case c.Subject == "":
+		return nil, fmt.Errorf("id token has no subject")
+	}
+	return &c, nil
+}
+
+// getKey returns the signing key for kid, refreshing the key set once if it is unknown
+func (p *Provider) getKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mux.RLock()
+	key, ok := p.keys[kid]
+	p.mux.RUnlock()
+	if !ok {
+		if err := p.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+		p.mux.RLock()
+		key, ok = p.keys[kid]
+		p.mux.RUnlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("no signing key found for kid %q", kid)
+	}
+	return key, nil
+}
+
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	var set jwks
+	if err := getJSON(ctx, d.JWKSURI, &set); err != nil {
+		return fmt.Errorf("failed to fetch jwks: %s", err.Error())
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mux.Lock()
+	p.keys = keys
+	p.mux.Unlock()
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
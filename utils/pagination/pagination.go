@@ -0,0 +1,37 @@
+package pagination
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+const (
+	// DefaultPerPage is used when per_page is not given
+	DefaultPerPage = 20
+	// MaxPerPage is the largest accepted per_page
+	MaxPerPage = 100
+)
+
+// Parse returns the page and per_page values, empty strings taking the defaults
+func Parse(pageStr, perPageStr string) (int, int, *errors.RestErr) {
+	page := 1
+	if pageStr != "" {
+		p, err := strconv.Atoi(pageStr)
+		if err != nil || p < 1 {
+			return 0, 0, errors.NewBadRequestError("page should be a positive number")
+		}
+		page = p
+	}
+
+	perPage := DefaultPerPage
+	if perPageStr != "" {
+		pp, err := strconv.Atoi(perPageStr)
+		if err != nil || pp < 1 || pp > MaxPerPage {
+			return 0, 0, errors.NewBadRequestError(fmt.Sprintf("per_page should be between 1 and %d", MaxPerPage))
+		}
+		perPage = pp
+	}
+	return page, perPage, nil
+}
@@ -0,0 +1,36 @@
+package pagination
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		page, perPage         string
+		wantPage, wantPerPage int
+		wantErr               bool
+	}{
+		{page: "", perPage: "", wantPage: 1, wantPerPage: DefaultPerPage},
+		{page: "3", perPage: "10", wantPage: 3, wantPerPage: 10},
+		{page: "1", perPage: fmt.Sprint(MaxPerPage), wantPage: 1, wantPerPage: MaxPerPage},
+		{page: "0", wantErr: true},
+		{page: "-1", wantErr: true},
+		{page: "two", wantErr: true},
+		{perPage: "0", wantErr: true},
+		{perPage: fmt.Sprint(MaxPerPage + 1), wantErr: true},
+		{perPage: "ten", wantErr: true},
+	}
+	for _, tt := range tests {
+		page, perPage, err := Parse(tt.page, tt.perPage)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q, %q) = %d, %d, want an error", tt.page, tt.perPage, page, perPage)
+			}
+			continue
+		}
+		if err != nil || page != tt.wantPage || perPage != tt.wantPerPage {
+			t.Errorf("Parse(%q, %q) = %d, %d, %v, want %d, %d", tt.page, tt.perPage, page, perPage, err, tt.wantPage, tt.wantPerPage)
+		}
+	}
+}
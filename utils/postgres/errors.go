@@ -54,7 +54,6 @@ func (dbe *DBError) Error() string {
 }
 
 func findValue(s string) string {
-	fmt.Println(s)
 	r := valPat.FindStringSubmatch(s)
 	if len(r) > 0 {
 		return r[1]
@@ -0,0 +1,157 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+func marshalJSON(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func unmarshalJSON(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// object is a JSON object as alternating keys and values, keeping the order of its fields
+type object []interface{}
+
+// MapBySlice has codec encode the object as a map
+func (object) MapBySlice() {}
+
+// decodeTree reads a JSON document into nil, bool, json.Number, string, []interface{} and
+// object values
+func decodeTree(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return readTree(dec)
+}
+
+func readTree(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			item, err := readTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		_, err := dec.Token()
+		return list, err
+	case json.Delim('{'):
+		obj := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, key, value)
+		}
+		_, err := dec.Token()
+		return obj, err
+	}
+	return tok, nil
+}
+
+var mapStringType = reflect.TypeOf(map[string]interface{}(nil))
+
+func msgpackHandle() *codec.MsgpackHandle {
+	h := new(codec.MsgpackHandle)
+	// distinguish strings from binary data as the current spec does
+	h.WriteExt = true
+	h.RawToString = true
+	return h
+}
+
+func marshalMsgPack(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := decodeTree(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := codec.NewEncoder(&buf, msgpackHandle()).Encode(msgpackValue(tree)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// msgpackValue turns the numbers of a JSON tree into integers where they are whole
+func msgpackValue(node interface{}) interface{} {
+	switch n := node.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		f, _ := n.Float64()
+		return f
+	case []interface{}:
+		for i := range n {
+			n[i] = msgpackValue(n[i])
+		}
+	case object:
+		for i := 1; i < len(n); i += 2 {
+			n[i] = msgpackValue(n[i])
+		}
+	}
+	return node
+}
+
+// unmarshalMsgPack decodes the document generically and hands it to the JSON decoder, so
+// fields are read the same way as from JSON bodies
+func unmarshalMsgPack(data []byte, v interface{}) error {
+	h := msgpackHandle()
+	h.MapType = mapStringType
+	var tree interface{}
+	if err := codec.NewDecoderBytes(data, h).Decode(&tree); err != nil {
+		return err
+	}
+	asJSON, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(asJSON, v)
+}
+
+func isProtoMarshaler(v interface{}) bool {
+	_, ok := v.(ProtoMarshaler)
+	return ok
+}
+
+func isProtoUnmarshaler(v interface{}) bool {
+	_, ok := v.(ProtoUnmarshaler)
+	return ok
+}
+
+func marshalProto(v interface{}) ([]byte, error) {
+	m, ok := v.(ProtoMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("%T has no protobuf representation", v)
+	}
+	return m.MarshalProto()
+}
+
+func unmarshalProto(data []byte, v interface{}) error {
+	m, ok := v.(ProtoUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%T has no protobuf representation", v)
+	}
+	return m.UnmarshalProto(data)
+}
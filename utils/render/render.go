@@ -0,0 +1,222 @@
+// Package render writes responses in the format the client accepts and reads request bodies
+// in the format they are sent in. JSON is the canonical encoding: XML and MessagePack
+// documents are transcoded from it, so every format names and omits fields the same way.
+package render
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/logger"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+// Format is an encoding of request and response bodies
+type Format struct {
+	// Name is how error messages refer to the format
+	Name string
+	// MediaType is the content type responses are sent with
+	MediaType string
+	// Aliases are other media types standing for the format
+	Aliases []string
+
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+	// canMarshal and canUnmarshal report whether a value can be written or read in the
+	// format, nil standing for any value
+	canMarshal   func(v interface{}) bool
+	canUnmarshal func(v interface{}) bool
+}
+
+// ProtoMarshaler is implemented by values with a protocol buffer representation
+type ProtoMarshaler interface {
+	MarshalProto() ([]byte, error)
+}
+
+// ProtoUnmarshaler is implemented by values that can be read from a protocol buffer message
+type ProtoUnmarshaler interface {
+	UnmarshalProto(data []byte) error
+}
+
+var (
+	// JSON is the default format
+	JSON = &Format{Name: "json", MediaType: "application/json", marshal: marshalJSON, unmarshal: unmarshalJSON}
+	// XML documents have a response root element, list items are item elements
+	XML = &Format{Name: "xml", MediaType: "application/xml", Aliases: []string{"text/xml"}, marshal: marshalXML, unmarshal: unmarshalXML}
+	// MsgPack is MessagePack
+	MsgPack = &Format{Name: "msgpack", MediaType: "application/msgpack", Aliases: []string{"application/x-msgpack"}, marshal: marshalMsgPack, unmarshal: unmarshalMsgPack}
+	// Protobuf is only available for the messages of users.proto
+	Protobuf = &Format{Name: "protobuf", MediaType: "application/x-protobuf", Aliases: []string{"application/protobuf"}, marshal: marshalProto, unmarshal: unmarshalProto, canMarshal: isProtoMarshaler, canUnmarshal: isProtoUnmarshaler}
+
+	// Formats in order of preference, when the client accepts several equally
+	Formats = []*Format{JSON, XML, MsgPack, Protobuf}
+)
+
+// mediaTypes returns every media type of the format
+func (f *Format) mediaTypes() []string {
+	return append([]string{f.MediaType}, f.Aliases...)
+}
+
+// ContentType is the Content-Type header of responses in the format
+func (f *Format) ContentType() string {
+	if f == JSON || f == XML {
+		return f.MediaType + "; charset=utf-8"
+	}
+	return f.MediaType
+}
+
+// Respond writes v with status in the format the request accepts best. Successful responses
+// that cannot be represented in any accepted format are answered with 406, errors are sent
+// as JSON instead so their cause is not lost.
+func Respond(c *gin.Context, status int, v interface{}) {
+	c.Writer.Header().Add("Vary", "Accept")
+
+	f := Negotiate(c.GetHeader("Accept"), v)
+	if f == nil {
+		if status < http.StatusBadRequest {
+			err := errors.NewNotAcceptableError(fmt.Sprintf("response can only be sent as %s", strings.Join(offered(v), ", ")))
+			status, v = err.Status, err
+		}
+		f = JSON
+	}
+
+	data, err := f.marshal(v)
+	if err != nil {
+		logger.Error(fmt.Sprintf("failed to encode %s response: ", f.Name), err)
+		restErr := errors.NewInternalServerError("failed to encode response")
+		f, status = JSON, restErr.Status
+		data, _ = marshalJSON(restErr)
+	}
+	c.Data(status, f.ContentType(), data)
+}
+
+// Negotiate returns the format among those able to represent v that the Accept header
+// prefers, nil if it accepts none of them. A missing header accepts anything.
+func Negotiate(accept string, v interface{}) *Format {
+	if strings.TrimSpace(accept) == "" {
+		return JSON
+	}
+	ranges := parseAccept(accept)
+
+	var (
+		best            *Format
+		bestQ           float64
+		bestSpecificity int
+	)
+	for _, f := range Formats {
+		if f.canMarshal != nil && !f.canMarshal(v) {
+			continue
+		}
+		q, specificity := quality(ranges, f)
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = f, q, specificity
+		}
+	}
+	return best
+}
+
+func offered(v interface{}) []string {
+	var types []string
+	for _, f := range Formats {
+		if f.canMarshal == nil || f.canMarshal(v) {
+			types = append(types, f.MediaType)
+		}
+	}
+	return types
+}
+
+// acceptRange is a media range of an Accept header
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		slash := strings.IndexByte(mediaType, '/')
+		if slash < 0 {
+			continue
+		}
+		r := acceptRange{typ: mediaType[:slash], subtype: mediaType[slash+1:], q: 1}
+		if qv, ok := params["q"]; ok {
+			if q, err := strconv.ParseFloat(qv, 64); err == nil && q >= 0 && q <= 1 {
+				r.q = q
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// quality returns the quality the most specific range matching the format gives it, along
+// with how specific that range is: 2 for a media type, 1 for type/* and 0 for */*
+func quality(ranges []acceptRange, f *Format) (float64, int) {
+	q, specificity := 0.0, -1
+	for _, mediaType := range f.mediaTypes() {
+		slash := strings.IndexByte(mediaType, '/')
+		typ, subtype := mediaType[:slash], mediaType[slash+1:]
+		for _, r := range ranges {
+			s := -1
+			switch {
+			case r.typ == typ && r.subtype == subtype:
+				s = 2
+			case r.typ == typ && r.subtype == "*":
+				s = 1
+			case r.typ == "*" && r.subtype == "*":
+				s = 0
+			}
+			if s > specificity || (s == specificity && s >= 0 && r.q > q) {
+				q, specificity = r.q, s
+			}
+		}
+	}
+	return q, specificity
+}
+
+// formatOf returns the format of a Content-Type header, JSON when it is missing
+func formatOf(contentType string) *Format {
+	if strings.TrimSpace(contentType) == "" {
+		return JSON
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	for _, f := range Formats {
+		for _, t := range f.mediaTypes() {
+			if t == mediaType {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// Bind decodes the request body into v according to its Content-Type, answering 415 for
+// formats v cannot be read from and 400 for malformed bodies
+func Bind(c *gin.Context, v interface{}) *errors.RestErr {
+	contentType := c.GetHeader("Content-Type")
+	f := formatOf(contentType)
+	if f == nil || (f.canUnmarshal != nil && !f.canUnmarshal(v)) {
+		return errors.NewUnsupportedMediaTypeError(fmt.Sprintf("unsupported content type %q", contentType))
+	}
+	data, err := c.GetRawData()
+	if err != nil {
+		return errors.NewBadRequestError(fmt.Sprintf("failed to read request body: %s", err.Error()))
+	}
+	if err := f.unmarshal(data, v); err != nil {
+		// XML and MessagePack bodies go through the JSON decoder too
+		msg := strings.TrimPrefix(err.Error(), "json: ")
+		return errors.NewBadRequestError(fmt.Sprintf("invalid %s body: %s", f.Name, msg))
+	}
+	return nil
+}
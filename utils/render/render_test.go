@@ -0,0 +1,254 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sauravgsh16/bookstore_users-api/domain/users"
+	"github.com/sauravgsh16/bookstore_users-api/utils/errors"
+)
+
+func formatName(f *Format) string {
+	if f == nil {
+		return "none"
+	}
+	return f.Name
+}
+
+func TestNegotiate(t *testing.T) {
+	proto := users.Document{}
+	plain := map[string]string{}
+
+	tests := []struct {
+		accept string
+		v      interface{}
+		want   *Format
+	}{
+		{accept: "", v: plain, want: JSON},
+		{accept: "application/json", v: plain, want: JSON},
+		{accept: "application/xml", v: plain, want: XML},
+		{accept: "text/xml", v: plain, want: XML},
+		{accept: "application/x-msgpack", v: plain, want: MsgPack},
+		{accept: "*/*", v: plain, want: JSON},
+		{accept: "application/*", v: plain, want: JSON},
+		{accept: "text/*", v: plain, want: XML},
+		{accept: "text/html", v: plain, want: nil},
+		{accept: "not a media type", v: plain, want: nil},
+		// quality picks among the accepted formats
+		{accept: "application/xml;q=0.5, application/msgpack", v: plain, want: MsgPack},
+		{accept: "application/json;q=0.1, application/xml;q=0.9", v: plain, want: XML},
+		// ties go to the preferred format
+		{accept: "application/msgpack, application/xml", v: plain, want: XML},
+		// q=0 refuses a format, even when a wildcard accepts it
+		{accept: "application/json;q=0", v: plain, want: nil},
+		{accept: "application/json;q=0, */*", v: plain, want: XML},
+		{accept: "application/*, application/json;q=0, application/xml;q=0", v: plain, want: MsgPack},
+		{accept: "*/*;q=0", v: plain, want: nil},
+		// the most specific range applies, whatever its quality
+		{accept: "*/*;q=1, application/json;q=0.2, application/xml;q=0.1", v: plain, want: MsgPack},
+		// invalid qualities are ignored
+		{accept: "application/json;q=2", v: plain, want: JSON},
+		// protobuf is only offered for values having a representation
+		{accept: "application/x-protobuf", v: plain, want: nil},
+		{accept: "application/x-protobuf", v: proto, want: Protobuf},
+		{accept: "application/protobuf, application/json;q=0.5", v: plain, want: JSON},
+		{accept: "application/protobuf, application/json;q=0.5", v: proto, want: Protobuf},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := Negotiate(tt.accept, tt.v); got != tt.want {
+				t.Errorf("Negotiate(%q, %T) = %s, want %s", tt.accept, tt.v, formatName(got), formatName(tt.want))
+			}
+		})
+	}
+}
+
+func TestRespond(t *testing.T) {
+	tests := []struct {
+		name            string
+		accept          string
+		status          int
+		v               interface{}
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name: "json", status: http.StatusOK, v: map[string]string{"status": "ok"},
+			wantStatus: http.StatusOK, wantContentType: "application/json; charset=utf-8", wantBody: `{"status":"ok"}`,
+		},
+		{
+			name: "xml", accept: "application/xml", status: http.StatusCreated, v: map[string]string{"status": "ok"},
+			wantStatus: http.StatusCreated, wantContentType: "application/xml; charset=utf-8", wantBody: "<response><status>ok</status></response>",
+		},
+		{
+			name: "not acceptable", accept: "text/html", status: http.StatusOK, v: map[string]string{"status": "ok"},
+			wantStatus: http.StatusNotAcceptable, wantContentType: "application/json; charset=utf-8", wantBody: `"status":406`,
+		},
+		{
+			name: "protobuf of a value without representation", accept: "application/x-protobuf", status: http.StatusOK, v: map[string]string{},
+			wantStatus: http.StatusNotAcceptable, wantContentType: "application/json; charset=utf-8", wantBody: "application/msgpack",
+		},
+		{
+			name: "error in an unacceptable format", accept: "text/html", status: http.StatusNotFound, v: errors.NewNotFoundError("user 1 not found"),
+			wantStatus: http.StatusNotFound, wantContentType: "application/json; charset=utf-8", wantBody: `"message":"user 1 not found"`,
+		},
+		{
+			name: "unencodable", status: http.StatusOK, v: map[string]interface{}{"c": make(chan int)},
+			wantStatus: http.StatusInternalServerError, wantContentType: "application/json; charset=utf-8", wantBody: "failed to encode response",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			if tt.accept != "" {
+				c.Request.Header.Set("Accept", tt.accept)
+			}
+
+			Respond(c, tt.status, tt.v)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q, want Accept", got)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestBind(t *testing.T) {
+	msgpackBody, err := marshalMsgPack(map[string]interface{}{"first_name": "Alice", "id": 3})
+	if err != nil {
+		t.Fatalf("marshalMsgPack: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		into        interface{}
+		want        interface{}
+		wantStatus  int
+	}{
+		{name: "json", contentType: "application/json", body: []byte(`{"first_name":"Alice","id":3}`), into: &users.User{}, want: &users.User{ID: 3, FirstName: "Alice"}},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", body: []byte(`{"id":3}`), into: &users.User{}, want: &users.User{ID: 3}},
+		{name: "missing content type", body: []byte(`{"id":3}`), into: &users.User{}, want: &users.User{ID: 3}},
+		{name: "xml", contentType: "text/xml", body: []byte(`<user><first_name>Alice</first_name><id>3</id></user>`), into: &users.User{}, want: &users.User{ID: 3, FirstName: "Alice"}},
+		{name: "msgpack", contentType: "application/msgpack", body: msgpackBody, into: &users.User{}, want: &users.User{ID: 3, FirstName: "Alice"}},
+		{name: "unsupported", contentType: "text/plain", body: []byte("id=3"), into: &users.User{}, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "malformed content type", contentType: "application/", body: []byte(`{}`), into: &users.User{}, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "protobuf into a value without representation", contentType: "application/x-protobuf", body: []byte{}, into: &map[string]string{}, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "malformed json", contentType: "application/json", body: []byte(`{"id":`), into: &users.User{}, wantStatus: http.StatusBadRequest},
+		{name: "mistyped json", contentType: "application/json", body: []byte(`{"id":"three"}`), into: &users.User{}, wantStatus: http.StatusBadRequest},
+		{name: "mistyped xml", contentType: "application/xml", body: []byte(`<user><id>three</id></user>`), into: &users.User{}, wantStatus: http.StatusBadRequest},
+		{name: "malformed msgpack", contentType: "application/msgpack", body: []byte{0xc1}, into: &users.User{}, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(tt.body))
+			if tt.contentType != "" {
+				c.Request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			err := Bind(c, tt.into)
+			if tt.wantStatus != 0 {
+				if err == nil || err.Status != tt.wantStatus {
+					t.Fatalf("Bind = %v, want status %d", err, tt.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Bind: %s", err.Message)
+			}
+			if !reflect.DeepEqual(tt.into, tt.want) {
+				t.Errorf("Bind decoded %+v, want %+v", tt.into, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	alice := &users.User{ID: 1, FirstName: "Alice", LastName: "O'Brien <&>", Email: "alice@test.invalid",
+		DateCreated: "2026-01-02 03:04:05", Status: users.StatusActive}
+	bob := &users.User{ID: 2, FirstName: "Bob", Email: "bob@test.invalid", Status: users.StatusDeleted, DeletedAt: "2026-02-03 04:05:06"}
+
+	tests := []struct {
+		name string
+		v    interface{}
+		// into returns a pointer to decode into
+		into func() interface{}
+	}{
+		{name: "user", v: alice, into: func() interface{} { return &users.User{} }},
+		{name: "users", v: users.Users{alice, bob}, into: func() interface{} { return &users.Users{} }},
+		{name: "no users", v: users.Users{}, into: func() interface{} { return &users.Users{} }},
+		{name: "error", v: errors.NewNotFoundError("user 3 not found"), into: func() interface{} { return &errors.RestErr{} }},
+	}
+	for _, f := range []*Format{JSON, XML, MsgPack} {
+		for _, tt := range tests {
+			t.Run(f.Name+" "+tt.name, func(t *testing.T) {
+				data, err := f.marshal(tt.v)
+				if err != nil {
+					t.Fatalf("marshal: %v", err)
+				}
+				got := tt.into()
+				if err := f.unmarshal(data, got); err != nil {
+					t.Fatalf("unmarshal %s: %v", data, err)
+				}
+				if want := reflect.ValueOf(tt.v); !reflect.DeepEqual(reflect.ValueOf(got).Elem().Interface(), deref(want)) {
+					t.Errorf("round trip through %s = %+v, want %+v", data, got, tt.v)
+				}
+			})
+		}
+	}
+}
+
+// deref returns the value v points to, or v if it is no pointer
+func deref(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		return v.Elem().Interface()
+	}
+	return v.Interface()
+}
+
+func TestRoundTripDocument(t *testing.T) {
+	p, restErr := users.NewVersionedProjection(users.ViewPublic, users.Version2, nil)
+	if restErr != nil {
+		t.Fatalf("projection: %s", restErr.Message)
+	}
+	u := &users.User{ID: 7, FirstName: "Alice", LastName: "Liddell", Email: "alice@test.invalid", Status: users.StatusActive}
+	want, err := json.Marshal(u.Marshall(p))
+	if err != nil {
+		t.Fatalf("marshalling json: %v", err)
+	}
+
+	for _, f := range []*Format{XML, MsgPack} {
+		data, err := f.marshal(u.Marshall(p))
+		if err != nil {
+			t.Fatalf("%s marshal: %v", f.Name, err)
+		}
+		var got map[string]interface{}
+		if err := f.unmarshal(data, &got); err != nil {
+			t.Fatalf("%s unmarshal: %v", f.Name, err)
+		}
+		var wantMap map[string]interface{}
+		json.Unmarshal(want, &wantMap)
+		if !reflect.DeepEqual(got, wantMap) {
+			t.Errorf("%s document = %v, want %v", f.Name, got, wantMap)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	xmlRoot = "response"
+	xmlItem = "item"
+)
+
+// xmlName matches the JSON keys that are usable as element names as they are
+var xmlName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9._-]*$`)
+
+// marshalXML writes the JSON document of v as elements named after its keys. Null values are
+// left out, list items become item elements and keys that are not valid names become entry
+// elements with a key attribute.
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := decodeTree(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := writeElement(enc, xmlRoot, tree); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeElement(enc *xml.Encoder, name string, node interface{}) error {
+	if node == nil {
+		return nil
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !xmlName.MatchString(name) || strings.HasPrefix(strings.ToLower(name), "xml") {
+		start.Name.Local = "entry"
+		start.Attr = []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch n := node.(type) {
+	case object:
+		for i := 0; i < len(n); i += 2 {
+			if err := writeElement(enc, n[i].(string), n[i+1]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range n {
+			if err := writeElement(enc, xmlItem, item); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(n))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// element is a parsed XML element, holding either text or child elements
+type element struct {
+	name     string
+	text     string
+	children []*element
+}
+
+func readElement(dec *xml.Decoder, start xml.StartElement) (*element, error) {
+	el := &element{name: start.Name.Local}
+	for _, attr := range start.Attr {
+		if el.name == "entry" && attr.Name.Local == "key" {
+			el.name = attr.Value
+		}
+	}
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := readElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			el.children = append(el.children, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			el.text = text.String()
+			return el, nil
+		}
+	}
+}
+
+// unmarshalXML reads a document shaped like those marshalXML writes. The type of v tells which
+// element texts are numbers or booleans, the resulting JSON is then decoded as a JSON body is.
+func unmarshalXML(data []byte, v interface{}) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var root *element
+	for root == nil {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return fmt.Errorf("missing root element")
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if root, err = readElement(dec, start); err != nil {
+				return err
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	writeJSON(&buf, root, reflect.TypeOf(v))
+	return json.Unmarshal(buf.Bytes(), v)
+}
+
+// writeJSON writes el as the JSON value of type t, strings for types it does not know
+func writeJSON(buf *bytes.Buffer, el *element, t reflect.Type) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	kind := reflect.Invalid
+	if t != nil {
+		kind = t.Kind()
+	}
+
+	switch {
+	case kind == reflect.Struct || kind == reflect.Map:
+		buf.WriteByte('{')
+		for i, child := range el.children {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(child.name)
+			buf.Write(key)
+			buf.WriteByte(':')
+			writeJSON(buf, child, fieldType(t, child.name))
+		}
+		buf.WriteByte('}')
+	case kind == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+		buf.WriteByte('[')
+		for i, child := range el.children {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSON(buf, child, t.Elem())
+		}
+		buf.WriteByte(']')
+	case kind == reflect.Bool:
+		writeLiteral(buf, el.text, func(s string) bool { _, err := strconv.ParseBool(s); return err == nil })
+	case kind >= reflect.Int && kind <= reflect.Float64:
+		writeLiteral(buf, el.text, func(s string) bool { _, err := strconv.ParseFloat(s, 64); return err == nil })
+	default:
+		text, _ := json.Marshal(el.text)
+		buf.Write(text)
+	}
+}
+
+// writeLiteral writes text as is if valid says it is a JSON literal, quoted otherwise so the
+// JSON decoder reports the mismatch
+func writeLiteral(buf *bytes.Buffer, text string, valid func(string) bool) {
+	text = strings.TrimSpace(text)
+	if valid(text) {
+		// ParseBool and ParseFloat accept more spellings than JSON does
+		if lit, err := json.Marshal(json.RawMessage(strings.ToLower(text))); err == nil {
+			buf.Write(lit)
+			return
+		}
+	}
+	quoted, _ := json.Marshal(text)
+	buf.Write(quoted)
+}
+
+// fieldType returns the type of the field a JSON key sets in t, nil if there is none
+func fieldType(t reflect.Type, key string) reflect.Type {
+	if t.Kind() == reflect.Map {
+		return t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if embedded := fieldType(ft, key); embedded != nil {
+					return embedded
+				}
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, key) {
+			return f.Type
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Package resp is a minimal client for servers speaking the Redis serialization protocol
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// ErrNil is returned for nil replies, such as GET on a missing key
+var ErrNil = errors.New("resp: nil reply")
+
+// Error is an error reply sent by the server
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Options configures a client
+type Options struct {
+	Addr     string
+	Password string
+	DB       int
+	// PoolSize is the maximum number of idle connections kept open
+	PoolSize int
+	// Timeout bounds dialing and each command when the context has no earlier deadline
+	Timeout time.Duration
+}
+
+// Client sends commands over a pool of connections. It is safe for concurrent use.
+type Client struct {
+	opts Options
+	idle chan *conn
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// NewClient returns a client for the server at opts.Addr. Connections are opened lazily.
+func NewClient(opts Options) *Client {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+	return &Client{opts: opts, idle: make(chan *conn, opts.PoolSize)}
+}
+
+// Do sends the command and returns its reply: a string for simple and bulk strings, an int64
+// for integers, a []interface{} for arrays. Nil replies return ErrNil, error replies an Error.
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(ctx, c.opts.Timeout, args)
+	if _, isReply := err.(Error); err != nil && !isReply && err != ErrNil {
+		// the connection state is unknown after an i/o error
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes the idle connections
+func (c *Client) Close() error {
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+
+	d := net.Dialer{Timeout: c.opts.Timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if c.opts.Password != "" {
+		if _, err := cn.do(ctx, c.opts.Timeout, []string{"AUTH", c.opts.Password}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := cn.do(ctx, c.opts.Timeout, []string{"SELECT", strconv.Itoa(c.opts.DB)}); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (c *Client) put(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+func (cn *conn) do(ctx context.Context, timeout time.Duration, args []string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(cn.r)
+}
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("resp: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := readReply(r)
+			if err != nil && err != ErrNil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("resp: unexpected reply %q", line)
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("resp: malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}